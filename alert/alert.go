@@ -0,0 +1,46 @@
+package alert
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// Alerter é a interface comum aos serviços de gestão de incidentes
+// A chave (key) identifica o incidente, de forma que disparos repetidos para a mesma URL sejam
+// agrupados em um único incidente pelo serviço
+type Alerter interface {
+	Trigger(key, summary string, details map[string]string) error
+	Resolve(key string) error
+}
+
+func newClient() *http.Client {
+	return &http.Client{Timeout: 10 * time.Second}
+}
+
+// postJSON envia body codificado em JSON e verifica se o código de retorno indica sucesso
+func postJSON(client *http.Client, url string, headers map[string]string, body interface{}) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for k, v := range headers {
+		req.Header.Set(k, v)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("%s returned status %d", url, resp.StatusCode)
+	}
+	return nil
+}
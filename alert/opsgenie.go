@@ -0,0 +1,63 @@
+package alert
+
+import (
+	"net/http"
+	"net/url"
+	"unicode/utf8"
+)
+
+// OpsgenieAlertsURL é o endereço da Alert API do Opsgenie (para contas na região EU utilize
+// https://api.eu.opsgenie.com/v2/alerts)
+const OpsgenieAlertsURL = "https://api.opsgenie.com/v2/alerts"
+
+// Opsgenie cria e fecha alertas através da Alert API, usando o alias como chave de deduplicação
+type Opsgenie struct {
+	APIKey string
+	URL    string
+	Client *http.Client
+}
+
+// NewOpsgenie cria um Alerter do Opsgenie para a API key informada
+func NewOpsgenie(apiKey string) *Opsgenie {
+	return &Opsgenie{
+		APIKey: apiKey,
+		URL:    OpsgenieAlertsURL,
+		Client: newClient(),
+	}
+}
+
+type opsgenieAlert struct {
+	Message string            `json:"message"`
+	Alias   string            `json:"alias"`
+	Source  string            `json:"source"`
+	Details map[string]string `json:"details,omitempty"`
+}
+
+type opsgenieClose struct {
+	Source string `json:"source"`
+}
+
+func (o *Opsgenie) headers() map[string]string {
+	return map[string]string{"Authorization": "GenieKey " + o.APIKey}
+}
+
+// Trigger cria um alerta; alertas abertos com o mesmo alias são agrupados pelo Opsgenie
+func (o *Opsgenie) Trigger(key, summary string, details map[string]string) error {
+	// O Opsgenie limita a mensagem a 130 caracteres; o corte é feito por caractere para não
+	// deixar um caractere multibyte (como os acentos) pela metade
+	if utf8.RuneCountInString(summary) > 130 {
+		summary = string([]rune(summary)[:130])
+	}
+	return postJSON(o.Client, o.URL, o.headers(), opsgenieAlert{
+		Message: summary,
+		Alias:   key,
+		Source:  "entendendo-worker-pool",
+		Details: details,
+	})
+}
+
+// Resolve fecha o alerta identificado pelo alias
+func (o *Opsgenie) Resolve(key string) error {
+	endpoint := o.URL + "/" + url.PathEscape(key) + "/close?identifierType=alias"
+	return postJSON(o.Client, endpoint, o.headers(), opsgenieClose{Source: "entendendo-worker-pool"})
+}
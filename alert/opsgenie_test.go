@@ -0,0 +1,46 @@
+package alert
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestOpsgenieTruncatesMessageOnRunes(t *testing.T) {
+	tests := []struct {
+		name    string
+		summary string
+		want    string
+	}{
+		{"short", "latência alta", "latência alta"},
+		{"ascii", strings.Repeat("a", 200), strings.Repeat("a", 130)},
+		// Em bytes, o corte cairia no meio do 65º "é"
+		{"multibyte", strings.Repeat("é", 200), strings.Repeat("é", 130)},
+		{"exactly 130", strings.Repeat("ç", 130), strings.Repeat("ç", 130)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got opsgenieAlert
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				json.NewDecoder(r.Body).Decode(&got)
+				w.WriteHeader(http.StatusAccepted)
+			}))
+			defer server.Close()
+
+			o := NewOpsgenie("key")
+			o.URL = server.URL
+			if err := o.Trigger("alias", tt.summary, nil); err != nil {
+				t.Fatal(err)
+			}
+			if !utf8.ValidString(got.Message) {
+				t.Fatalf("message is not valid UTF-8: %q", got.Message)
+			}
+			if got.Message != tt.want {
+				t.Errorf("message = %q (%d runes), want %d runes", got.Message, utf8.RuneCountInString(got.Message), utf8.RuneCountInString(tt.want))
+			}
+		})
+	}
+}
@@ -0,0 +1,60 @@
+package alert
+
+import "net/http"
+
+// PagerDutyEventsURL é o endereço da Events API v2 do PagerDuty
+const PagerDutyEventsURL = "https://events.pagerduty.com/v2/enqueue"
+
+// PagerDuty dispara e resolve incidentes através da Events API v2
+type PagerDuty struct {
+	RoutingKey string
+	URL        string
+	Client     *http.Client
+}
+
+// NewPagerDuty cria um Alerter do PagerDuty para a integration/routing key informada
+func NewPagerDuty(routingKey string) *PagerDuty {
+	return &PagerDuty{
+		RoutingKey: routingKey,
+		URL:        PagerDutyEventsURL,
+		Client:     newClient(),
+	}
+}
+
+type pagerDutyPayload struct {
+	Summary       string            `json:"summary"`
+	Source        string            `json:"source"`
+	Severity      string            `json:"severity"`
+	CustomDetails map[string]string `json:"custom_details,omitempty"`
+}
+
+type pagerDutyEvent struct {
+	RoutingKey  string            `json:"routing_key"`
+	EventAction string            `json:"event_action"`
+	DedupKey    string            `json:"dedup_key"`
+	Payload     *pagerDutyPayload `json:"payload,omitempty"`
+}
+
+// Trigger abre (ou atualiza, caso a dedup key já exista) um incidente
+func (p *PagerDuty) Trigger(key, summary string, details map[string]string) error {
+	return postJSON(p.Client, p.URL, nil, pagerDutyEvent{
+		RoutingKey:  p.RoutingKey,
+		EventAction: "trigger",
+		DedupKey:    key,
+		Payload: &pagerDutyPayload{
+			Summary:       summary,
+			Source:        "entendendo-worker-pool",
+			Severity:      "error",
+			CustomDetails: details,
+		},
+	})
+}
+
+// Resolve fecha o incidente associado à dedup key
+func (p *PagerDuty) Resolve(key string) error {
+	return postJSON(p.Client, p.URL, nil, pagerDutyEvent{
+		RoutingKey:  p.RoutingKey,
+		EventAction: "resolve",
+		DedupKey:    key,
+	})
+}
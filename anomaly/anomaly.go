@@ -0,0 +1,120 @@
+// Package anomaly detecta picos de latência estatisticamente anormais, comparando cada medição com
+// a linha de base das medições recentes da mesma URL, em vez de depender apenas de um limite fixo
+package anomaly
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultThreshold é a pontuação a partir da qual uma medição é considerada anômala; 3.5 é o valor
+// recomendado por Iglewicz e Hoaglin para o z-score modificado
+const DefaultThreshold = 3.5
+
+// minSamples é a quantidade de medições necessária para que a linha de base seja confiável
+const minSamples = 10
+
+// Detector guarda, para cada URL, uma janela com as últimas medições e avalia cada nova medição
+// contra a mediana e o desvio absoluto mediano (MAD) dessa janela. A mediana e o MAD não são
+// distorcidos pelos próprios picos, ao contrário da média e do desvio padrão. É seguro para uso por
+// várias goroutines
+type Detector struct {
+	window    int
+	threshold float64
+	mux       sync.Mutex
+	samples   map[string]*ring
+}
+
+// Anomaly descreve uma medição fora do padrão da URL
+type Anomaly struct {
+	// Baseline é a mediana da janela e Score, o z-score modificado da medição
+	Baseline time.Duration
+	Score    float64
+}
+
+// New cria um detector com janelas de window medições por URL; threshold <= 0 usa DefaultThreshold
+func New(window int, threshold float64) *Detector {
+	if threshold <= 0 {
+		threshold = DefaultThreshold
+	}
+	return &Detector{window: window, threshold: threshold, samples: make(map[string]*ring)}
+}
+
+// Observe avalia a medição contra a linha de base da URL e a inclui na janela. Enquanto a janela
+// não tem medições suficientes, nenhuma anomalia é apontada. As medições anômalas também entram na
+// janela, para que uma mudança duradoura de patamar passe a ser o novo normal
+func (d *Detector) Observe(url string, latency time.Duration) (Anomaly, bool) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	r, ok := d.samples[url]
+	if !ok {
+		r = &ring{values: make([]float64, 0, d.window)}
+		d.samples[url] = r
+	}
+	defer r.add(float64(latency), d.window)
+
+	if len(r.values) < min(minSamples, d.window) {
+		return Anomaly{}, false
+	}
+	median, mad := medianMAD(r.values)
+	// Com medições quase idênticas o MAD tende a zero e qualquer variação viraria anomalia; o piso de
+	// 5% da mediana ignora as oscilações pequenas
+	mad = math.Max(mad, median*0.05)
+	if mad == 0 {
+		return Anomaly{}, false
+	}
+	score := 0.6745 * (float64(latency) - median) / mad
+	// Apenas os picos interessam: respostas mais rápidas que o normal não são um problema
+	if score < d.threshold {
+		return Anomaly{}, false
+	}
+	return Anomaly{Baseline: time.Duration(median), Score: score}, true
+}
+
+// Keep descarta as linhas de base das URLs que não estão em urls, por exemplo quando elas saem da
+// lista medida
+func (d *Detector) Keep(urls map[string]bool) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	for url := range d.samples {
+		if !urls[url] {
+			delete(d.samples, url)
+		}
+	}
+}
+
+// ring é uma janela circular de medições
+type ring struct {
+	values []float64
+	next   int
+}
+
+func (r *ring) add(v float64, size int) {
+	if len(r.values) < size {
+		r.values = append(r.values, v)
+		return
+	}
+	r.values[r.next] = v
+	r.next = (r.next + 1) % size
+}
+
+func medianMAD(values []float64) (float64, float64) {
+	sorted := append([]float64(nil), values...)
+	sort.Float64s(sorted)
+	median := middle(sorted)
+	for i, v := range sorted {
+		sorted[i] = math.Abs(v - median)
+	}
+	sort.Float64s(sorted)
+	return median, middle(sorted)
+}
+
+func middle(sorted []float64) float64 {
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
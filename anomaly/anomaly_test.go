@@ -0,0 +1,94 @@
+package anomaly
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestObserve(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name      string
+		baseline  []time.Duration
+		latency   time.Duration
+		want      bool
+		wantScore float64
+	}{
+		// Com medições idênticas o MAD é zero e vale o piso de 5% da mediana (5ms)
+		{name: "identical samples, small change", baseline: repeat(100*ms, 20), latency: 120 * ms},
+		{name: "identical samples, spike", baseline: repeat(100*ms, 20), latency: 130 * ms, want: true, wantScore: 0.6745 * 30 / 5},
+		{name: "faster than usual", baseline: repeat(100*ms, 20), latency: 10 * ms},
+		// Sem nenhuma variação possível (mediana zero), não há como pontuar a medição
+		{name: "zero baseline", baseline: repeat(0, 20), latency: time.Second},
+		{name: "not enough samples", baseline: repeat(100*ms, minSamples-1), latency: 10 * time.Second},
+		{name: "noisy baseline", baseline: alternate(90*ms, 110*ms, 20), latency: 200 * ms, want: true, wantScore: 0.6745 * 100 / 10},
+		{name: "within the noise", baseline: alternate(90*ms, 110*ms, 20), latency: 140 * ms},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := New(30, 0)
+			for _, l := range tt.baseline {
+				if _, ok := d.Observe("http://a", l); ok {
+					t.Fatalf("baseline sample %s flagged as anomaly", l)
+				}
+			}
+			a, ok := d.Observe("http://a", tt.latency)
+			if ok != tt.want {
+				t.Fatalf("Observe(%s) anomaly = %v (%+v), want %v", tt.latency, ok, a, tt.want)
+			}
+			if ok && math.Abs(a.Score-tt.wantScore) > 1e-9 {
+				t.Errorf("score = %f, want %f", a.Score, tt.wantScore)
+			}
+		})
+	}
+}
+
+func TestObserveWindowAdapts(t *testing.T) {
+	d := New(10, 0)
+	for _, l := range repeat(100*time.Millisecond, 10) {
+		d.Observe("http://a", l)
+	}
+	// As medições anômalas entram na janela: um novo patamar deixa de ser anômalo depois que passa a
+	// ser a maioria da janela
+	flagged := 0
+	for i := 0; i < 10; i++ {
+		if _, ok := d.Observe("http://a", 300*time.Millisecond); ok {
+			flagged++
+		}
+	}
+	if flagged == 0 || flagged == 10 {
+		t.Errorf("%d of 10 samples at the new level were flagged, want the first ones only", flagged)
+	}
+	if _, ok := d.Observe("http://a", 300*time.Millisecond); ok {
+		t.Error("the new level is still flagged after filling the window")
+	}
+
+	// As URLs têm linhas de base independentes e Keep descarta as que saíram da lista
+	if _, ok := d.Observe("http://b", time.Second); ok {
+		t.Error("a new URL was flagged without a baseline")
+	}
+	d.Keep(map[string]bool{"http://b": true})
+	if _, ok := d.samples["http://a"]; ok {
+		t.Error("Keep did not drop http://a")
+	}
+}
+
+func repeat(d time.Duration, n int) []time.Duration {
+	values := make([]time.Duration, n)
+	for i := range values {
+		values[i] = d
+	}
+	return values
+}
+
+func alternate(a, b time.Duration, n int) []time.Duration {
+	values := make([]time.Duration, n)
+	for i := range values {
+		values[i] = a
+		if i%2 == 1 {
+			values[i] = b
+		}
+	}
+	return values
+}
@@ -0,0 +1,140 @@
+// Package clock abstrai o relógio usado pelo pool e pelo teste de carga para controlar o ritmo
+// das requisições e marcar o horário dos resultados, para que os testes possam usar um relógio
+// falso e verificar o ritmo dos disparos sem esperas reais. A latência de cada requisição
+// continua medida com o relógio do sistema pelas funções de visita e pelas sondas
+package clock
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// Clock é a fonte de tempo
+type Clock interface {
+	Now() time.Time
+	Since(t time.Time) time.Duration
+	// Timer cria um temporizador que dispara uma única vez após d
+	Timer(d time.Duration) Timer
+}
+
+// Timer é um temporizador criado por um Clock
+type Timer interface {
+	// C recebe o momento do disparo
+	C() <-chan time.Time
+	// Stop cancela o temporizador, informando se ele ainda não tinha disparado
+	Stop() bool
+}
+
+// Real é o relógio do sistema
+var Real Clock = realClock{}
+
+type realClock struct{}
+
+func (realClock) Now() time.Time                  { return time.Now() }
+func (realClock) Since(t time.Time) time.Duration { return time.Since(t) }
+func (realClock) Timer(d time.Duration) Timer     { return realTimer{time.NewTimer(d)} }
+
+type realTimer struct{ t *time.Timer }
+
+func (t realTimer) C() <-chan time.Time { return t.t.C }
+func (t realTimer) Stop() bool          { return t.t.Stop() }
+
+// Sleep aguarda d no relógio informado
+func Sleep(c Clock, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	<-c.Timer(d).C()
+}
+
+// Fake é um relógio controlado manualmente: o tempo só avança com Advance, que dispara os
+// temporizadores vencidos. É seguro para uso por várias goroutines
+type Fake struct {
+	mux    sync.Mutex
+	now    time.Time
+	timers []*fakeTimer
+}
+
+// NewFake cria um relógio falso parado em start
+func NewFake(start time.Time) *Fake {
+	return &Fake{now: start}
+}
+
+// Now devolve o momento atual do relógio falso
+func (f *Fake) Now() time.Time {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return f.now
+}
+
+// Since devolve o tempo decorrido desde t no relógio falso
+func (f *Fake) Since(t time.Time) time.Duration {
+	return f.Now().Sub(t)
+}
+
+// Timer cria um temporizador que dispara quando o relógio for avançado até o prazo
+func (f *Fake) Timer(d time.Duration) Timer {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	t := &fakeTimer{clock: f, deadline: f.now.Add(d), c: make(chan time.Time, 1)}
+	if d <= 0 {
+		t.c <- f.now
+		t.done = true
+		return t
+	}
+	f.timers = append(f.timers, t)
+	return t
+}
+
+// Advance avança o relógio em d, disparando em ordem os temporizadores que vencerem
+func (f *Fake) Advance(d time.Duration) {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	f.now = f.now.Add(d)
+	sort.Slice(f.timers, func(i, j int) bool { return f.timers[i].deadline.Before(f.timers[j].deadline) })
+	pending := f.timers[:0]
+	for _, t := range f.timers {
+		if t.deadline.After(f.now) {
+			pending = append(pending, t)
+			continue
+		}
+		t.done = true
+		t.c <- t.deadline
+	}
+	f.timers = pending
+}
+
+// Waiting informa quantos temporizadores aguardam o avanço do relógio, permitindo que o teste
+// espere uma goroutine chegar ao ponto de espera antes de avançar
+func (f *Fake) Waiting() int {
+	f.mux.Lock()
+	defer f.mux.Unlock()
+	return len(f.timers)
+}
+
+type fakeTimer struct {
+	clock    *Fake
+	deadline time.Time
+	c        chan time.Time
+	// done indica que o temporizador já disparou ou foi cancelado
+	done bool
+}
+
+func (t *fakeTimer) C() <-chan time.Time { return t.c }
+
+func (t *fakeTimer) Stop() bool {
+	t.clock.mux.Lock()
+	defer t.clock.mux.Unlock()
+	if t.done {
+		return false
+	}
+	for i, other := range t.clock.timers {
+		if other == t {
+			t.clock.timers = append(t.clock.timers[:i], t.clock.timers[i+1:]...)
+			break
+		}
+	}
+	t.done = true
+	return true
+}
@@ -0,0 +1,59 @@
+package clock
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFakeAdvanceFiresDueTimersInOrder(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	f := NewFake(start)
+	late := f.Timer(2 * time.Second)
+	early := f.Timer(time.Second)
+	if f.Waiting() != 2 {
+		t.Fatalf("Waiting() = %d, want 2", f.Waiting())
+	}
+
+	f.Advance(time.Second)
+	select {
+	case at := <-early.C():
+		if !at.Equal(start.Add(time.Second)) {
+			t.Errorf("early timer fired at %s, want %s", at, start.Add(time.Second))
+		}
+	default:
+		t.Fatal("early timer did not fire")
+	}
+	select {
+	case <-late.C():
+		t.Fatal("late timer fired before its deadline")
+	default:
+	}
+	if got := f.Since(start); got != time.Second {
+		t.Errorf("Since(start) = %s, want 1s", got)
+	}
+
+	if !late.Stop() {
+		t.Error("Stop() = false for a pending timer")
+	}
+	f.Advance(time.Hour)
+	select {
+	case <-late.C():
+		t.Fatal("stopped timer fired")
+	default:
+	}
+	if early.Stop() {
+		t.Error("Stop() = true for a timer that already fired")
+	}
+}
+
+func TestFakeZeroTimerFiresImmediately(t *testing.T) {
+	f := NewFake(time.Unix(0, 0))
+	select {
+	case <-f.Timer(0).C():
+	default:
+		t.Fatal("zero timer did not fire")
+	}
+	if f.Waiting() != 0 {
+		t.Errorf("Waiting() = %d, want 0", f.Waiting())
+	}
+}
@@ -0,0 +1,134 @@
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// Coordinator distribui uma lista de URLs entre agentes remotos, cada um executando o modo serve
+// com o seu próprio worker pool, e junta os resultados de todos
+type Coordinator struct {
+	Agents []string
+	// Replicate envia a lista completa para todos os agentes, permitindo comparar a latência das
+	// mesmas URLs a partir de pontos diferentes; caso contrário a lista é dividida entre eles
+	Replicate bool
+	// PollInterval é o intervalo entre as consultas ao estado das execuções nos agentes
+	PollInterval time.Duration
+	Client       *http.Client
+}
+
+// AgentResult agrupa os resultados obtidos por um agente
+type AgentResult struct {
+	Agent   string
+	Elapsed time.Duration
+	Results []pool.Result
+	Err     error
+}
+
+// NewCoordinator cria um coordenador para os endereços base dos agentes (ex: http://10.0.0.1:8080)
+func NewCoordinator(agents []string) *Coordinator {
+	normalized := make([]string, len(agents))
+	for i, a := range agents {
+		normalized[i] = strings.TrimSuffix(a, "/")
+	}
+	return &Coordinator{
+		Agents:       normalized,
+		PollInterval: 500 * time.Millisecond,
+		Client:       &http.Client{Timeout: 30 * time.Second},
+	}
+}
+
+// Run envia as URLs para os agentes e aguarda todos terminarem
+func (c *Coordinator) Run(urls []string) []AgentResult {
+	parts := c.split(urls)
+	results := make([]AgentResult, len(c.Agents))
+
+	// Cada agente é acompanhado por uma goroutine própria
+	var wg sync.WaitGroup
+	wg.Add(len(c.Agents))
+	for i, agent := range c.Agents {
+		go func(i int, agent string) {
+			defer wg.Done()
+			start := time.Now()
+			agentResults, err := c.runOnAgent(agent, parts[i])
+			results[i] = AgentResult{Agent: agent, Elapsed: time.Since(start), Results: agentResults, Err: err}
+		}(i, agent)
+	}
+	wg.Wait()
+	return results
+}
+
+// split divide as URLs entre os agentes de forma alternada, ou replica a lista para todos
+func (c *Coordinator) split(urls []string) [][]string {
+	parts := make([][]string, len(c.Agents))
+	for i := range c.Agents {
+		if c.Replicate {
+			parts[i] = urls
+		}
+	}
+	if !c.Replicate {
+		for i, url := range urls {
+			parts[i%len(parts)] = append(parts[i%len(parts)], url)
+		}
+	}
+	return parts
+}
+
+type agentRun struct {
+	ID     string `json:"id"`
+	Status string `json:"status"`
+}
+
+func (c *Coordinator) runOnAgent(agent string, urls []string) ([]pool.Result, error) {
+	if len(urls) == 0 {
+		return nil, nil
+	}
+	body, err := json.Marshal(map[string][]string{"urls": urls})
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Client.Post(agent+"/runs", "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	var run agentRun
+	err = decode(resp, http.StatusAccepted, &run)
+	if err != nil {
+		return nil, err
+	}
+
+	// Aguarda a execução terminar no agente
+	for run.Status != "done" {
+		time.Sleep(c.PollInterval)
+		resp, err := c.Client.Get(agent + "/runs/" + run.ID)
+		if err != nil {
+			return nil, err
+		}
+		if err := decode(resp, http.StatusOK, &run); err != nil {
+			return nil, err
+		}
+	}
+
+	resp, err = c.Client.Get(agent + "/runs/" + run.ID + "/results")
+	if err != nil {
+		return nil, err
+	}
+	var results []pool.Result
+	err = decode(resp, http.StatusOK, &results)
+	return results, err
+}
+
+func decode(resp *http.Response, expected int, v interface{}) error {
+	defer resp.Body.Close()
+	if resp.StatusCode != expected {
+		return fmt.Errorf("agent returned status %d for %s", resp.StatusCode, resp.Request.URL)
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
@@ -0,0 +1,261 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/url"
+	"sort"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+	"github.com/joaomarcelofa/entendendo-worker-pool/report"
+	"github.com/joaomarcelofa/entendendo-worker-pool/sink"
+	"github.com/joaomarcelofa/entendendo-worker-pool/urls"
+)
+
+// runCompare executa duas listas de URLs com as mesmas configurações do pool e compara os tempos
+// em pares, por exemplo para comparar homologação e produção ou dois provedores de CDN
+func runCompare(args []string) error {
+	var reports, sinkSpecs stringList
+	fs := flag.NewFlagSet("compare", flag.ExitOnError)
+	sinkFlag(fs, &sinkSpecs)
+	labels := labelFlag(fs)
+	by := fs.String("by", "position", "how URLs are paired: \"position\" (line by line) or \"path\" (same path and query)")
+	qtyWorkers := fs.Int("workers", 8, "number of workers")
+	timeout := fs.Int("timeout", 5, "HTTP client timeout in seconds")
+	fs.Var(&reports, "report", "write a report of the comparison to this file (the extension picks the format: .json, .html, .md, .csv or .txt); may be repeated")
+	dryRun := dryRunFlag(fs)
+	format := formatFlag(fs)
+	watch := fs.Bool("watch", false, "keep running: repeat the comparison whenever one of the list files or the -config file changes")
+	watchInterval := fs.Duration("watch-interval", time.Second, "how often the watched files are checked for changes in -watch mode")
+	tags := tagsFlag(fs)
+	pc := pacingFlags(fs)
+	probes := probeFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if fs.NArg() != 2 {
+		return errors.New("usage: compare [flags] listA.txt listB.txt")
+	}
+	if *by != "position" && *by != "path" {
+		return fmt.Errorf("invalid -by %q", *by)
+	}
+	if _, err := probes.visit(*timeout); err != nil {
+		return err
+	}
+	// No dry-run nenhum resultado é gerado, então os sinks e a saída formatada não são abertos
+	var sinks *sink.Multi
+	var output *reportOutput
+	if !*dryRun {
+		var err error
+		if sinks, err = openSinks(sinkSpecs, labels); err != nil {
+			return err
+		}
+		if output, err = startOutput(*format); err != nil {
+			return err
+		}
+		defer output.restore()
+	}
+
+	session := &compareSession{
+		fs:         fs,
+		by:         *by,
+		qtyWorkers: *qtyWorkers,
+		tags:       *tags,
+		timeout:    *timeout,
+		reports:    reports,
+		dryRun:     *dryRun,
+		pacing:     pc,
+		probes:     probes,
+		sinks:      sinks,
+		labels:     labels,
+		output:     output,
+	}
+	if *watch {
+		watched := fs.Args()
+		if pc.config.path != "" {
+			watched = append(watched, pc.config.path)
+		}
+		return watchFiles(watched, *watchInterval, session.run)
+	}
+	return session.run()
+}
+
+// compareSession guarda as configurações do comando compare, permitindo repetir a comparação a cada
+// alteração das listas no modo -watch
+type compareSession struct {
+	fs         *flag.FlagSet
+	by         string
+	qtyWorkers int
+	tags       string
+	timeout    int
+	reports    []string
+	dryRun     bool
+	pacing     *pacing
+	probes     *probeConfig
+	sinks      *sink.Multi
+	labels     labelList
+	output     *reportOutput
+}
+
+// run lê as duas listas (e o arquivo de -config, que pode ter sido alterado) e executa a comparação
+func (s *compareSession) run() error {
+	fs := s.fs
+	if err := s.pacing.reload(); err != nil {
+		return err
+	}
+	var inputs [2][]pool.Job
+	for i, path := range fs.Args() {
+		jobs, err := urls.ReadFile(path)
+		if err == nil {
+			jobs, err = urls.Expand(jobs)
+		}
+		if err != nil {
+			return err
+		}
+		if jobs, err = filterTags(jobs, s.tags); err != nil {
+			return fmt.Errorf("%s: %v", path, err)
+		}
+		inputs[i] = jobs
+	}
+	if s.dryRun {
+		printSettings(fs)
+		invalid := printJobs("A: "+fs.Arg(0), inputs[0], time.Duration(s.timeout)*time.Second)
+		invalid += printJobs("B: "+fs.Arg(1), inputs[1], time.Duration(s.timeout)*time.Second)
+		return planError(invalid)
+	}
+
+	// Cada lista é executada por um pool novo com as mesmas configurações, uma depois da outra,
+	// para que uma não interfira nas medições da outra
+	rep := report.New()
+	rep.Labels = s.labels
+	var sides [2]map[string]pool.Result
+	var lists [2][]string
+	for i, path := range fs.Args() {
+		jobs := inputs[i]
+		list := urls.URLs(jobs)
+		lists[i] = list
+		p, err := s.probes.newPool(s.qtyWorkers, s.timeout)
+		if err != nil {
+			return err
+		}
+		s.pacing.apply(p)
+
+		fmt.Printf("Running %s (%d URL(s))\n", path, len(list))
+		start := time.Now()
+		results := p.Collect(jobs)
+		elapsed := time.Since(start)
+		p.Close()
+
+		// Os resultados chegam na ordem de conclusão; a posição é recuperada pela URL
+		positions := make(map[string][]int)
+		for pos, u := range list {
+			positions[u] = append(positions[u], pos)
+		}
+		sides[i] = make(map[string]pool.Result)
+		var fastest pool.Result
+		for _, result := range results {
+			writeResult(s.sinks, result)
+			pos := positions[result.URL][0]
+			positions[result.URL] = positions[result.URL][1:]
+			key := pairKey(s.by, pos, result.URL)
+			sides[i][key] = result
+			if result.Err == nil && (fastest.TimeTooked == 0 || result.TimeTooked < fastest.TimeTooked) {
+				fastest = result
+			}
+		}
+		rep.Add(report.Method{Name: path, Elapsed: elapsed, Fastest: fastest, Results: results})
+	}
+	flushSinks(s.sinks)
+
+	// Segue a ordem do primeiro arquivo e inclui depois os pares que só existem no segundo
+	var order []string
+	seen := make(map[string]bool)
+	for _, list := range lists {
+		for pos, u := range list {
+			if key := pairKey(s.by, pos, u); !seen[key] {
+				seen[key] = true
+				order = append(order, key)
+			}
+		}
+	}
+	printComparison(fs.Arg(0), fs.Arg(1), order, sides)
+	if err := s.output.write(rep); err != nil {
+		return err
+	}
+
+	for _, path := range s.reports {
+		if err := rep.WriteFile(path); err != nil {
+			return err
+		}
+		fmt.Printf("Report written to %s\n", path)
+	}
+	return nil
+}
+
+// pairKey é a chave usada para encontrar o par de uma URL na outra lista
+func pairKey(by string, pos int, rawURL string) string {
+	if by == "position" {
+		return fmt.Sprintf("#%d", pos+1)
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	key := u.EscapedPath()
+	if key == "" {
+		key = "/"
+	}
+	if u.RawQuery != "" {
+		key += "?" + u.RawQuery
+	}
+	return key
+}
+
+func printComparison(nameA, nameB string, order []string, sides [2]map[string]pool.Result) {
+	fmt.Printf("\n%-40s %14s %14s %14s\n", "Pair", "A", "B", "B - A")
+	var timesA, timesB []time.Duration
+	fasterB := 0
+	for _, key := range order {
+		a, okA := sides[0][key]
+		b, okB := sides[1][key]
+		fmt.Printf("%-40s %14s %14s", key, formatSide(a, okA), formatSide(b, okB))
+		if okA && okB && a.Err == nil && b.Err == nil {
+			fmt.Printf(" %14s", b.TimeTooked-a.TimeTooked)
+			timesA = append(timesA, a.TimeTooked)
+			timesB = append(timesB, b.TimeTooked)
+			if b.TimeTooked < a.TimeTooked {
+				fasterB++
+			}
+		}
+		fmt.Println()
+	}
+
+	fmt.Printf("\nA: %s\nB: %s\n", nameA, nameB)
+	if len(timesA) == 0 {
+		fmt.Println("No successful pairs to compare")
+		return
+	}
+	fmt.Printf("Successful pairs: %d\n", len(timesA))
+	fmt.Printf("Median time:      A %s, B %s\n", median(timesA), median(timesB))
+	fmt.Printf("B faster in %d of %d pairs\n", fasterB, len(timesA))
+}
+
+func formatSide(r pool.Result, ok bool) string {
+	switch {
+	case !ok:
+		return "-"
+	case r.Err != nil:
+		return "error"
+	default:
+		return r.TimeTooked.String()
+	}
+}
+
+func median(times []time.Duration) time.Duration {
+	sorted := append([]time.Duration(nil), times...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	return sorted[len(sorted)/2]
+}
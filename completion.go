@@ -0,0 +1,192 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// errDescribed interrompe um comando logo após o registro das suas flags (ver parseFlags)
+var errDescribed = errors.New("flags described")
+
+// describing, quando informada, recebe o FlagSet de cada comando no lugar da interpretação dos
+// argumentos; é assim que a geração do autocompletar descobre as flags de todos os comandos
+var describing func(fs *flag.FlagSet)
+
+// parseFlags interpreta os argumentos de um comando. Durante a geração do autocompletar, as flags
+// registradas são apenas coletadas e o comando é interrompido antes de executar qualquer coisa
+func parseFlags(fs *flag.FlagSet, args []string) error {
+	if describing != nil {
+		describing(fs)
+		return errDescribed
+	}
+	return fs.Parse(args)
+}
+
+// commandFlags são as flags aceitas por um comando
+type commandFlags struct {
+	name  string
+	flags []*flag.Flag
+}
+
+// describeCommands coleta as flags da execução padrão e de cada subcomando
+func describeCommands() (root []*flag.Flag, commands []commandFlags, err error) {
+	var collected []*flag.Flag
+	describing = func(fs *flag.FlagSet) {
+		collected = nil
+		fs.VisitAll(func(f *flag.Flag) { collected = append(collected, f) })
+	}
+	defer func() { describing = nil }()
+
+	if err := runComparison(nil); err != errDescribed {
+		return nil, nil, fmt.Errorf("describing the default run: %v", err)
+	}
+	root = collected
+	for _, name := range commandNames {
+		collected = nil
+		if err := runCommand(name, nil); err != errDescribed {
+			return nil, nil, fmt.Errorf("describing command %s: %v", name, err)
+		}
+		commands = append(commands, commandFlags{name: name, flags: collected})
+	}
+	return root, commands, nil
+}
+
+// runCompletion gera o script de autocompletar para o shell informado, que pode ser carregado com,
+// por exemplo, source <(entendendo-worker-pool completion bash)
+func runCompletion(args []string) error {
+	fs := flag.NewFlagSet("completion", flag.ExitOnError)
+	name := fs.String("name", "entendendo-worker-pool", "name of the executable the completion is registered for")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		return errors.New("usage: completion [flags] bash|zsh|fish")
+	}
+
+	root, commands, err := describeCommands()
+	if err != nil {
+		return err
+	}
+	var script string
+	switch fs.Arg(0) {
+	case "bash":
+		script = bashCompletion(*name, root, commands)
+	case "zsh":
+		script = zshCompletion(*name, root, commands)
+	case "fish":
+		script = fishCompletion(*name, root, commands)
+	default:
+		return fmt.Errorf("unsupported shell %q (expected bash, zsh or fish)", fs.Arg(0))
+	}
+	fmt.Print(script)
+	return nil
+}
+
+var nonIdentifier = regexp.MustCompile(`[^A-Za-z0-9_]`)
+
+// completionFunc é o nome da função de autocompletar, já que o nome do executável pode ter hífens
+func completionFunc(name string) string {
+	return "_" + nonIdentifier.ReplaceAllString(name, "_")
+}
+
+func flagNames(flags []*flag.Flag) string {
+	names := make([]string, len(flags))
+	for i, f := range flags {
+		names[i] = "-" + f.Name
+	}
+	return strings.Join(names, " ")
+}
+
+func bashCompletion(name string, root []*flag.Flag, commands []commandFlags) string {
+	var b strings.Builder
+	fn := completionFunc(name)
+	fmt.Fprintf(&b, "# bash completion for %s\n", name)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("\tlocal cur=\"${COMP_WORDS[COMP_CWORD]}\" cmd=\"\" flags=\"\"\n")
+	b.WriteString("\t[ \"$COMP_CWORD\" -gt 1 ] && cmd=\"${COMP_WORDS[1]}\"\n")
+	b.WriteString("\tcase \"$cmd\" in\n")
+	for _, c := range commands {
+		fmt.Fprintf(&b, "\t%s) flags=%q ;;\n", c.name, flagNames(c.flags))
+	}
+	fmt.Fprintf(&b, "\t*) flags=%q ;;\n", flagNames(root))
+	b.WriteString("\tesac\n")
+	b.WriteString("\tif [[ \"$cur\" == -* ]]; then\n")
+	b.WriteString("\t\tCOMPREPLY=($(compgen -W \"$flags\" -- \"$cur\"))\n")
+	b.WriteString("\telif [ \"$COMP_CWORD\" -eq 1 ]; then\n")
+	fmt.Fprintf(&b, "\t\tCOMPREPLY=($(compgen -W %q -- \"$cur\"))\n", strings.Join(commandNames, " "))
+	b.WriteString("\telse\n")
+	b.WriteString("\t\tCOMPREPLY=($(compgen -f -- \"$cur\"))\n")
+	b.WriteString("\tfi\n")
+	b.WriteString("}\n")
+	fmt.Fprintf(&b, "complete -o filenames -F %s %s\n", fn, name)
+	return b.String()
+}
+
+// zshDescribe monta os itens "nome:descrição" aceitos pelo _describe do zsh
+func zshDescribe(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = "'" + strings.ReplaceAll(item, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
+
+func zshFlags(flags []*flag.Flag) string {
+	items := make([]string, len(flags))
+	for i, f := range flags {
+		items[i] = "-" + f.Name + ":" + strings.ReplaceAll(f.Usage, ":", `\:`)
+	}
+	return zshDescribe(items)
+}
+
+func zshCompletion(name string, root []*flag.Flag, commands []commandFlags) string {
+	var b strings.Builder
+	fn := completionFunc(name)
+	fmt.Fprintf(&b, "#compdef %s\n\n", name)
+	fmt.Fprintf(&b, "%s() {\n", fn)
+	b.WriteString("\tlocal cmd=\"\"\n")
+	b.WriteString("\tlocal -a flags commands\n")
+	b.WriteString("\t(( CURRENT > 2 )) && cmd=$words[2]\n")
+	b.WriteString("\tcase $cmd in\n")
+	for _, c := range commands {
+		fmt.Fprintf(&b, "\t%s) flags=(%s) ;;\n", c.name, zshFlags(c.flags))
+	}
+	fmt.Fprintf(&b, "\t*) flags=(%s) ;;\n", zshFlags(root))
+	b.WriteString("\tesac\n")
+	fmt.Fprintf(&b, "\tcommands=(%s)\n", zshDescribe(commandNames))
+	b.WriteString("\tif [[ $words[CURRENT] == -* ]]; then\n")
+	b.WriteString("\t\t_describe 'flag' flags\n")
+	b.WriteString("\telif (( CURRENT == 2 )); then\n")
+	b.WriteString("\t\t_describe 'command' commands\n")
+	b.WriteString("\telse\n")
+	b.WriteString("\t\t_files\n")
+	b.WriteString("\tfi\n")
+	b.WriteString("}\n\n")
+	fmt.Fprintf(&b, "compdef %s %s\n", fn, name)
+	return b.String()
+}
+
+// fishQuote coloca o texto entre aspas simples, no formato aceito pelo fish
+func fishQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return "'" + strings.ReplaceAll(s, "'", `\'`) + "'"
+}
+
+func fishCompletion(name string, root []*flag.Flag, commands []commandFlags) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# fish completion for %s\n", name)
+	fmt.Fprintf(&b, "complete -c %s -f -n __fish_use_subcommand -a %s\n", name, fishQuote(strings.Join(commandNames, " ")))
+	// As flags do Go usam um único hífen, que no fish corresponde às opções do tipo -o
+	for _, f := range root {
+		fmt.Fprintf(&b, "complete -c %s -n __fish_use_subcommand -o %s -d %s\n", name, f.Name, fishQuote(f.Usage))
+	}
+	for _, c := range commands {
+		for _, f := range c.flags {
+			fmt.Fprintf(&b, "complete -c %s -n %s -o %s -d %s\n", name, fishQuote("__fish_seen_subcommand_from "+c.name), f.Name, fishQuote(f.Usage))
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,141 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Config é o arquivo de configuração em JSON informado com a flag -config, por exemplo:
+//
+//	{
+//	  "ratelimits": {"example.com": "2rps", "api.foo.com": "10rps"},
+//	  "groups": [{"name": "critical", "weight": 3, "match": ["api.foo.com", "https://example.com/checkout"]}]
+//	}
+type Config struct {
+	// RateLimits limita a taxa de requisições de cada host, para que hosts frágeis sejam tratados com
+	// cuidado enquanto os demais são medidos na velocidade normal
+	RateLimits map[string]Rate `json:"ratelimits"`
+	// Groups dá pesos a grupos de URLs, para que os grupos mais importantes sejam amostrados com mais
+	// frequência nos modos contínuos
+	Groups []Group `json:"groups"`
+}
+
+// Group é um grupo de URLs com um peso. Cada item de Match é uma URL completa, comparada
+// exatamente, ou um host, que inclui todas as URLs dele
+type Group struct {
+	Name   string   `json:"name"`
+	Weight int      `json:"weight"`
+	Match  []string `json:"match"`
+}
+
+// Matches informa se a URL faz parte do grupo
+func (g Group) Matches(rawURL string) bool {
+	host := ""
+	if u, err := url.Parse(rawURL); err == nil {
+		host = strings.ToLower(u.Hostname())
+	}
+	for _, m := range g.Match {
+		if m == rawURL || (!strings.Contains(m, "/") && strings.EqualFold(m, host)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Weight devolve o maior peso entre os grupos de que a URL faz parte, ou zero quando ela não está em
+// nenhum grupo
+func (c *Config) Weight(rawURL string) int {
+	weight := 0
+	for _, g := range c.Groups {
+		if g.Weight > weight && g.Matches(rawURL) {
+			weight = g.Weight
+		}
+	}
+	return weight
+}
+
+// Rate é uma taxa em requisições por segundo. No JSON, pode ser um número ou um texto com a unidade:
+// "2rps" (por segundo) ou "30rpm" (por minuto)
+type Rate float64
+
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	var value float64
+	if err := json.Unmarshal(data, &value); err == nil {
+		return r.set(value, string(data))
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return fmt.Errorf("invalid rate %s: expected a number or a text such as \"2rps\"", data)
+	}
+	parsed, err := ParseRate(text)
+	if err != nil {
+		return err
+	}
+	*r = parsed
+	return nil
+}
+
+func (r *Rate) set(value float64, text string) error {
+	if value <= 0 {
+		return fmt.Errorf("invalid rate %s: must be greater than zero", text)
+	}
+	*r = Rate(value)
+	return nil
+}
+
+// ParseRate interpreta uma taxa como "2rps", "30rpm" ou "2" (requisições por segundo)
+func ParseRate(text string) (Rate, error) {
+	s := strings.ToLower(strings.TrimSpace(text))
+	per := time.Second
+	switch {
+	case strings.HasSuffix(s, "rps"):
+		s = strings.TrimSuffix(s, "rps")
+	case strings.HasSuffix(s, "rpm"):
+		s, per = strings.TrimSuffix(s, "rpm"), time.Minute
+	}
+	value, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid rate %q: expected a value such as 2rps or 30rpm", text)
+	}
+	var r Rate
+	if err := r.set(value*float64(time.Second)/float64(per), strconv.Quote(text)); err != nil {
+		return 0, err
+	}
+	return r, nil
+}
+
+func (r Rate) String() string {
+	return strconv.FormatFloat(float64(r), 'f', -1, 64) + "rps"
+}
+
+// Load lê o arquivo de configuração. Campos desconhecidos são rejeitados, para que um erro de
+// digitação não seja ignorado silenciosamente
+func Load(path string) (*Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	cfg := &Config{}
+	if err := dec.Decode(cfg); err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	for host := range cfg.RateLimits {
+		if host == "" || strings.Contains(host, "/") {
+			return nil, fmt.Errorf("%s: invalid rate limit host %q: expected a host name such as example.com", path, host)
+		}
+	}
+	for i, g := range cfg.Groups {
+		if g.Name == "" || g.Weight < 1 || len(g.Match) == 0 {
+			return nil, fmt.Errorf("%s: invalid group #%d: name, weight (1 or more) and match are required", path, i+1)
+		}
+	}
+	return cfg, nil
+}
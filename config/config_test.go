@@ -0,0 +1,104 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseRate(t *testing.T) {
+	tests := []struct {
+		text    string
+		want    Rate
+		wantErr bool
+	}{
+		{text: "2rps", want: 2},
+		{text: "30rpm", want: 0.5},
+		{text: " 10 RPS ", want: 10},
+		{text: "0.5", want: 0.5},
+		{text: "0rps", wantErr: true},
+		{text: "-1rpm", wantErr: true},
+		{text: "fast", wantErr: true},
+		{text: "2/s", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := ParseRate(tt.text)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseRate(%q) = %v, want an error", tt.text, got)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseRate(%q) = %v, %v, want %v", tt.text, got, err, tt.want)
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		json    string
+		wantErr string
+		check   func(t *testing.T, cfg *Config)
+	}{
+		{
+			name: "rate limits and groups",
+			json: `{"ratelimits": {"example.com": "2rps", "api.foo.com": 10, "slow.test": "30rpm"},
+				"groups": [{"name": "critical", "weight": 3, "match": ["api.foo.com", "https://example.com/checkout"]},
+					{"name": "api", "weight": 2, "match": ["API.FOO.COM"]}]}`,
+			check: func(t *testing.T, cfg *Config) {
+				want := map[string]Rate{"example.com": 2, "api.foo.com": 10, "slow.test": 0.5}
+				for host, rate := range want {
+					if cfg.RateLimits[host] != rate {
+						t.Errorf("rate limit of %s = %v, want %v", host, cfg.RateLimits[host], rate)
+					}
+				}
+				weights := map[string]int{
+					"https://api.foo.com/v1/users":  3,
+					"https://example.com/checkout":  3,
+					"https://example.com/checkout/": 0,
+					"https://example.com/":          0,
+				}
+				for u, w := range weights {
+					if got := cfg.Weight(u); got != w {
+						t.Errorf("Weight(%s) = %d, want %d", u, got, w)
+					}
+				}
+			},
+		},
+		{name: "empty", json: `{}`},
+		{name: "unknown field", json: `{"ratelimit": {}}`, wantErr: "unknown field"},
+		{name: "invalid rate", json: `{"ratelimits": {"example.com": "fast"}}`, wantErr: "invalid rate"},
+		{name: "zero rate", json: `{"ratelimits": {"example.com": 0}}`, wantErr: "greater than zero"},
+		{name: "URL as rate limit host", json: `{"ratelimits": {"https://example.com/": "1rps"}}`, wantErr: "invalid rate limit host"},
+		{name: "group without weight", json: `{"groups": [{"name": "x", "match": ["a.com"]}]}`, wantErr: "invalid group #1"},
+		{name: "group without match", json: `{"groups": [{"name": "x", "weight": 2}]}`, wantErr: "invalid group #1"},
+		{name: "invalid JSON", json: `{"ratelimits": `, wantErr: "config.json"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.json")
+			if err := os.WriteFile(path, []byte(tt.json), 0644); err != nil {
+				t.Fatal(err)
+			}
+			cfg, err := Load(path)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Load error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if tt.check != nil {
+				tt.check(t, cfg)
+			}
+		})
+	}
+	if _, err := Load(filepath.Join(t.TempDir(), "missing.json")); err == nil {
+		t.Error("Load of a missing file did not fail")
+	}
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptrace"
+	"sort"
+	"sync"
+)
+
+// connStats conta, por host, as requisições que reaproveitaram uma conexão já aberta e as que
+// precisaram abrir uma nova. O reaproveitamento pesa bastante na comparação entre os métodos: sem
+// ele, cada requisição paga novamente a conexão TCP e o handshake TLS
+type connStats struct {
+	mux   sync.Mutex
+	hosts map[string]*hostConns
+}
+
+// hostConns são os contadores de um host
+type hostConns struct {
+	Reused int
+	Fresh  int
+}
+
+// track passa a registrar as conexões usadas pelo cliente, envolvendo o seu transporte
+func (s *connStats) track(client *http.Client) {
+	base := client.Transport
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	client.Transport = &tracingTransport{base: base, stats: s}
+}
+
+func (s *connStats) add(host string, reused bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	if s.hosts == nil {
+		s.hosts = make(map[string]*hostConns)
+	}
+	h := s.hosts[host]
+	if h == nil {
+		h = &hostConns{}
+		s.hosts[host] = h
+	}
+	if reused {
+		h.Reused++
+	} else {
+		h.Fresh++
+	}
+}
+
+// total soma os contadores de todos os hosts
+func (s *connStats) total() hostConns {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	var total hostConns
+	for _, h := range s.hosts {
+		total.Reused += h.Reused
+		total.Fresh += h.Fresh
+	}
+	return total
+}
+
+// print mostra o total e, com perHost, os contadores de cada host
+func (s *connStats) print(perHost bool) {
+	total := s.total()
+	fmt.Printf("Connections: %d reused, %d new\n", total.Reused, total.Fresh)
+	if !perHost {
+		return
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	hosts := make([]string, 0, len(s.hosts))
+	for host := range s.hosts {
+		hosts = append(hosts, host)
+	}
+	sort.Strings(hosts)
+	fmt.Printf("%-40s %8s %8s\n", "Host", "Reused", "New")
+	for _, host := range hosts {
+		fmt.Printf("%-40s %8d %8d\n", host, s.hosts[host].Reused, s.hosts[host].Fresh)
+	}
+}
+
+// tracingTransport registra, via httptrace, se cada requisição reaproveitou uma conexão. Cada
+// redirecionamento passa pelo transporte e é contado no host de destino
+type tracingTransport struct {
+	base  http.RoundTripper
+	stats *connStats
+}
+
+func (t *tracingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := req.URL.Host
+	trace := &httptrace.ClientTrace{
+		GotConn: func(info httptrace.GotConnInfo) {
+			t.stats.add(host, info.Reused)
+		},
+	}
+	return t.base.RoundTrip(req.WithContext(httptrace.WithClientTrace(req.Context(), trace)))
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/jobsource"
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// runConsume consome jobs de uma fila externa, executando-os no worker pool
+func runConsume(args []string) error {
+	var sinkSpecs stringList
+	fs := flag.NewFlagSet("consume", flag.ExitOnError)
+	sinkFlag(fs, &sinkSpecs)
+	labels := labelFlag(fs)
+	from := fs.String("from", "", "job source (e.g. redis://localhost:6379/0?queue=jobs)")
+	qtyWorkers := fs.Int("workers", 8, "number of workers")
+	timeout := fs.Int("timeout", 5, "HTTP client timeout in seconds")
+	pc := pacingFlags(fs)
+	probes := probeFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *from == "" {
+		return errors.New("no job source informed (-from)")
+	}
+
+	sinks, err := openSinks(sinkSpecs, labels, "stdout")
+	if err != nil {
+		return err
+	}
+	// Como o consumo não tem fim, os sinks são descarregados a cada segundo
+	go func() {
+		for range time.Tick(time.Second) {
+			flushSinks(sinks)
+		}
+	}()
+
+	source, err := jobsource.Open(*from)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	p, err := probes.newPool(*qtyWorkers, *timeout)
+	if err != nil {
+		return err
+	}
+	pc.apply(p)
+	defer p.Close()
+
+	fmt.Printf("Consuming jobs from %s\n", *from)
+	for {
+		delivery, err := source.Next()
+		if err != nil {
+			return err
+		}
+		// Submit bloqueia enquanto a fila do pool estiver cheia, então novos jobs só são retirados
+		// da fila externa quando houver workers disponíveis
+		reply := make(chan pool.Result, 1)
+		p.Submit(delivery.Job(), reply)
+		go func(delivery jobsource.Delivery) {
+			result := <-reply
+			writeResult(sinks, result)
+			if err := delivery.Done(result); err != nil {
+				fmt.Printf("Error at acknowledging job %s\nError: %s\n", result.URL, err.Error())
+			}
+		}(delivery)
+	}
+}
+
+// runEnqueue adiciona a lista de URLs a uma fila externa
+func runEnqueue(args []string) error {
+	fs := flag.NewFlagSet("enqueue", flag.ExitOnError)
+	to := fs.String("to", "", "job queue (e.g. redis://localhost:6379/0?queue=jobs)")
+	profile := profileFlag(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *to == "" {
+		return errors.New("no job queue informed (-to)")
+	}
+
+	list, err := profileURLs(*profile)
+	if err != nil {
+		return err
+	}
+
+	source, err := jobsource.Open(*to)
+	if err != nil {
+		return err
+	}
+	defer source.Close()
+
+	if err := source.Push(list...); err != nil {
+		return err
+	}
+	fmt.Printf("%d job(s) enqueued to %s\n", len(list), *to)
+	return nil
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/cluster"
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+	"github.com/joaomarcelofa/entendendo-worker-pool/report"
+)
+
+func runCoordinate(args []string) error {
+	var reports, sinkSpecs stringList
+	fs := flag.NewFlagSet("coordinate", flag.ExitOnError)
+	sinkFlag(fs, &sinkSpecs)
+	labels := labelFlag(fs)
+	agents := fs.String("agents", "", "comma separated list of agent base URLs (agents run the serve command)")
+	replicate := fs.Bool("replicate", false, "send the whole list to every agent instead of splitting it")
+	format := formatFlag(fs)
+	profile := profileFlag(fs)
+	fs.Var(&reports, "report", "write a report of the run to this file (the extension picks the format: .json, .html, .md, .csv or .txt); may be repeated")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *agents == "" {
+		return errors.New("no agents informed (-agents)")
+	}
+	list, err := profileURLs(*profile)
+	if err != nil {
+		return err
+	}
+	sinks, err := openSinks(sinkSpecs, labels, "stdout")
+	if err != nil {
+		return err
+	}
+	output, err := startOutput(*format)
+	if err != nil {
+		return err
+	}
+	defer output.restore()
+	coordinator := cluster.NewCoordinator(strings.Split(*agents, ","))
+	coordinator.Replicate = *replicate
+
+	rep := report.New()
+	rep.Labels = labels
+	for _, agentResult := range coordinator.Run(list) {
+		fmt.Printf("Agent %s\n", agentResult.Agent)
+		if agentResult.Err != nil {
+			fmt.Printf("Error at running on agent %s\nError: %s\n", agentResult.Agent, agentResult.Err.Error())
+			continue
+		}
+
+		// Encontra a URL mais rápida vista pelo agente
+		var fastest pool.Result
+		for _, r := range agentResult.Results {
+			writeResult(sinks, r)
+			if r.Err != nil {
+				continue
+			}
+			if fastest.TimeTooked == 0 || r.TimeTooked < fastest.TimeTooked {
+				fastest = r
+			}
+		}
+		fmt.Printf("Fastest URL: %s - %s\n", fastest.URL, fastest.TimeTooked)
+		fmt.Printf("Total time tooked on agent: %s\n\n", agentResult.Elapsed)
+
+		rep.Add(report.Method{
+			Name:    "Agent " + agentResult.Agent,
+			Elapsed: agentResult.Elapsed,
+			Fastest: fastest,
+			Results: agentResult.Results,
+		})
+	}
+
+	flushSinks(sinks)
+	if err := output.write(rep); err != nil {
+		return err
+	}
+
+	for _, path := range reports {
+		if err := rep.WriteFile(path); err != nil {
+			return err
+		}
+		fmt.Printf("Report written to %s\n", path)
+	}
+	return nil
+}
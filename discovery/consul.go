@@ -0,0 +1,89 @@
+package discovery
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// consul lista as instâncias saudáveis de um serviço pela API de health do Consul
+// (/v1/health/service/<serviço>?passing=true). As tags do serviço no Consul viram as tags dos jobs.
+// Opções: tag (somente as instâncias com a tag), dc (datacenter) e tls=true para falar com o agente
+// por HTTPS. O token é lido de CONSUL_HTTP_TOKEN
+type consul struct {
+	endpoint string
+	token    string
+	target   targetOptions
+	client   *http.Client
+	tracker  tracker
+}
+
+func newConsul(u *url.URL, target targetOptions) (Source, error) {
+	service := strings.Trim(u.Path, "/")
+	if u.Host == "" || service == "" {
+		return nil, errors.New("consul discovery expects consul://host:port/<service>")
+	}
+	apiScheme := "http"
+	if u.Query().Get("tls") == "true" {
+		apiScheme = "https"
+	}
+	query := url.Values{"passing": {"true"}}
+	for _, option := range []string{"tag", "dc"} {
+		if v := u.Query().Get(option); v != "" {
+			query.Set(option, v)
+		}
+	}
+	return &consul{
+		endpoint: fmt.Sprintf("%s://%s/v1/health/service/%s?%s", apiScheme, u.Host, url.PathEscape(service), query.Encode()),
+		token:    os.Getenv("CONSUL_HTTP_TOKEN"),
+		target:   target,
+		client:   &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+func (c *consul) Fetch() ([]pool.Job, bool, error) {
+	req, err := http.NewRequest("GET", c.endpoint, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	if c.token != "" {
+		req.Header.Set("X-Consul-Token", c.token)
+	}
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("consul: status code %d", resp.StatusCode)
+	}
+	var entries []struct {
+		Node struct {
+			Address string
+		}
+		Service struct {
+			Address string
+			Port    int
+			Tags    []string
+		}
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&entries); err != nil {
+		return nil, false, fmt.Errorf("consul: %v", err)
+	}
+	jobs := make([]pool.Job, 0, len(entries))
+	for _, entry := range entries {
+		// Sem um endereço próprio, o serviço responde no endereço do nó
+		host := entry.Service.Address
+		if host == "" {
+			host = entry.Node.Address
+		}
+		jobs = append(jobs, pool.Job{URL: c.target.url(host, entry.Service.Port), Tags: entry.Service.Tags})
+	}
+	return jobs, c.tracker.changed(jobs), nil
+}
@@ -0,0 +1,80 @@
+package discovery
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// Source lista as instâncias de um serviço registradas em um catálogo (Consul, etcd...) e gera um job
+// de health check para cada uma, para que o pool meça as instâncias reais em vez de uma lista fixa
+type Source interface {
+	// Fetch consulta o catálogo e devolve um job por instância; changed é falso quando as instâncias
+	// são as mesmas da consulta anterior
+	Fetch() (jobs []pool.Job, changed bool, err error)
+}
+
+// requestTimeout é o timeout das consultas ao catálogo
+const requestTimeout = 10 * time.Second
+
+// Open cria a fonte de acordo com o esquema do endereço, por exemplo
+// consul://localhost:8500/web?path=/health, etcd://localhost:2379/services/web?path=/health ou
+// kube:///services?namespace=prod&selector=app=web.
+// As opções comuns são path (o caminho do health check, por padrão /) e scheme (http ou https, o
+// protocolo usado para falar com as instâncias)
+func Open(address string) (Source, error) {
+	u, err := url.Parse(address)
+	if err != nil {
+		return nil, err
+	}
+	target := targetOptions{scheme: "http", path: "/"}
+	if v := u.Query().Get("scheme"); v != "" {
+		target.scheme = v
+	}
+	if v := u.Query().Get("path"); v != "" {
+		target.path = "/" + strings.TrimPrefix(v, "/")
+	}
+	switch u.Scheme {
+	case "consul":
+		return newConsul(u, target)
+	case "etcd":
+		return newEtcd(u, target)
+	case "kube":
+		return newKube(u, target)
+	default:
+		return nil, fmt.Errorf("unsupported discovery backend %q", u.Scheme)
+	}
+}
+
+// targetOptions define como a URL de health check de cada instância é montada
+type targetOptions struct {
+	scheme string
+	path   string
+}
+
+func (t targetOptions) url(host string, port int) string {
+	return t.scheme + "://" + net.JoinHostPort(host, strconv.Itoa(port)) + t.path
+}
+
+// tracker detecta se as instâncias mudaram entre duas consultas
+type tracker struct {
+	last string
+}
+
+func (t *tracker) changed(jobs []pool.Job) bool {
+	keys := make([]string, len(jobs))
+	for i, job := range jobs {
+		keys[i] = job.URL + " " + strings.Join(job.Tags, ",")
+	}
+	sort.Strings(keys)
+	current := strings.Join(keys, "\n")
+	changed := current != t.last
+	t.last = current
+	return changed
+}
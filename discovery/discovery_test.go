@@ -0,0 +1,244 @@
+package discovery
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// serve responde cada caminho com o corpo informado, guardando as requisições recebidas
+func serve(t *testing.T, routes map[string]string) (string, *[]*http.Request) {
+	t.Helper()
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		body, ok := routes[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	return strings.TrimPrefix(server.URL, "http://"), &requests
+}
+
+func fetch(t *testing.T, address string) []pool.Job {
+	t.Helper()
+	source, err := Open(address)
+	if err != nil {
+		t.Fatal(err)
+	}
+	jobs, changed, err := source.Fetch()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) > 0 && !changed {
+		t.Error("first Fetch reported no change")
+	}
+	if _, changed, _ := source.Fetch(); changed {
+		t.Error("second Fetch of the same instances reported a change")
+	}
+	return jobs
+}
+
+const ingressList = `{"items": [
+	{"metadata": {"namespace": "prod"}, "spec": {
+		"tls": [{"hosts": ["secure.example.com"]}],
+		"rules": [{"host": "secure.example.com"}, {"host": "plain.example.com"}, {"host": "*.example.com"}, {}]
+	}},
+	{"metadata": {"namespace": "staging"}, "spec": {"rules": [{"host": "plain.example.com"}]}}
+]}`
+
+func TestKubeIngresses(t *testing.T) {
+	host, requests := serve(t, map[string]string{"/apis/networking.k8s.io/v1/ingresses": ingressList})
+	tests := []struct {
+		query string
+		want  []pool.Job
+	}{
+		// Os hosts da seção tls são medidos por HTTPS; curingas e regras sem host são ignorados e o
+		// host repetido em outro Ingress aparece uma vez
+		{"?path=/health", []pool.Job{
+			{URL: "https://secure.example.com/health", Tags: []string{"prod"}},
+			{URL: "http://plain.example.com/health", Tags: []string{"prod"}},
+		}},
+		// Com scheme informado, ele vale para todos os hosts
+		{"?scheme=http", []pool.Job{
+			{URL: "http://secure.example.com/", Tags: []string{"prod"}},
+			{URL: "http://plain.example.com/", Tags: []string{"prod"}},
+		}},
+	}
+	for _, tt := range tests {
+		if got := fetch(t, "kube://"+host+"/ingresses"+tt.query); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %+v, want %+v", tt.query, got, tt.want)
+		}
+	}
+	if len(*requests) == 0 || (*requests)[0].Header.Get("Authorization") != "" {
+		t.Error("the plain HTTP API was called with credentials")
+	}
+}
+
+const endpointList = `{"items": [
+	{"metadata": {"namespace": "prod"}, "subsets": [
+		{"addresses": [{"ip": "10.0.0.1"}, {"ip": "10.0.0.2"}], "ports": [{"name": "http", "port": 8080}, {"name": "metrics", "port": 9090}]}
+	]},
+	{"metadata": {"namespace": "prod"}, "subsets": [
+		{"addresses": [{"ip": "fd00::3"}], "ports": [{"name": "http", "port": 80}]}
+	]}
+]}`
+
+func TestKubeEndpoints(t *testing.T) {
+	host, requests := serve(t, map[string]string{
+		"/api/v1/endpoints":                 endpointList,
+		"/api/v1/namespaces/prod/endpoints": endpointList,
+	})
+	tests := []struct {
+		query string
+		want  []string
+	}{
+		{"", []string{"http://10.0.0.1:8080/", "http://10.0.0.2:8080/", "http://10.0.0.1:9090/", "http://10.0.0.2:9090/", "http://[fd00::3]:80/"}},
+		{"?port=http&path=health", []string{"http://10.0.0.1:8080/health", "http://10.0.0.2:8080/health", "http://[fd00::3]:80/health"}},
+		{"?port=9090", []string{"http://10.0.0.1:9090/", "http://10.0.0.2:9090/"}},
+		{"?port=grpc", nil},
+	}
+	for _, tt := range tests {
+		var got []string
+		for _, job := range fetch(t, "kube://"+host+"/services"+tt.query) {
+			got = append(got, job.URL)
+			if !reflect.DeepEqual(job.Tags, []string{"prod"}) {
+				t.Errorf("%s: %s tags = %v, want the namespace", tt.query, job.URL, job.Tags)
+			}
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%q: got %v, want %v", tt.query, got, tt.want)
+		}
+	}
+
+	*requests = nil
+	fetch(t, "kube://"+host+"/services?namespace=prod&selector=app=web")
+	if r := (*requests)[0]; r.URL.Path != "/api/v1/namespaces/prod/endpoints" || r.URL.Query().Get("labelSelector") != "app=web" {
+		t.Errorf("request = %s, want the prod namespace with the label selector", r.URL)
+	}
+}
+
+func TestKubeErrors(t *testing.T) {
+	if _, err := Open("kube://127.0.0.1:1/pods"); err == nil {
+		t.Error("unsupported resource accepted")
+	}
+	t.Setenv("KUBERNETES_SERVICE_HOST", "")
+	if _, err := Open("kube:///services"); err == nil || !strings.Contains(err.Error(), "kubectl proxy") {
+		t.Errorf("outside a cluster error = %v, want a hint about kubectl proxy", err)
+	}
+	host, _ := serve(t, nil)
+	source, err := Open("kube://" + host + "/services")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := source.Fetch(); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Errorf("Fetch error = %v, want the status code", err)
+	}
+}
+
+func TestConsul(t *testing.T) {
+	entries := `[
+		{"Node": {"Address": "10.0.0.1"}, "Service": {"Address": "10.1.0.1", "Port": 8080, "Tags": ["v2"]}},
+		{"Node": {"Address": "10.0.0.2"}, "Service": {"Address": "", "Port": 8081}}
+	]`
+	host, requests := serve(t, map[string]string{"/v1/health/service/web": entries})
+	t.Setenv("CONSUL_HTTP_TOKEN", "secret")
+
+	got := fetch(t, "consul://"+host+"/web?path=/health&tag=v2&dc=east")
+	want := []pool.Job{
+		{URL: "http://10.1.0.1:8080/health", Tags: []string{"v2"}},
+		// Sem endereço do serviço, é usado o endereço do nó
+		{URL: "http://10.0.0.2:8081/health"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+	r := (*requests)[0]
+	if q := r.URL.Query(); q.Get("passing") != "true" || q.Get("tag") != "v2" || q.Get("dc") != "east" {
+		t.Errorf("query = %s, want passing, tag and dc", r.URL.RawQuery)
+	}
+	if r.Header.Get("X-Consul-Token") != "secret" {
+		t.Error("the Consul token was not sent")
+	}
+}
+
+func TestEtcd(t *testing.T) {
+	values := []string{
+		"10.0.0.1:8080",
+		"https://web-2.internal:8443/status",
+		`{"Addr": "10.0.0.3:9000"}`,
+		`{"host": "10.0.0.4", "port": 7000}`,
+		"[fd00::5]:80",
+	}
+	var kvs []map[string]string
+	for i, v := range values {
+		kvs = append(kvs, map[string]string{
+			"key":   base64.StdEncoding.EncodeToString([]byte("/services/web/" + string(rune('a'+i)))),
+			"value": base64.StdEncoding.EncodeToString([]byte(v)),
+		})
+	}
+	body, _ := json.Marshal(map[string]interface{}{"kvs": kvs})
+	var rangeReq map[string]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		json.NewDecoder(r.Body).Decode(&rangeReq)
+		w.Write(body)
+	}))
+	defer server.Close()
+
+	var got []string
+	for _, job := range fetch(t, "etcd://"+strings.TrimPrefix(server.URL, "http://")+"/services/web/?path=/health") {
+		got = append(got, job.URL)
+	}
+	want := []string{
+		"http://10.0.0.1:8080/health",
+		// Uma URL completa é usada como está
+		"https://web-2.internal:8443/status",
+		"http://10.0.0.3:9000/health",
+		"http://10.0.0.4:7000/health",
+		"http://[fd00::5]:80/health",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	key, _ := base64.StdEncoding.DecodeString(rangeReq["key"])
+	end, _ := base64.StdEncoding.DecodeString(rangeReq["range_end"])
+	if string(key) != "/services/web/" || string(end) != "/services/web0" {
+		t.Errorf("range = [%q, %q), want the prefix range", key, end)
+	}
+}
+
+func TestEtcdInvalidValues(t *testing.T) {
+	e := &etcd{target: targetOptions{scheme: "http", path: "/"}}
+	for _, value := range []string{"10.0.0.1", "10.0.0.1:http", `{"host": "10.0.0.1"}`, `{"Addr": `} {
+		if got, err := e.instanceURL(value); err == nil {
+			t.Errorf("instanceURL(%q) = %q, want an error", value, got)
+		}
+	}
+}
+
+func TestPrefixEnd(t *testing.T) {
+	tests := []struct {
+		prefix string
+		want   []byte
+	}{
+		{"/services/web/", []byte("/services/web0")},
+		{"a", []byte("b")},
+		{"a\xff", []byte("b")},
+		{"\xff\xff", []byte{0}},
+		{"", []byte{0}},
+	}
+	for _, tt := range tests {
+		if got := prefixEnd(tt.prefix); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("prefixEnd(%q) = %q, want %q", tt.prefix, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+package discovery
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// etcd lista as instâncias registradas sob um prefixo de chaves do etcd, usando o gateway JSON da
+// API v3 (/v3/kv/range). O etcd não acompanha a saúde das instâncias: a convenção é que cada uma
+// mantenha a sua chave com um lease, que expira quando ela para. O valor de cada chave pode ser o
+// endereço (host:porta), uma URL completa ou um JSON como {"Addr": "host:porta"} (o formato do
+// naming do gRPC) ou {"host": "...", "port": 8080}. Com tls=true, o etcd é acessado por HTTPS
+type etcd struct {
+	endpoint string
+	prefix   string
+	target   targetOptions
+	client   *http.Client
+	tracker  tracker
+}
+
+func newEtcd(u *url.URL, target targetOptions) (Source, error) {
+	if u.Host == "" || u.Path == "" || u.Path == "/" {
+		return nil, errors.New("etcd discovery expects etcd://host:port/<key prefix>")
+	}
+	apiScheme := "http"
+	if u.Query().Get("tls") == "true" {
+		apiScheme = "https"
+	}
+	return &etcd{
+		endpoint: apiScheme + "://" + u.Host + "/v3/kv/range",
+		prefix:   u.Path,
+		target:   target,
+		client:   &http.Client{Timeout: requestTimeout},
+	}, nil
+}
+
+func (e *etcd) Fetch() ([]pool.Job, bool, error) {
+	body, err := json.Marshal(map[string]string{
+		"key":       base64.StdEncoding.EncodeToString([]byte(e.prefix)),
+		"range_end": base64.StdEncoding.EncodeToString(prefixEnd(e.prefix)),
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	resp, err := e.client.Post(e.endpoint, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("etcd: status code %d", resp.StatusCode)
+	}
+	var out struct {
+		Kvs []struct {
+			Key   string `json:"key"`
+			Value string `json:"value"`
+		} `json:"kvs"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return nil, false, fmt.Errorf("etcd: %v", err)
+	}
+	jobs := make([]pool.Job, 0, len(out.Kvs))
+	for _, kv := range out.Kvs {
+		key, _ := base64.StdEncoding.DecodeString(kv.Key)
+		value, err := base64.StdEncoding.DecodeString(kv.Value)
+		if err != nil {
+			return nil, false, fmt.Errorf("etcd: invalid value for key %s: %v", key, err)
+		}
+		target, err := e.instanceURL(strings.TrimSpace(string(value)))
+		if err != nil {
+			return nil, false, fmt.Errorf("etcd: key %s: %v", key, err)
+		}
+		jobs = append(jobs, pool.Job{URL: target})
+	}
+	return jobs, e.tracker.changed(jobs), nil
+}
+
+// instanceURL interpreta o valor registrado para uma instância
+func (e *etcd) instanceURL(value string) (string, error) {
+	if strings.HasPrefix(value, "{") {
+		var endpoint struct {
+			Addr string `json:"Addr"`
+			Host string `json:"host"`
+			Port int    `json:"port"`
+		}
+		if err := json.Unmarshal([]byte(value), &endpoint); err != nil {
+			return "", err
+		}
+		if endpoint.Addr == "" {
+			if endpoint.Host == "" || endpoint.Port == 0 {
+				return "", errors.New("expected Addr or host and port")
+			}
+			return e.target.url(endpoint.Host, endpoint.Port), nil
+		}
+		value = endpoint.Addr
+	}
+	if strings.Contains(value, "://") {
+		return value, nil
+	}
+	host, port, err := net.SplitHostPort(value)
+	if err != nil {
+		return "", err
+	}
+	n, err := strconv.Atoi(port)
+	if err != nil {
+		return "", fmt.Errorf("invalid port %q", port)
+	}
+	return e.target.url(host, n), nil
+}
+
+// prefixEnd é o fim do intervalo de chaves com o prefixo: o prefixo com o último byte incrementado
+func prefixEnd(prefix string) []byte {
+	end := []byte(prefix)
+	for i := len(end) - 1; i >= 0; i-- {
+		if end[i] < 0xff {
+			end[i]++
+			return end[:i+1]
+		}
+	}
+	return []byte{0}
+}
@@ -0,0 +1,255 @@
+package discovery
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// Credenciais montadas pelo Kubernetes em todo pod, usadas quando o endereço não informa a API
+const (
+	serviceAccountDir = "/var/run/secrets/kubernetes.io/serviceaccount"
+	tokenFile         = serviceAccountDir + "/token"
+	caFile            = serviceAccountDir + "/ca.crt"
+)
+
+// kube lista, pela API REST do Kubernetes, os hosts dos Ingresses (kube://.../ingresses) ou os
+// endereços prontos dos Endpoints dos Services (kube://.../services). Sem host no endereço, a API do
+// cluster é acessada de dentro do pod, com a service account; com host (ex: o kubectl proxy em
+// kube://127.0.0.1:8001/services), a API é acessada por HTTP, ou por HTTPS com tls=true e o token de
+// KUBERNETES_TOKEN. O kubeconfig (~/.kube/config ou $KUBECONFIG) não é lido, já que o client-go não é
+// usado: fora do cluster, a API deve ser acessada pelo kubectl proxy ou com o token. Opções: namespace (lista separada por vírgulas; por padrão todos), selector
+// (label selector, ex: app=web,tier!=cache) e, para services, port (nome ou número da porta medida).
+// O namespace de cada instância vira a tag do job
+type kube struct {
+	api        string
+	resource   string
+	namespaces []string
+	selector   string
+	port       string
+	// ingressScheme indica se scheme foi informado; senão, o protocolo de cada host do Ingress
+	// depende de ele estar na seção tls
+	ingressScheme bool
+	tokenPath     string
+	token         string
+	target        targetOptions
+	client        *http.Client
+	tracker       tracker
+}
+
+func newKube(u *url.URL, target targetOptions) (Source, error) {
+	resource := strings.Trim(u.Path, "/")
+	if resource == "" {
+		resource = "ingresses"
+	}
+	if resource != "ingresses" && resource != "services" {
+		return nil, fmt.Errorf("kube discovery lists ingresses or services, not %q", resource)
+	}
+	query := u.Query()
+	k := &kube{
+		resource:      resource,
+		selector:      query.Get("selector"),
+		port:          query.Get("port"),
+		ingressScheme: query.Get("scheme") != "",
+		target:        target,
+		client:        &http.Client{Timeout: requestTimeout},
+	}
+	for _, ns := range strings.Split(query.Get("namespace"), ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			k.namespaces = append(k.namespaces, ns)
+		}
+	}
+
+	switch {
+	case u.Host == "":
+		// Dentro do cluster, a API é anunciada pelas variáveis de ambiente do pod
+		host, port := os.Getenv("KUBERNETES_SERVICE_HOST"), os.Getenv("KUBERNETES_SERVICE_PORT")
+		if host == "" || port == "" {
+			return nil, errors.New("kube discovery without an API address only works inside a cluster (KUBERNETES_SERVICE_HOST is not set); the kubeconfig is not read, so use kubectl proxy, e.g. kube://127.0.0.1:8001/services")
+		}
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, err
+		}
+		roots := x509.NewCertPool()
+		if !roots.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificate found in %s", caFile)
+		}
+		k.api = "https://" + net.JoinHostPort(host, port)
+		k.tokenPath = tokenFile
+		k.client.Transport = &http.Transport{TLSClientConfig: &tls.Config{RootCAs: roots}}
+	case query.Get("tls") == "true":
+		k.api = "https://" + u.Host
+		k.token = os.Getenv("KUBERNETES_TOKEN")
+	default:
+		k.api = "http://" + u.Host
+	}
+	return k, nil
+}
+
+func (k *kube) Fetch() ([]pool.Job, bool, error) {
+	namespaces := k.namespaces
+	if len(namespaces) == 0 {
+		// Sem namespaces informados, uma única consulta lista todos eles
+		namespaces = []string{""}
+	}
+	var jobs []pool.Job
+	seen := make(map[string]bool)
+	for _, ns := range namespaces {
+		var found []pool.Job
+		var err error
+		if k.resource == "ingresses" {
+			found, err = k.ingresses(ns)
+		} else {
+			found, err = k.endpoints(ns)
+		}
+		if err != nil {
+			return nil, false, err
+		}
+		// O mesmo host pode aparecer em vários Ingresses
+		for _, job := range found {
+			if !seen[job.URL] {
+				seen[job.URL] = true
+				jobs = append(jobs, job)
+			}
+		}
+	}
+	return jobs, k.tracker.changed(jobs), nil
+}
+
+// list faz um GET na coleção do recurso, no namespace informado ou em todos
+func (k *kube) list(group, resource, namespace string, out interface{}) error {
+	path := group + "/" + resource
+	if namespace != "" {
+		path = group + "/namespaces/" + url.PathEscape(namespace) + "/" + resource
+	}
+	endpoint := k.api + path
+	if k.selector != "" {
+		endpoint += "?" + url.Values{"labelSelector": {k.selector}}.Encode()
+	}
+	req, err := http.NewRequest("GET", endpoint, nil)
+	if err != nil {
+		return err
+	}
+	token := k.token
+	if k.tokenPath != "" {
+		// O token da service account é renovado periodicamente, então é lido a cada consulta
+		b, err := os.ReadFile(k.tokenPath)
+		if err != nil {
+			return err
+		}
+		token = strings.TrimSpace(string(b))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := k.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("kube: status code %d listing %s", resp.StatusCode, path)
+	}
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		return fmt.Errorf("kube: %v", err)
+	}
+	return nil
+}
+
+// ingresses gera uma URL para cada host das regras dos Ingresses; hosts curinga são ignorados
+func (k *kube) ingresses(namespace string) ([]pool.Job, error) {
+	var out struct {
+		Items []struct {
+			Metadata struct {
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+			Spec struct {
+				TLS []struct {
+					Hosts []string `json:"hosts"`
+				} `json:"tls"`
+				Rules []struct {
+					Host string `json:"host"`
+				} `json:"rules"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := k.list("/apis/networking.k8s.io/v1", "ingresses", namespace, &out); err != nil {
+		return nil, err
+	}
+	var jobs []pool.Job
+	for _, item := range out.Items {
+		secure := make(map[string]bool)
+		for _, t := range item.Spec.TLS {
+			for _, host := range t.Hosts {
+				secure[host] = true
+			}
+		}
+		for _, rule := range item.Spec.Rules {
+			if rule.Host == "" || strings.HasPrefix(rule.Host, "*") {
+				continue
+			}
+			scheme := k.target.scheme
+			if !k.ingressScheme && secure[rule.Host] {
+				scheme = "https"
+			}
+			jobs = append(jobs, pool.Job{
+				URL:  scheme + "://" + rule.Host + k.target.path,
+				Tags: []string{item.Metadata.Namespace},
+			})
+		}
+	}
+	return jobs, nil
+}
+
+// endpoints gera uma URL para cada endereço pronto dos Endpoints, ou seja, para cada pod que está
+// recebendo tráfego do Service. Os Endpoints herdam os labels do Service, então o selector vale para
+// os dois
+func (k *kube) endpoints(namespace string) ([]pool.Job, error) {
+	var out struct {
+		Items []struct {
+			Metadata struct {
+				Namespace string `json:"namespace"`
+			} `json:"metadata"`
+			Subsets []struct {
+				Addresses []struct {
+					IP string `json:"ip"`
+				} `json:"addresses"`
+				Ports []struct {
+					Name string `json:"name"`
+					Port int    `json:"port"`
+				} `json:"ports"`
+			} `json:"subsets"`
+		} `json:"items"`
+	}
+	if err := k.list("/api/v1", "endpoints", namespace, &out); err != nil {
+		return nil, err
+	}
+	var jobs []pool.Job
+	for _, item := range out.Items {
+		for _, subset := range item.Subsets {
+			for _, port := range subset.Ports {
+				if k.port != "" && k.port != port.Name && k.port != strconv.Itoa(port.Port) {
+					continue
+				}
+				for _, address := range subset.Addresses {
+					jobs = append(jobs, pool.Job{
+						URL:  k.target.url(address.IP, port.Port),
+						Tags: []string{item.Metadata.Namespace},
+					})
+				}
+			}
+		}
+	}
+	return jobs, nil
+}
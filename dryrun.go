@@ -0,0 +1,107 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// dryRunFlag registra a flag -dry-run
+func dryRunFlag(fs *flag.FlagSet) *bool {
+	return fs.Bool("dry-run", false, "validate the configuration, print the planned jobs and the effective settings, and exit without making any requests")
+}
+
+// printPlan mostra o que o comando faria: o valor efetivo de cada flag (as alteradas na linha de
+// comando ficam marcadas com *) e os jobs planejados, com as URLs normalizadas e o timeout de cada
+// uma, junto com as notes, quando informadas, que descrevem o plano além das flags (ex: o perfil
+// de um teste de carga). URLs inválidas fazem o comando terminar com erro, sem nenhuma requisição.
+func printPlan(fs *flag.FlagSet, jobs []pool.Job, timeout time.Duration, notes ...string) error {
+	printSettings(fs, notes...)
+	return planError(printJobs("Planned jobs", jobs, timeout))
+}
+
+// printSettings mostra o valor efetivo de cada flag e as notas do plano
+func printSettings(fs *flag.FlagSet, notes ...string) {
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+
+	fmt.Printf("Dry run of %s: no requests will be made\n\nSettings:\n", fs.Name())
+	fs.VisitAll(func(f *flag.Flag) {
+		if f.Name == "dry-run" {
+			return
+		}
+		mark := " "
+		if set[f.Name] {
+			mark = "*"
+		}
+		fmt.Printf("  %s %-14s %s\n", mark, f.Name, f.Value.String())
+	})
+	for _, note := range notes {
+		fmt.Printf("\n%s\n", note)
+	}
+}
+
+// printJobs lista os jobs planejados e devolve a quantidade de URLs inválidas
+func printJobs(title string, jobs []pool.Job, timeout time.Duration) int {
+	fmt.Printf("\n%s (%d):\n", title, len(jobs))
+	seen := make(map[string]bool)
+	invalid := 0
+	for _, job := range jobs {
+		target, err := normalizeTarget(job.URL)
+		if err != nil {
+			invalid++
+			fmt.Printf("  %-60s INVALID: %s\n", job.URL, err)
+			continue
+		}
+		jobTimeout := timeout
+		if job.Timeout > 0 {
+			jobTimeout = job.Timeout
+		}
+		note := ""
+		if job.Weight > 1 {
+			note += fmt.Sprintf(" weight=%d", job.Weight)
+		}
+		for _, tag := range job.Tags {
+			note += " #" + tag
+		}
+		if seen[target] {
+			note += " (duplicate)"
+		}
+		seen[target] = true
+		fmt.Printf("  %-60s timeout=%s%s\n", target, jobTimeout, note)
+	}
+	return invalid
+}
+
+func planError(invalid int) error {
+	if invalid > 0 {
+		return fmt.Errorf("%d invalid URL(s) in the plan", invalid)
+	}
+	return nil
+}
+
+// normalizeTarget valida um alvo e o devolve normalizado: o esquema e o host das URLs ficam em
+// minúsculas. Alvos sem esquema (como host:porta nos modos tcp e dns) são mantidos como estão
+func normalizeTarget(target string) (string, error) {
+	target = strings.TrimSpace(target)
+	if target == "" {
+		return "", fmt.Errorf("empty target")
+	}
+	if !strings.Contains(target, "://") {
+		return target, nil
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("missing host")
+	}
+	u.Scheme = strings.ToLower(u.Scheme)
+	u.Host = strings.ToLower(u.Host)
+	return u.String(), nil
+}
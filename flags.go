@@ -0,0 +1,44 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// stringList é uma flag que pode ser informada várias vezes, acumulando os valores
+type stringList []string
+
+func (l *stringList) String() string {
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	*l = append(*l, value)
+	return nil
+}
+
+// labelList é uma flag repetível no formato chave=valor; repetir uma chave substitui o valor anterior
+type labelList map[string]string
+
+func (l labelList) String() string {
+	keys := make([]string, 0, len(l))
+	for k := range l {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for i, k := range keys {
+		keys[i] = k + "=" + l[k]
+	}
+	return strings.Join(keys, ",")
+}
+
+func (l labelList) Set(value string) error {
+	key, val, ok := strings.Cut(value, "=")
+	key = strings.TrimSpace(key)
+	if !ok || key == "" || strings.ContainsAny(key, ";,= ") {
+		return fmt.Errorf("invalid label %q: expected key=value", value)
+	}
+	l[key] = strings.TrimSpace(val)
+	return nil
+}
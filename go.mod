@@ -1,3 +1,3 @@
 module github.com/joaomarcelofa/entendendo-worker-pool
 
-go 1.13
+go 1.24
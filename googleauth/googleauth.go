@@ -0,0 +1,172 @@
+package googleauth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTokenURL = "https://oauth2.googleapis.com/token"
+	metadataURL     = "http://metadata.google.internal/computeMetadata/v1/instance/service-accounts/default/token"
+)
+
+// credentialsFile representa os arquivos de credenciais do Google (service account ou usuário do gcloud)
+type credentialsFile struct {
+	Type         string `json:"type"`
+	ClientEmail  string `json:"client_email"`
+	PrivateKey   string `json:"private_key"`
+	TokenURI     string `json:"token_uri"`
+	ClientID     string `json:"client_id"`
+	ClientSecret string `json:"client_secret"`
+	RefreshToken string `json:"refresh_token"`
+}
+
+type tokenResponse struct {
+	AccessToken string `json:"access_token"`
+}
+
+// AccessToken obtém um access token OAuth2 seguindo a mesma ordem das Application Default Credentials:
+// 1. variável GOOGLE_OAUTH_ACCESS_TOKEN
+// 2. arquivo apontado por GOOGLE_APPLICATION_CREDENTIALS
+// 3. arquivo gerado por "gcloud auth application-default login"
+// 4. servidor de metadados (GCE, GKE, Cloud Run...)
+func AccessToken(client *http.Client, scopes ...string) (string, error) {
+	if token := os.Getenv("GOOGLE_OAUTH_ACCESS_TOKEN"); token != "" {
+		return token, nil
+	}
+
+	path := os.Getenv("GOOGLE_APPLICATION_CREDENTIALS")
+	if path == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			wellKnown := filepath.Join(home, ".config", "gcloud", "application_default_credentials.json")
+			if _, err := os.Stat(wellKnown); err == nil {
+				path = wellKnown
+			}
+		}
+	}
+	if path != "" {
+		return tokenFromFile(client, path, scopes)
+	}
+
+	return tokenFromMetadata(client)
+}
+
+func tokenFromFile(client *http.Client, path string, scopes []string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	var creds credentialsFile
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return "", err
+	}
+	tokenURL := creds.TokenURI
+	if tokenURL == "" {
+		tokenURL = defaultTokenURL
+	}
+
+	switch creds.Type {
+	case "service_account":
+		assertion, err := signJWT(creds, tokenURL, scopes)
+		if err != nil {
+			return "", err
+		}
+		return exchange(client, tokenURL, url.Values{
+			"grant_type": {"urn:ietf:params:oauth:grant-type:jwt-bearer"},
+			"assertion":  {assertion},
+		})
+	case "authorized_user":
+		return exchange(client, tokenURL, url.Values{
+			"grant_type":    {"refresh_token"},
+			"client_id":     {creds.ClientID},
+			"client_secret": {creds.ClientSecret},
+			"refresh_token": {creds.RefreshToken},
+		})
+	default:
+		return "", fmt.Errorf("unsupported Google credentials type %q", creds.Type)
+	}
+}
+
+// signJWT monta a asserção assinada com a chave privada da service account
+func signJWT(creds credentialsFile, audience string, scopes []string) (string, error) {
+	block, _ := pem.Decode([]byte(creds.PrivateKey))
+	if block == nil {
+		return "", errors.New("invalid service account private key")
+	}
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return "", err
+	}
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return "", errors.New("service account private key is not RSA")
+	}
+
+	now := time.Now()
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	claims, _ := json.Marshal(map[string]interface{}{
+		"iss":   creds.ClientEmail,
+		"scope": strings.Join(scopes, " "),
+		"aud":   audience,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	})
+	unsigned := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+	digest := sha256.Sum256([]byte(unsigned))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
+
+func exchange(client *http.Client, tokenURL string, form url.Values) (string, error) {
+	resp, err := client.PostForm(tokenURL, form)
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	return decodeToken(resp)
+}
+
+func tokenFromMetadata(client *http.Client) (string, error) {
+	req, err := http.NewRequest("GET", metadataURL, nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("no Google credentials found: %s", err.Error())
+	}
+	defer resp.Body.Close()
+	return decodeToken(resp)
+}
+
+func decodeToken(resp *http.Response) (string, error) {
+	if resp.StatusCode != 200 {
+		return "", fmt.Errorf("token request returned status %d", resp.StatusCode)
+	}
+	var token tokenResponse
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", err
+	}
+	if token.AccessToken == "" {
+		return "", errors.New("token response without access_token")
+	}
+	return token.AccessToken, nil
+}
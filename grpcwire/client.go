@@ -0,0 +1,68 @@
+package grpcwire
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// StatusError é o erro devolvido por Invoke quando o servidor responde com um status diferente de OK
+type StatusError struct {
+	Code    int
+	Message string
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("grpc: status %d: %s", e.Code, e.Message)
+}
+
+// Invoke faz uma chamada unária: envia req para o método (ex: "/grpc.health.v1.Health/Check") do
+// servidor em baseURL e devolve a mensagem de resposta. O client deve falar HTTP/2, em texto puro
+// (h2c) ou com TLS
+func Invoke(client *http.Client, baseURL, method string, req []byte) ([]byte, error) {
+	var body bytes.Buffer
+	if err := WriteMessage(&body, req); err != nil {
+		return nil, err
+	}
+	httpReq, err := http.NewRequest("POST", strings.TrimSuffix(baseURL, "/")+method, &body)
+	if err != nil {
+		return nil, err
+	}
+	httpReq.Header.Set("Content-Type", "application/grpc")
+	httpReq.Header.Set("TE", "trailers")
+
+	resp, err := client.Do(httpReq)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("grpc: unexpected HTTP status %d", resp.StatusCode)
+	}
+
+	msg, readErr := ReadMessage(resp.Body)
+	// Os trailers só ficam disponíveis depois que o corpo é lido até o fim
+	io.Copy(io.Discard, resp.Body)
+
+	// Respostas sem mensagem ("trailers-only") trazem o status nos próprios cabeçalhos
+	status := resp.Trailer.Get("Grpc-Status")
+	message := resp.Trailer.Get("Grpc-Message")
+	if status == "" {
+		status = resp.Header.Get("Grpc-Status")
+		message = resp.Header.Get("Grpc-Message")
+	}
+	code, err := strconv.Atoi(status)
+	if err != nil {
+		return nil, fmt.Errorf("grpc: missing or invalid grpc-status %q", status)
+	}
+	if code != CodeOK {
+		return nil, &StatusError{Code: code, Message: message}
+	}
+	if readErr != nil {
+		return nil, readErr
+	}
+	return msg, nil
+}
@@ -0,0 +1,166 @@
+package grpcwire
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// Códigos de status do gRPC utilizados pelo projeto
+const (
+	CodeOK              = 0
+	CodeInvalidArgument = 3
+	CodeNotFound        = 5
+	CodeUnimplemented   = 12
+	CodeInternal        = 13
+)
+
+// Tipos de codificação (wire types) do protobuf
+const (
+	wireVarint  = 0
+	wireFixed64 = 1
+	wireBytes   = 2
+	wireFixed32 = 5
+)
+
+// maxMessageSize é o mesmo limite padrão das implementações oficiais (4 MiB)
+const maxMessageSize = 4 << 20
+
+// WriteMessage escreve uma mensagem no formato de framing do gRPC: 1 byte indicando compressão
+// (sempre 0), 4 bytes com o tamanho e a mensagem codificada em protobuf
+func WriteMessage(w io.Writer, msg []byte) error {
+	header := make([]byte, 5)
+	binary.BigEndian.PutUint32(header[1:], uint32(len(msg)))
+	if _, err := w.Write(header); err != nil {
+		return err
+	}
+	_, err := w.Write(msg)
+	return err
+}
+
+// ReadMessage lê a próxima mensagem do stream; io.EOF indica que não há mais mensagens
+func ReadMessage(r io.Reader) ([]byte, error) {
+	header := make([]byte, 5)
+	if _, err := io.ReadFull(r, header); err != nil {
+		return nil, err
+	}
+	if header[0] != 0 {
+		return nil, errors.New("grpc: compressed messages are not supported")
+	}
+	length := binary.BigEndian.Uint32(header[1:])
+	if length > maxMessageSize {
+		return nil, fmt.Errorf("grpc: message of %d bytes exceeds the limit", length)
+	}
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(r, msg); err != nil {
+		return nil, io.ErrUnexpectedEOF
+	}
+	return msg, nil
+}
+
+// Encoder monta uma mensagem protobuf campo a campo. Valores padrão (zero) não são escritos,
+// como especificado pelo proto3
+type Encoder struct {
+	buf []byte
+}
+
+// Bytes devolve a mensagem codificada
+func (e *Encoder) Bytes() []byte {
+	return e.buf
+}
+
+func (e *Encoder) tag(field, wireType int) {
+	e.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (e *Encoder) varint(v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	n := binary.PutUvarint(tmp[:], v)
+	e.buf = append(e.buf, tmp[:n]...)
+}
+
+// String escreve um campo string
+func (e *Encoder) String(field int, s string) {
+	if s == "" {
+		return
+	}
+	e.tag(field, wireBytes)
+	e.varint(uint64(len(s)))
+	e.buf = append(e.buf, s...)
+}
+
+// Int64 escreve um campo int64 ou int32 (ambos codificados como varint)
+func (e *Encoder) Int64(field int, v int64) {
+	if v == 0 {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.varint(uint64(v))
+}
+
+// Bool escreve um campo bool
+func (e *Encoder) Bool(field int, v bool) {
+	if !v {
+		return
+	}
+	e.tag(field, wireVarint)
+	e.varint(1)
+}
+
+// Field é um campo lido de uma mensagem protobuf
+type Field struct {
+	Number int
+	// Varint contém o valor de campos numéricos; Data o conteúdo de campos length-delimited
+	Varint uint64
+	Data   []byte
+}
+
+// String interpreta o campo como string
+func (f Field) String() string {
+	return string(f.Data)
+}
+
+// Decode percorre os campos da mensagem, chamando fn para cada um. Campos de tipos fixos são
+// ignorados, já que as mensagens do projeto não os utilizam
+func Decode(msg []byte, fn func(Field)) error {
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		if n <= 0 {
+			return errors.New("protobuf: invalid field key")
+		}
+		msg = msg[n:]
+		f := Field{Number: int(key >> 3)}
+		switch key & 7 {
+		case wireVarint:
+			f.Varint, n = binary.Uvarint(msg)
+			if n <= 0 {
+				return errors.New("protobuf: invalid varint")
+			}
+			msg = msg[n:]
+		case wireBytes:
+			length, n := binary.Uvarint(msg)
+			if n <= 0 || uint64(len(msg)-n) < length {
+				return errors.New("protobuf: invalid length-delimited field")
+			}
+			f.Data = msg[n : n+int(length)]
+			msg = msg[n+int(length):]
+		case wireFixed64:
+			if len(msg) < 8 {
+				return errors.New("protobuf: truncated fixed64")
+			}
+			msg = msg[8:]
+			continue
+		case wireFixed32:
+			if len(msg) < 4 {
+				return errors.New("protobuf: truncated fixed32")
+			}
+			msg = msg[4:]
+			continue
+		default:
+			return fmt.Errorf("protobuf: unsupported wire type %d", key&7)
+		}
+		fn(f)
+	}
+	return nil
+}
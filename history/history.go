@@ -0,0 +1,172 @@
+// Package history guarda, entre execuções, o hash do conteúdo de cada URL, permitindo detectar as
+// URLs cujo conteúdo mudou desde a última execução. Os dados ficam em um arquivo JSON
+package history
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Entry é o último conteúdo conhecido de uma URL
+type Entry struct {
+	Hash string `json:"hash"`
+	// Seen é a última vez em que o conteúdo foi verificado e Changed a última vez em que ele mudou
+	Seen    time.Time `json:"seen"`
+	Changed time.Time `json:"changed"`
+}
+
+// Store é o histórico de conteúdo das URLs, seguro para uso por várias goroutines
+type Store struct {
+	path    string
+	mux     sync.Mutex
+	entries map[string]Entry
+}
+
+// Open carrega o histórico do arquivo; se o arquivo ainda não existir, o histórico começa vazio e
+// o arquivo é criado no primeiro Save
+func Open(path string) (*Store, error) {
+	s := &Store{path: path, entries: make(map[string]Entry)}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &s.entries); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Update registra o hash atual do conteúdo de uma URL e informa se ele mudou, devolvendo também o
+// registro anterior. A primeira verificação de uma URL não é considerada uma mudança
+func (s *Store) Update(url, hash string, at time.Time) (Entry, bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	previous, known := s.entries[url]
+	entry := previous
+	entry.Hash = hash
+	entry.Seen = at
+	changed := known && previous.Hash != hash
+	if !known || changed {
+		entry.Changed = at
+	}
+	s.entries[url] = entry
+	return previous, changed
+}
+
+// Retention é a política de retenção do histórico: MaxAge remove as URLs que não são verificadas há
+// mais tempo que o informado e MaxURLs mantém somente as URLs verificadas mais recentemente. Como o
+// histórico guarda uma entrada por URL, e não por execução, os limites são sempre contados em URLs.
+// Sem ela, o histórico de um monitor cujas URLs mudam (ex: instâncias descobertas a cada rodada)
+// cresce indefinidamente. Valores zero não limitam nada
+type Retention struct {
+	MaxAge  time.Duration
+	MaxURLs int
+}
+
+// ParseRetention interpreta uma política como "90d", "36h", "500urls" ou "90d,500urls": durações (com
+// o sufixo d para dias) definem MaxAge e números com o sufixo urls, MaxURLs
+func ParseRetention(spec string) (Retention, error) {
+	var r Retention
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		// O sufixo deixa claro que o limite é de URLs, e não de execuções
+		if count, ok := strings.CutSuffix(part, "urls"); ok {
+			n, err := strconv.Atoi(count)
+			if err != nil || n < 1 {
+				return Retention{}, fmt.Errorf("invalid retention %q: the number of URLs must be a whole number greater than zero", part)
+			}
+			r.MaxURLs = n
+			continue
+		}
+		if _, err := strconv.Atoi(part); err == nil {
+			return Retention{}, fmt.Errorf("invalid retention %q: the history keeps one entry per URL, not per run; use %surls to keep the most recently checked URLs", part, part)
+		}
+		age, err := parseAge(part)
+		if err != nil || age <= 0 {
+			return Retention{}, fmt.Errorf("invalid retention %q: expected a period such as 90d or 36h, or a number of URLs such as 500urls", part)
+		}
+		r.MaxAge = age
+	}
+	return r, nil
+}
+
+// parseAge aceita as durações do Go e, além delas, dias (ex: 90d)
+func parseAge(s string) (time.Duration, error) {
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, err
+		}
+		return time.Duration(n) * 24 * time.Hour, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// Prune aplica a política de retenção, considerando now como o momento atual, e devolve quantas URLs
+// foram removidas
+func (s *Store) Prune(r Retention, now time.Time) int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	removed := 0
+	if r.MaxAge > 0 {
+		for url, entry := range s.entries {
+			if now.Sub(entry.Seen) > r.MaxAge {
+				delete(s.entries, url)
+				removed++
+			}
+		}
+	}
+	if r.MaxURLs > 0 && len(s.entries) > r.MaxURLs {
+		urls := make([]string, 0, len(s.entries))
+		for url := range s.entries {
+			urls = append(urls, url)
+		}
+		// As URLs verificadas há mais tempo ficam no fim e são as removidas
+		sort.Slice(urls, func(i, j int) bool { return s.entries[urls[i]].Seen.After(s.entries[urls[j]].Seen) })
+		for _, url := range urls[r.MaxURLs:] {
+			delete(s.entries, url)
+			removed++
+		}
+	}
+	return removed
+}
+
+// Save grava o histórico no arquivo. A gravação é feita em um arquivo temporário renomeado no final,
+// para que uma interrupção no meio não corrompa o histórico
+func (s *Store) Save() error {
+	s.mux.Lock()
+	data, err := json.MarshalIndent(s.entries, "", "  ")
+	s.mux.Unlock()
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".history-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
@@ -0,0 +1,57 @@
+package history
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRetention(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Retention
+		wantErr string
+	}{
+		{spec: "90d", want: Retention{MaxAge: 90 * 24 * time.Hour}},
+		{spec: "36h", want: Retention{MaxAge: 36 * time.Hour}},
+		{spec: "500urls", want: Retention{MaxURLs: 500}},
+		{spec: "90d, 500urls", want: Retention{MaxAge: 90 * 24 * time.Hour, MaxURLs: 500}},
+		{spec: "", want: Retention{}},
+		{spec: "500", wantErr: "not per run"},
+		{spec: "0urls", wantErr: "greater than zero"},
+		{spec: "soon", wantErr: "expected a period"},
+	}
+	for _, tt := range tests {
+		got, err := ParseRetention(tt.spec)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseRetention(%q) error = %v, want %q", tt.spec, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tt.want {
+			t.Errorf("ParseRetention(%q) = %+v, %v; want %+v", tt.spec, got, err, tt.want)
+		}
+	}
+}
+
+func TestPruneKeepsMostRecentlyCheckedURLs(t *testing.T) {
+	now := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+	s := &Store{entries: map[string]Entry{
+		"http://old":    {Seen: now.Add(-100 * 24 * time.Hour)},
+		"http://a":      {Seen: now.Add(-3 * time.Hour)},
+		"http://b":      {Seen: now.Add(-2 * time.Hour)},
+		"http://recent": {Seen: now.Add(-time.Hour)},
+	}}
+	if removed := s.Prune(Retention{MaxAge: 90 * 24 * time.Hour, MaxURLs: 2}, now); removed != 2 {
+		t.Errorf("Prune removed %d URLs, want 2", removed)
+	}
+	for _, url := range []string{"http://b", "http://recent"} {
+		if _, ok := s.entries[url]; !ok {
+			t.Errorf("%s was pruned, want it kept", url)
+		}
+	}
+	if len(s.entries) != 2 {
+		t.Errorf("history has %d URLs, want 2", len(s.entries))
+	}
+}
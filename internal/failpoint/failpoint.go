@@ -0,0 +1,157 @@
+// Package failpoint permite injetar falhas em pontos nomeados do código durante os testes, para que
+// os caminhos de recuperação de panics, timeouts e contrapressão tenham cobertura determinística.
+// Nenhum ponto fica ativo por padrão e, nesse caso, Eval custa apenas uma leitura atômica.
+//
+// As ações são:
+//
+//	panic        provoca um panic (ou panic(mensagem))
+//	sleep(200ms) aguarda a duração informada
+//	block        bloqueia até que o ponto seja desativado ou alterado
+//	off          desativa o ponto
+//
+// e aceitam os prefixos "N*", para disparar apenas nas N primeiras vezes (ex: 3*panic), e "P%",
+// para disparar com essa probabilidade (ex: 10%sleep(1s)).
+// Os pontos também podem ser ativados pela variável de ambiente WORKERPOOL_FAILPOINTS, no formato
+// "nome=ação;nome=ação" (ex: WORKERPOOL_FAILPOINTS="pool/worker-panic=3*panic")
+package failpoint
+
+import (
+	"fmt"
+	"math/rand/v2"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// EnvVar é a variável de ambiente lida na inicialização do pacote
+const EnvVar = "WORKERPOOL_FAILPOINTS"
+
+type failpoint struct {
+	action string
+	arg    string
+	// remaining é a quantidade de disparos restantes, ou -1 para sem limite
+	remaining   int
+	probability float64
+	// release é fechado quando o ponto é desativado ou alterado, liberando quem estiver em block
+	release chan struct{}
+}
+
+var (
+	mux    sync.Mutex
+	points = make(map[string]*failpoint)
+	// active indica se há algum ponto ativo, evitando o mutex no caminho comum
+	active atomic.Bool
+)
+
+func init() {
+	spec := os.Getenv(EnvVar)
+	if spec == "" {
+		return
+	}
+	for _, entry := range strings.Split(spec, ";") {
+		name, action, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok {
+			fmt.Fprintf(os.Stderr, "failpoint: invalid entry %q in %s\n", entry, EnvVar)
+			continue
+		}
+		if err := Enable(name, action); err != nil {
+			fmt.Fprintf(os.Stderr, "failpoint: %s\n", err)
+		}
+	}
+}
+
+// Enable ativa o ponto name com a ação informada, substituindo a anterior
+func Enable(name, spec string) error {
+	fp, err := parse(spec)
+	if err != nil {
+		return fmt.Errorf("invalid action %q for %s: %w", spec, name, err)
+	}
+	mux.Lock()
+	defer mux.Unlock()
+	if previous := points[name]; previous != nil {
+		close(previous.release)
+	}
+	if fp == nil {
+		delete(points, name)
+	} else {
+		points[name] = fp
+	}
+	active.Store(len(points) > 0)
+	return nil
+}
+
+// Disable desativa o ponto name, liberando quem estiver bloqueado nele
+func Disable(name string) {
+	Enable(name, "off")
+}
+
+func parse(spec string) (*failpoint, error) {
+	spec = strings.TrimSpace(spec)
+	fp := &failpoint{remaining: -1, probability: 1, release: make(chan struct{})}
+	if count, rest, ok := strings.Cut(spec, "*"); ok {
+		n, err := strconv.Atoi(count)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid count %q", count)
+		}
+		fp.remaining, spec = n, rest
+	}
+	if percent, rest, ok := strings.Cut(spec, "%"); ok {
+		p, err := strconv.ParseFloat(percent, 64)
+		if err != nil || p < 0 || p > 100 {
+			return nil, fmt.Errorf("invalid probability %q", percent)
+		}
+		fp.probability, spec = p/100, rest
+	}
+	fp.action, fp.arg = spec, ""
+	if name, arg, ok := strings.Cut(spec, "("); ok && strings.HasSuffix(arg, ")") {
+		fp.action, fp.arg = name, strings.TrimSuffix(arg, ")")
+	}
+	switch fp.action {
+	case "off":
+		return nil, nil
+	case "panic", "block":
+	case "sleep":
+		if _, err := time.ParseDuration(fp.arg); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown action %q", fp.action)
+	}
+	return fp, nil
+}
+
+// Eval executa a ação do ponto name, se ele estiver ativo
+func Eval(name string) {
+	if !active.Load() {
+		return
+	}
+	mux.Lock()
+	fp := points[name]
+	// Um ponto que já esgotou os seus disparos continua registrado, para que os bloqueados nele
+	// só sejam liberados ao desativá-lo
+	if fp == nil || fp.remaining == 0 || (fp.probability < 1 && rand.Float64() >= fp.probability) {
+		mux.Unlock()
+		return
+	}
+	if fp.remaining > 0 {
+		fp.remaining--
+	}
+	action, arg, release := fp.action, fp.arg, fp.release
+	mux.Unlock()
+
+	switch action {
+	case "panic":
+		if arg == "" {
+			arg = "failpoint " + name
+		}
+		panic(arg)
+	case "sleep":
+		d, _ := time.ParseDuration(arg)
+		time.Sleep(d)
+	case "block":
+		<-release
+	}
+}
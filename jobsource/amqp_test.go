@@ -0,0 +1,56 @@
+package jobsource
+
+import (
+	"bufio"
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// amqpStream monta uma sequência de frames como se tivesse sido enviada pelo broker
+func amqpStream(frames ...[]byte) *bufio.Reader {
+	return bufio.NewReader(bytes.NewReader(bytes.Join(frames, nil)))
+}
+
+func TestAMQPShortMethodFrame(t *testing.T) {
+	for _, payload := range [][]byte{nil, {0}, {0, 60, 0}} {
+		s := &amqpSource{reader: amqpStream(amqpFrame(amqpFrameMethod, 1, payload)), msgs: make(chan amqpMsg, 1)}
+		// A leitura deve terminar com erro em vez de causar panic
+		s.read()
+		if s.err == nil || !strings.Contains(s.err.Error(), "method frame too short") {
+			t.Errorf("read(%v) error = %v, want a short frame error", payload, s.err)
+		}
+
+		s = &amqpSource{reader: amqpStream(amqpFrame(amqpFrameMethod, 0, payload))}
+		if _, err := s.expect(amqpConnection, 10); err == nil || !strings.Contains(err.Error(), "method frame too short") {
+			t.Errorf("expect(%v) error = %v, want a short frame error", payload, err)
+		}
+	}
+}
+
+func TestAMQPDelivery(t *testing.T) {
+	var deliver amqpWriter
+	deliver.short(amqpBasic)
+	deliver.short(60)
+	deliver.shortstr("ctag")
+	deliver.longlong(7)
+	deliver.octet(1)
+	header := make([]byte, 14)
+	header[11] = byte(len("http://a"))
+	s := &amqpSource{
+		reader: amqpStream(
+			amqpFrame(amqpFrameMethod, 1, deliver.buf),
+			amqpFrame(amqpFrameHeader, 1, header),
+			amqpFrame(amqpFrameBody, 1, []byte("http://a")),
+		),
+		msgs: make(chan amqpMsg, 1),
+	}
+	s.read()
+	msg, ok := <-s.msgs
+	if !ok {
+		t.Fatalf("no message delivered (error %v)", s.err)
+	}
+	if msg.tag != 7 || !msg.redelivered || msg.body != "http://a" {
+		t.Errorf("message = %+v, want tag 7, redelivered, body http://a", msg)
+	}
+}
@@ -0,0 +1,291 @@
+package jobsource
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// natsSource consome jobs de um subject do NATS (protocolo core, sem JetStream). As instâncias
+// entram no mesmo queue group, então cada job é entregue a apenas uma delas
+type natsSource struct {
+	subject string
+	results string
+	group   string
+	// A inscrição só é feita no primeiro Next, para que uma instância que apenas publica jobs
+	// (enqueue) não entre no queue group e receba jobs que nunca seriam processados
+	subscribe sync.Once
+	subErr    error
+
+	conn     net.Conn
+	writeMux sync.Mutex
+	// queue guarda as mensagens recebidas até que Next as consuma. Ela não tem limite para que a
+	// leitura nunca fique bloqueada esperando o pool e continue respondendo aos PINGs do servidor, que
+	// derruba as conexões que não respondem; ready avisa Next de que há mensagens novas
+	queueMux sync.Mutex
+	queue    []natsMsg
+	ready    chan struct{}
+	// pongs recebe um aviso a cada PONG do servidor, respondendo ao PING enviado por Push
+	pongs chan struct{}
+	// done é fechado quando a leitura termina; err guarda o motivo, devolvido por Next e Push
+	done chan struct{}
+	err  error
+}
+
+// natsFlushTimeout é o tempo máximo que Push aguarda o PONG que confirma as publicações
+const natsFlushTimeout = 10 * time.Second
+
+type natsMsg struct {
+	replyTo string
+	payload string
+}
+
+type natsDelivery struct {
+	source *natsSource
+	msg    natsMsg
+}
+
+// openNATS conecta ao endereço nats://[usuario:senha@]host:porta. Os parâmetros opcionais são:
+//   - subject: subject dos jobs (padrão "entendendo-worker-pool.jobs")
+//   - results: subject onde os resultados são publicados (padrão "<subject>.results")
+//   - group: queue group dos consumidores (padrão "entendendo-worker-pool")
+//
+// Quando a mensagem do job possui reply subject, o resultado também é enviado como resposta
+func openNATS(u *url.URL) (Source, error) {
+	q := u.Query()
+	s := &natsSource{
+		subject: q.Get("subject"),
+		results: q.Get("results"),
+		ready:   make(chan struct{}, 1),
+		pongs:   make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	if s.subject == "" {
+		s.subject = "entendendo-worker-pool.jobs"
+	}
+	if s.results == "" {
+		s.results = s.subject + ".results"
+	}
+	s.group = q.Get("group")
+	if s.group == "" {
+		s.group = "entendendo-worker-pool"
+	}
+
+	addr := u.Host
+	if u.Port() == "" {
+		addr += ":4222"
+	}
+	conn, err := net.DialTimeout("tcp", addr, 10*time.Second)
+	if err != nil {
+		return nil, err
+	}
+	s.conn = conn
+	reader := bufio.NewReader(conn)
+
+	// O servidor se apresenta com INFO assim que a conexão é aberta
+	conn.SetReadDeadline(time.Now().Add(10 * time.Second))
+	line, err := reader.ReadString('\n')
+	if err != nil || !strings.HasPrefix(line, "INFO") {
+		conn.Close()
+		return nil, errors.New("nats: unexpected server greeting")
+	}
+
+	options := map[string]interface{}{
+		"verbose":  false,
+		"pedantic": false,
+		"name":     "entendendo-worker-pool",
+		"lang":     "go",
+	}
+	if u.User != nil {
+		if password, ok := u.User.Password(); ok {
+			options["user"] = u.User.Username()
+			options["pass"] = password
+		} else {
+			options["auth_token"] = u.User.Username()
+		}
+	}
+	connect, _ := json.Marshal(options)
+	if err := s.write("CONNECT " + string(connect) + "\r\nPING\r\n"); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	// O PONG confirma que o CONNECT foi aceito; um -ERR indica falha de autenticação
+	line, err = reader.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if !strings.HasPrefix(line, "PONG") {
+		conn.Close()
+		return nil, fmt.Errorf("nats: %s", strings.TrimSpace(line))
+	}
+	conn.SetReadDeadline(time.Time{})
+
+	go s.read(reader)
+	return s, nil
+}
+
+// read processa as mensagens enviadas pelo servidor até que a conexão seja encerrada
+func (s *natsSource) read(reader *bufio.Reader) {
+	defer close(s.done)
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			s.err = err
+			return
+		}
+		line = strings.TrimRight(line, "\r\n")
+		switch {
+		case strings.HasPrefix(line, "MSG "):
+			// MSG <subject> <sid> [reply-to] <tamanho>
+			fields := strings.Fields(line)
+			if len(fields) < 4 {
+				s.err = errors.New("nats: invalid MSG line")
+				return
+			}
+			size, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil {
+				s.err = err
+				return
+			}
+			payload := make([]byte, size+2)
+			if _, err := io.ReadFull(reader, payload); err != nil {
+				s.err = err
+				return
+			}
+			msg := natsMsg{payload: string(payload[:size])}
+			if len(fields) == 5 {
+				msg.replyTo = fields[3]
+			}
+			s.queueMux.Lock()
+			s.queue = append(s.queue, msg)
+			s.queueMux.Unlock()
+			select {
+			case s.ready <- struct{}{}:
+			default:
+			}
+		case line == "PING":
+			s.write("PONG\r\n")
+		case line == "PONG":
+			select {
+			case s.pongs <- struct{}{}:
+			default:
+			}
+		case strings.HasPrefix(line, "-ERR"):
+			s.err = errors.New("nats: " + strings.TrimSpace(strings.TrimPrefix(line, "-ERR")))
+			return
+		}
+	}
+}
+
+func (s *natsSource) write(data string) error {
+	s.writeMux.Lock()
+	defer s.writeMux.Unlock()
+	_, err := io.WriteString(s.conn, data)
+	return err
+}
+
+func (s *natsSource) publish(subject, payload string) error {
+	return s.write("PUB " + subject + " " + strconv.Itoa(len(payload)) + "\r\n" + payload + "\r\n")
+}
+
+func (s *natsSource) Next() (Delivery, error) {
+	s.subscribe.Do(func() {
+		s.subErr = s.write("SUB " + s.subject + " " + s.group + " 1\r\n")
+	})
+	if s.subErr != nil {
+		return nil, s.subErr
+	}
+	for {
+		s.queueMux.Lock()
+		if len(s.queue) > 0 {
+			msg := s.queue[0]
+			s.queue[0] = natsMsg{}
+			s.queue = s.queue[1:]
+			s.queueMux.Unlock()
+			return &natsDelivery{source: s, msg: msg}, nil
+		}
+		s.queueMux.Unlock()
+		// As mensagens recebidas antes do encerramento da leitura são entregues antes do erro
+		select {
+		case <-s.ready:
+		case <-s.done:
+			s.queueMux.Lock()
+			pending := len(s.queue)
+			s.queueMux.Unlock()
+			if pending > 0 {
+				continue
+			}
+			if s.err == nil {
+				return nil, io.EOF
+			}
+			return nil, s.err
+		}
+	}
+}
+
+func (s *natsSource) Push(urls ...string) error {
+	for _, u := range urls {
+		if err := s.publish(s.subject, u); err != nil {
+			return err
+		}
+	}
+	return s.flush()
+}
+
+// flush envia um PING e aguarda o PONG. Como o servidor processa os comandos em ordem, o PONG
+// confirma que as publicações anteriores foram recebidas e a conexão já pode ser fechada
+func (s *natsSource) flush() error {
+	// Descarta um PONG que tenha chegado sem ter sido aguardado
+	select {
+	case <-s.pongs:
+	default:
+	}
+	if err := s.write("PING\r\n"); err != nil {
+		return err
+	}
+	select {
+	case <-s.pongs:
+		return nil
+	case <-s.done:
+		if s.err != nil {
+			return s.err
+		}
+		return io.EOF
+	case <-time.After(natsFlushTimeout):
+		return errors.New("nats: timed out waiting for PONG")
+	}
+}
+
+func (s *natsSource) Close() error {
+	return s.conn.Close()
+}
+
+func (d *natsDelivery) Job() pool.Job {
+	return pool.Job{URL: d.msg.payload}
+}
+
+// Done publica o resultado no subject de resultados e, se houver, no reply subject
+func (d *natsDelivery) Done(result pool.Result) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	if err := d.source.publish(d.source.results, string(data)); err != nil {
+		return err
+	}
+	if d.msg.replyTo != "" {
+		return d.source.publish(d.msg.replyTo, string(data))
+	}
+	return nil
+}
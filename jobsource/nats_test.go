@@ -0,0 +1,174 @@
+package jobsource
+
+import (
+	"bufio"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeNATS aceita uma conexão, responde ao handshake e repassa as linhas seguintes para lines;
+// os PINGs após o handshake só são respondidos quando pong é verdadeiro
+func fakeNATS(t *testing.T, pong bool) (string, <-chan string) {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { ln.Close() })
+	lines := make(chan string, 16)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		conn.Write([]byte("INFO {}\r\n"))
+		handshake := true
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				close(lines)
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			if line == "PING" && (handshake || pong) {
+				handshake = false
+				conn.Write([]byte("PONG\r\n"))
+				continue
+			}
+			if !strings.HasPrefix(line, "CONNECT") {
+				lines <- line
+			}
+		}
+	}()
+	return "nats://" + ln.Addr().String(), lines
+}
+
+func TestNATSPushWaitsForPong(t *testing.T) {
+	addr, lines := fakeNATS(t, true)
+	source, err := Open(addr + "?subject=jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+
+	if err := source.Push("http://a"); err != nil {
+		t.Fatalf("Push: %v", err)
+	}
+	// Quando Push retorna, o servidor já recebeu a publicação e o PING
+	want := []string{"PUB jobs 8", "http://a"}
+	for _, w := range want {
+		select {
+		case got := <-lines:
+			if got != w {
+				t.Errorf("server got %q, want %q", got, w)
+			}
+		default:
+			t.Fatalf("server had not received %q when Push returned", w)
+		}
+	}
+}
+
+func TestNATSPushFailsWhenConnectionCloses(t *testing.T) {
+	addr, lines := fakeNATS(t, false)
+	source, err := Open(addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pushed := make(chan error, 1)
+	go func() { pushed <- source.Push("http://a") }()
+
+	// Sem o PONG, Push continua aguardando até que a conexão seja encerrada
+	for line := range lines {
+		if line == "PING" {
+			break
+		}
+	}
+	select {
+	case err := <-pushed:
+		t.Fatalf("Push returned %v before the PONG", err)
+	case <-time.After(20 * time.Millisecond):
+	}
+	source.Close()
+	select {
+	case err := <-pushed:
+		if err == nil {
+			t.Fatal("Push returned nil after the connection closed")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Push did not return after the connection closed")
+	}
+}
+
+func TestNATSAnswersPingWhileJobsPileUp(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	const jobs = 1000
+	ponged := make(chan struct{})
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		conn.Write([]byte("INFO {}\r\n"))
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			line = strings.TrimRight(line, "\r\n")
+			switch {
+			case line == "PING":
+				conn.Write([]byte("PONG\r\n"))
+			case strings.HasPrefix(line, "SUB "):
+				// Entrega muito mais jobs do que o consumidor processa e então verifica se a conexão
+				// continua viva
+				w := bufio.NewWriter(conn)
+				for i := 0; i < jobs; i++ {
+					payload := fmt.Sprintf("http://job/%d", i)
+					fmt.Fprintf(w, "MSG jobs 1 %d\r\n%s\r\n", len(payload), payload)
+				}
+				w.WriteString("PING\r\n")
+				w.Flush()
+			case line == "PONG":
+				close(ponged)
+			}
+		}
+	}()
+
+	source, err := Open("nats://" + ln.Addr().String() + "?subject=jobs")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer source.Close()
+	first, err := source.Next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Job().URL != "http://job/0" {
+		t.Fatalf("first job = %q", first.Job().URL)
+	}
+	select {
+	case <-ponged:
+	case <-time.After(5 * time.Second):
+		t.Fatal("the server PING was not answered while jobs were waiting")
+	}
+	for i := 1; i < jobs; i++ {
+		delivery, err := source.Next()
+		if err != nil {
+			t.Fatalf("job %d: %v", i, err)
+		}
+		if want := fmt.Sprintf("http://job/%d", i); delivery.Job().URL != want {
+			t.Fatalf("job %d = %q, want %q", i, delivery.Job().URL, want)
+		}
+	}
+}
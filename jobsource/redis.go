@@ -0,0 +1,172 @@
+package jobsource
+
+import (
+	"encoding/json"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// redisSource usa uma lista do Redis como fila. Cada job consumido é movido atomicamente para uma
+// lista de processamento própria da instância e só é removido dela depois de concluído, de forma
+// que os jobs em andamento sobrevivem a uma reinicialização
+type redisSource struct {
+	queue      string
+	processing string
+	results    string
+
+	// A conexão de consumo fica bloqueada aguardando jobs; as demais operações usam outra conexão
+	consumer *respConn
+	mux      sync.Mutex
+	conn     *respConn
+
+	// A recuperação dos jobs pendentes só é feita no primeiro Next, para que um enqueue executado na
+	// mesma máquina não devolva para a fila os jobs em andamento de um consumidor ativo
+	recover    sync.Once
+	recoverErr error
+}
+
+type redisDelivery struct {
+	source  *redisSource
+	payload string
+}
+
+// openRedis conecta ao endereço redis://[:senha@]host:porta/db. Os parâmetros opcionais são:
+//   - queue: lista de jobs (padrão "entendendo-worker-pool:jobs")
+//   - results: lista onde os resultados são publicados (padrão "<queue>:results")
+//   - consumer: nome da instância, usado na lista de processamento (padrão hostname)
+func openRedis(u *url.URL) (Source, error) {
+	q := u.Query()
+	s := &redisSource{
+		queue:   q.Get("queue"),
+		results: q.Get("results"),
+	}
+	if s.queue == "" {
+		s.queue = "entendendo-worker-pool:jobs"
+	}
+	if s.results == "" {
+		s.results = s.queue + ":results"
+	}
+	consumer := q.Get("consumer")
+	if consumer == "" {
+		consumer, _ = os.Hostname()
+	}
+	s.processing = s.queue + ":processing:" + consumer
+
+	var err error
+	if s.consumer, err = dialRedis(u); err != nil {
+		return nil, err
+	}
+	if s.conn, err = dialRedis(u); err != nil {
+		s.consumer.close()
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// requeuePending devolve para a fila os jobs que ficaram pendentes em uma execução anterior desta instância
+func (s *redisSource) requeuePending() error {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	for {
+		reply, err := s.conn.do("RPOPLPUSH", s.processing, s.queue)
+		if err != nil {
+			return err
+		}
+		if reply == nil {
+			return nil
+		}
+	}
+}
+
+func dialRedis(u *url.URL) (*respConn, error) {
+	addr := u.Host
+	if u.Port() == "" {
+		addr += ":6379"
+	}
+	c, err := dialRESP(addr)
+	if err != nil {
+		return nil, err
+	}
+	if u.User != nil {
+		password, ok := u.User.Password()
+		args := []string{"AUTH", password}
+		if !ok {
+			args = []string{"AUTH", u.User.Username()}
+		} else if u.User.Username() != "" {
+			args = []string{"AUTH", u.User.Username(), password}
+		}
+		if _, err := c.do(args...); err != nil {
+			c.close()
+			return nil, err
+		}
+	}
+	if db := strings.Trim(u.Path, "/"); db != "" {
+		if _, err := strconv.Atoi(db); err == nil {
+			if _, err := c.do("SELECT", db); err != nil {
+				c.close()
+				return nil, err
+			}
+		}
+	}
+	return c, nil
+}
+
+func (s *redisSource) Next() (Delivery, error) {
+	s.recover.Do(func() {
+		s.recoverErr = s.requeuePending()
+	})
+	if s.recoverErr != nil {
+		return nil, s.recoverErr
+	}
+	for {
+		// Aguarda até 5 segundos por um job; sem job, a espera é repetida
+		reply, err := s.consumer.do("BRPOPLPUSH", s.queue, s.processing, "5")
+		if err != nil {
+			return nil, err
+		}
+		if payload, ok := reply.(string); ok {
+			return &redisDelivery{source: s, payload: payload}, nil
+		}
+	}
+}
+
+func (s *redisSource) Push(urls ...string) error {
+	if len(urls) == 0 {
+		return nil
+	}
+	// LPUSH + BRPOPLPUSH (que consome pela direita) mantém a ordem de chegada
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	_, err := s.conn.do(append([]string{"LPUSH", s.queue}, urls...)...)
+	return err
+}
+
+func (s *redisSource) Close() error {
+	s.consumer.close()
+	return s.conn.close()
+}
+
+func (d *redisDelivery) Job() pool.Job {
+	return pool.Job{URL: d.payload}
+}
+
+// Done publica o resultado e remove o job da lista de processamento
+func (d *redisDelivery) Done(result pool.Result) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	d.source.mux.Lock()
+	defer d.source.mux.Unlock()
+	if _, err := d.source.conn.do("RPUSH", d.source.results, string(data)); err != nil {
+		return err
+	}
+	_, err = d.source.conn.do("LREM", d.source.processing, "1", d.payload)
+	return err
+}
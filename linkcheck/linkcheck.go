@@ -0,0 +1,219 @@
+// Package linkcheck verifica links quebrados: as URLs de uma lista são visitadas e, opcionalmente,
+// as páginas HTML são percorridas seguindo os seus links até uma profundidade máxima. O foco é a
+// correção e não a velocidade: toda resposta 4xx/5xx, timeout ou erro de conexão é registrada junto
+// com as páginas que apontam para o link
+package linkcheck
+
+import (
+	"fmt"
+	"html"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+	"github.com/joaomarcelofa/entendendo-worker-pool/probe"
+)
+
+// maxPageSize limita a quantidade de bytes lidos de cada página ao procurar os links
+const maxPageSize = 5 << 20
+
+// linkPattern encontra os atributos href e src, com ou sem aspas. O atributo precisa vir depois de um
+// espaço, para que atributos como data-href não sejam confundidos com links
+var linkPattern = regexp.MustCompile(`(?i)(?:^|\s)(?:href|src)\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s>"']+))`)
+
+// Broken é um link quebrado encontrado na verificação
+type Broken struct {
+	URL string
+	// Status é o código HTTP da resposta, ou zero quando a requisição falhou (ex: timeout)
+	Status int
+	Err    error
+	// Referrers são as páginas que apontam para o link; fica vazio para as URLs da lista inicial
+	Referrers []string
+}
+
+// Summary resume uma verificação
+type Summary struct {
+	Checked int
+	Broken  []Broken
+}
+
+// Checker percorre os links a partir de uma lista de URLs. Somente as páginas dos hosts da lista
+// inicial são percorridas; links para outros hosts são verificados, mas não seguidos
+type Checker struct {
+	client probe.Doer
+	depth  int
+
+	// links guarda os links encontrados em cada página visitada, até que o resultado seja processado
+	mux   sync.Mutex
+	hosts map[string]bool
+	links map[string][]string
+}
+
+// New cria um verificador. Com depth zero, apenas as URLs informadas são verificadas; com depth
+// maior que zero, os links das páginas são seguidos até essa quantidade de níveis
+func New(client probe.Doer, depth int) *Checker {
+	return &Checker{client: client, depth: depth, hosts: make(map[string]bool), links: make(map[string][]string)}
+}
+
+// Visit é a medição executada pelos workers: a URL é requisitada e, quando for uma página HTML de
+// um dos hosts da lista inicial, os seus links são extraídos. O detalhe "status" traz o código HTTP
+func (c *Checker) Visit(job pool.Job) pool.Result {
+	result := pool.Result{URL: job.URL}
+	req, err := http.NewRequest("GET", job.URL, nil)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	start := time.Now()
+	resp, err := probe.Client(c.client, job).Do(req)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	defer resp.Body.Close()
+	result.TimeTooked = time.Since(start)
+	result.Details = map[string]string{"status": strconv.Itoa(resp.StatusCode)}
+	if resp.StatusCode >= 400 {
+		result.Err = fmt.Errorf("status code %d", resp.StatusCode)
+		return result
+	}
+
+	if c.depth > 0 && c.crawlable(resp) {
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxPageSize))
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		// Os links relativos são resolvidos a partir da URL final, após os redirecionamentos
+		links := Extract(resp.Request.URL, string(body))
+		c.mux.Lock()
+		c.links[job.URL] = links
+		c.mux.Unlock()
+	}
+	return result
+}
+
+func (c *Checker) crawlable(resp *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType != "text/html" && mediaType != "application/xhtml+xml" {
+		return false
+	}
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	return c.hosts[resp.Request.URL.Host]
+}
+
+// Run verifica os jobs informados usando o pool, que deve ter sido criado com Visit, e devolve o
+// resumo quando todos os links tiverem sido verificados. Os links encontrados nas páginas usam o
+// timeout padrão do cliente. onResult, se informado, recebe cada resultado
+func (c *Checker) Run(p *pool.Pool, seeds []pool.Job, onResult func(pool.Result)) Summary {
+	depth := make(map[string]int)
+	referrers := make(map[string][]string)
+	reply := make(chan pool.Result)
+	var pending sync.WaitGroup
+
+	// Os jobs são submetidos em goroutines separadas para que a coleta dos resultados nunca fique
+	// bloqueada esperando espaço na fila do pool
+	submit := func(job pool.Job, level int) {
+		if _, seen := depth[job.URL]; seen {
+			return
+		}
+		depth[job.URL] = level
+		pending.Add(1)
+		go p.Submit(job, reply)
+	}
+	c.mux.Lock()
+	for _, seed := range seeds {
+		if u, err := url.Parse(seed.URL); err == nil {
+			c.hosts[u.Host] = true
+		}
+	}
+	c.mux.Unlock()
+	for _, seed := range seeds {
+		submit(seed, 0)
+	}
+	go func() {
+		pending.Wait()
+		close(reply)
+	}()
+
+	var summary Summary
+	var broken []pool.Result
+	for result := range reply {
+		summary.Checked++
+		if onResult != nil {
+			onResult(result)
+		}
+		if result.Err != nil {
+			broken = append(broken, result)
+		}
+
+		c.mux.Lock()
+		links := c.links[result.URL]
+		delete(c.links, result.URL)
+		c.mux.Unlock()
+		level := depth[result.URL] + 1
+		for _, link := range links {
+			if !contains(referrers[link], result.URL) {
+				referrers[link] = append(referrers[link], result.URL)
+			}
+			if level <= c.depth {
+				submit(pool.Job{URL: link}, level)
+			}
+		}
+		pending.Done()
+	}
+
+	sort.Slice(broken, func(i, j int) bool { return broken[i].URL < broken[j].URL })
+	for _, result := range broken {
+		status, _ := strconv.Atoi(result.Details["status"])
+		summary.Broken = append(summary.Broken, Broken{
+			URL:       result.URL,
+			Status:    status,
+			Err:       result.Err,
+			Referrers: referrers[result.URL],
+		})
+	}
+	return summary
+}
+
+// Extract devolve os links http e https de uma página HTML, resolvidos a partir de base, sem
+// fragmentos e sem repetições
+func Extract(base *url.URL, page string) []string {
+	var links []string
+	seen := make(map[string]bool)
+	for _, match := range linkPattern.FindAllStringSubmatch(page, -1) {
+		ref := html.UnescapeString(strings.TrimSpace(match[1] + match[2] + match[3]))
+		if ref == "" || strings.HasPrefix(ref, "#") {
+			continue
+		}
+		u, err := base.Parse(ref)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+			continue
+		}
+		u.Fragment = ""
+		link := u.String()
+		if !seen[link] {
+			seen[link] = true
+			links = append(links, link)
+		}
+	}
+	return links
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
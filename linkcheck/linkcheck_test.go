@@ -0,0 +1,157 @@
+package linkcheck
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// site serve páginas HTML nos caminhos informados; os demais caminhos respondem 404. visits conta as
+// requisições de cada caminho
+type site struct {
+	*httptest.Server
+	mux    sync.Mutex
+	visits map[string]int
+}
+
+func newSite(t *testing.T, pages func(base string) map[string]string) *site {
+	t.Helper()
+	s := &site{visits: make(map[string]int)}
+	var routes map[string]string
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mux.Lock()
+		s.visits[r.URL.Path]++
+		s.mux.Unlock()
+		page, ok := routes[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		fmt.Fprint(w, page)
+	}))
+	routes = pages(s.URL)
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *site) visited(path string) int {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.visits[path]
+}
+
+func TestRunFindsBrokenLinkTwoLevelsDeep(t *testing.T) {
+	external := newSite(t, func(string) map[string]string {
+		return map[string]string{"/page": `<a href="/never-crawled">x</a>`}
+	})
+	site := newSite(t, func(base string) map[string]string {
+		return map[string]string{
+			"/": `<a href="/a">a</a> <a href='/b'>b</a> <a href="/a#top">again</a>
+				<a href="` + external.URL + `/page">external</a> <a href="` + external.URL + `/gone">gone</a>
+				<a href="mailto:me@example.com">mail</a> <a href="javascript:void(0)">js</a>`,
+			"/a": `<a href="/broken">broken</a> <a href="/">home</a> <a href="/a/deeper">deeper</a>`,
+			"/b": `<a href="` + base + `/broken">broken</a>`,
+			// Já no último nível: os links desta página não são seguidos
+			"/a/deeper": `<a href="/unreached">unreached</a>`,
+		}
+	})
+
+	checker := New(&http.Client{}, 2)
+	p := pool.New(4, checker.Visit)
+	defer p.Close()
+	var results []string
+	summary := checker.Run(p, []pool.Job{{URL: site.URL + "/"}}, func(r pool.Result) { results = append(results, r.URL) })
+
+	// /, /a, /b, as duas páginas externas, /broken, /a/deeper; / e /a#top não se repetem
+	if summary.Checked != 7 || len(results) != 7 {
+		t.Errorf("checked %d links (%d results), want 7: %v", summary.Checked, len(results), results)
+	}
+	for _, path := range []string{"/", "/a", "/broken"} {
+		if n := site.visited(path); n != 1 {
+			t.Errorf("%s visited %d times, want once", path, n)
+		}
+	}
+	if n := site.visited("/unreached"); n != 0 {
+		t.Errorf("/unreached is past the depth limit but was visited %d times", n)
+	}
+	// Links para outros hosts são verificados, mas não percorridos
+	if n := external.visited("/page"); n != 1 {
+		t.Errorf("external page visited %d times, want once", n)
+	}
+	if n := external.visited("/never-crawled"); n != 0 {
+		t.Error("a link of an external page was followed")
+	}
+
+	if len(summary.Broken) != 2 {
+		t.Fatalf("got %d broken links, want 2: %+v", len(summary.Broken), summary.Broken)
+	}
+	byURL := make(map[string]Broken)
+	for _, b := range summary.Broken {
+		sort.Strings(b.Referrers)
+		byURL[b.URL] = b
+	}
+	broken := byURL[site.URL+"/broken"]
+	if broken.Status != 404 || broken.Err == nil {
+		t.Errorf("/broken = %+v, want status 404 with an error", broken)
+	}
+	if want := []string{site.URL + "/a", site.URL + "/b"}; !reflect.DeepEqual(broken.Referrers, want) {
+		t.Errorf("/broken referrers = %v, want %v", broken.Referrers, want)
+	}
+	if gone := byURL[external.URL+"/gone"]; gone.Status != 404 || !reflect.DeepEqual(gone.Referrers, []string{site.URL + "/"}) {
+		t.Errorf("external /gone = %+v, want 404 referred by the home page", gone)
+	}
+}
+
+func TestRunWithoutDepthChecksOnlySeeds(t *testing.T) {
+	site := newSite(t, func(string) map[string]string {
+		return map[string]string{"/": `<a href="/missing">x</a>`}
+	})
+	checker := New(&http.Client{}, 0)
+	p := pool.New(2, checker.Visit)
+	defer p.Close()
+	summary := checker.Run(p, []pool.Job{{URL: site.URL + "/"}, {URL: site.URL + "/seed-missing"}, {URL: site.URL + "/"}}, nil)
+
+	if summary.Checked != 2 {
+		t.Errorf("checked %d links, want the 2 distinct seeds", summary.Checked)
+	}
+	if site.visited("/missing") != 0 {
+		t.Error("a link was followed with depth 0")
+	}
+	if len(summary.Broken) != 1 || summary.Broken[0].URL != site.URL+"/seed-missing" || summary.Broken[0].Referrers != nil {
+		t.Errorf("broken = %+v, want only the missing seed, without referrers", summary.Broken)
+	}
+}
+
+func TestExtract(t *testing.T) {
+	base, _ := url.Parse("https://example.com/docs/index.html")
+	tests := []struct {
+		name string
+		page string
+		want []string
+	}{
+		{"double quotes", `<a href="/about">`, []string{"https://example.com/about"}},
+		{"single quotes", `<img src='logo.png'>`, []string{"https://example.com/docs/logo.png"}},
+		{"unquoted", `<a href=guide.html>x</a><a HREF = /faq >`, []string{"https://example.com/docs/guide.html", "https://example.com/faq"}},
+		{"fragments", `<a href="#top"><a href="/page#one"><a href="/page#two">`, []string{"https://example.com/page"}},
+		{"other schemes", `<a href="mailto:a@b.c"><a href="javascript:x()"><a href="ftp://h/f"><a href="data:,x">`, nil},
+		{"absolute and protocol-relative", `<a href="http://other.test/x"><script src="//cdn.test/a.js">`, []string{"http://other.test/x", "https://cdn.test/a.js"}},
+		{"entities", `<a href="/search?a=1&amp;b=2">`, []string{"https://example.com/search?a=1&b=2"}},
+		{"duplicates and blanks", `<a href="/x"><a href=" /x "><a href="">`, []string{"https://example.com/x"}},
+		{"other attributes", `<a data-href="/no" title="href=/no">`, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := Extract(base, tt.page); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Extract(%q) = %v, want %v", tt.page, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/linkcheck"
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+	"github.com/joaomarcelofa/entendendo-worker-pool/urls"
+)
+
+// runLinks procura links quebrados nas URLs informadas e, com -depth, nas páginas ligadas a elas.
+// Ao encontrar algum, o comando termina com erro, o que permite usá-lo em pipelines de CI
+func runLinks(args []string) error {
+	var sinkSpecs stringList
+	fs := flag.NewFlagSet("links", flag.ExitOnError)
+	sinkFlag(fs, &sinkSpecs)
+	labels := labelFlag(fs)
+	list := fs.String("list", "", "file with the URLs to check (default: the URLs given as arguments or the -profile list)")
+	profile := profileFlag(fs)
+	depth := fs.Int("depth", 0, "follow the links of HTML pages on the same hosts up to this many levels (0: check only the given URLs)")
+	qtyWorkers := fs.Int("workers", 8, "number of workers")
+	timeout := fs.Int("timeout", 10, "HTTP client timeout in seconds")
+	dryRun := dryRunFlag(fs)
+	tags := tagsFlag(fs)
+	pc := pacingFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	seeds := pool.JobsFromURLs(fs.Args())
+	if *list != "" {
+		fromFile, err := urls.ReadFile(*list)
+		if err != nil {
+			return err
+		}
+		seeds = append(seeds, fromFile...)
+	}
+	if len(seeds) == 0 {
+		fromProfile, err := urls.LoadProfile(*profile)
+		if err != nil {
+			return err
+		}
+		seeds = fromProfile
+	}
+	seeds, err := urls.Expand(seeds)
+	if err != nil {
+		return err
+	}
+	seeds, err = filterTags(seeds, *tags)
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		return printPlan(fs, seeds, time.Duration(*timeout)*time.Second,
+			fmt.Sprintf("Links found on HTML pages are followed up to depth %d", *depth))
+	}
+	sinks, err := openSinks(sinkSpecs, labels)
+	if err != nil {
+		return err
+	}
+
+	checker := linkcheck.New(createSimpleHTTPClient(*timeout), *depth)
+	p := pool.New(*qtyWorkers, checker.Visit)
+	pc.apply(p)
+	fmt.Printf("Checking %d URL(s), depth %d\n", len(seeds), *depth)
+	summary := checker.Run(p, seeds, func(result pool.Result) {
+		writeResult(sinks, result)
+	})
+	p.Close()
+	flushSinks(sinks)
+
+	fmt.Printf("%d link(s) checked, %d broken\n", summary.Checked, len(summary.Broken))
+	for _, b := range summary.Broken {
+		fmt.Printf("  %s\n    %s\n", b.URL, b.Err)
+		if len(b.Referrers) > 0 {
+			fmt.Printf("    referrer: %s\n", strings.Join(b.Referrers, ", "))
+		}
+	}
+	if len(summary.Broken) > 0 {
+		return fmt.Errorf("%d broken link(s) found", len(summary.Broken))
+	}
+	return nil
+}
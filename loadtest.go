@@ -0,0 +1,207 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/loadtest"
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// runLoadTest dispara requisições para a lista de URLs em uma taxa fixa ou seguindo um perfil de rampa,
+// usando o worker pool
+func runLoadTest(args []string) error {
+	var sinkSpecs stringList
+	fs := flag.NewFlagSet("loadtest", flag.ExitOnError)
+	sinkFlag(fs, &sinkSpecs)
+	labels := labelFlag(fs)
+	rps := fs.Float64("rps", 50, "requests per second")
+	duration := fs.Duration("duration", time.Minute, "how long to keep sending requests")
+	ramp := fs.String("ramp", "", "load profile overriding -rps/-duration, e.g. 0-100rps/60s (stages may be chained with commas)")
+	spike := fs.String("spike", "", "spike profile as <peak>rps/<length> (e.g. 500rps/10s): -rps for -duration, the spike, then -rps for -duration again")
+	progress := fs.Duration("progress", 0, "print an interim summary at this interval (e.g. 5m for soak tests)")
+	interval := fs.Duration("interval", 0, "window size of the timeline in the summary (default: a tenth of the test, at least 1s)")
+	qtyWorkers := fs.Int("workers", 64, "number of workers (must be enough to sustain the rate)")
+	timeout := fs.Int("timeout", 5, "HTTP client timeout in seconds")
+	dryRun := dryRunFlag(fs)
+	targetList := targetFlags(fs)
+	pc := pacingFlags(fs)
+	probes := probeFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	profile := loadtest.Constant(*rps, *duration)
+	description := fmt.Sprintf("at %.1f req/s for %s", *rps, *duration)
+	switch {
+	case *ramp != "":
+		var err error
+		if profile, err = loadtest.ParseProfile(*ramp); err != nil {
+			return err
+		}
+		description = "with profile " + *ramp
+	// Sem -ramp, a taxa e a duração são usadas diretamente (e, com -spike, antes e depois do pico)
+	case *rps <= 0:
+		return errors.New("-rps must be greater than zero")
+	case *duration <= 0:
+		return errors.New("-duration must be greater than zero")
+	case *spike != "":
+		burst, err := loadtest.ParseProfile(*spike)
+		if err != nil {
+			return err
+		}
+		if len(burst) != 1 || burst[0].From != burst[0].To {
+			return fmt.Errorf("invalid -spike %q: expected <peak>rps/<length>", *spike)
+		}
+		if burst[0].To <= 0 {
+			return fmt.Errorf("invalid -spike %q: the peak must be greater than zero", *spike)
+		}
+		profile = loadtest.Spike(*rps, burst[0].To, *duration, burst[0].Duration, *duration)
+		description = fmt.Sprintf("at %.1f req/s with a spike of %.1f req/s for %s", *rps, burst[0].To, burst[0].Duration)
+	}
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+	jobs, err := targetList.jobs()
+	if err != nil {
+		return err
+	}
+	jobs = pc.weigh(jobs)
+	if *dryRun {
+		if _, err := probes.visit(*timeout); err != nil {
+			return err
+		}
+		return printPlan(fs, jobs, time.Duration(*timeout)*time.Second,
+			fmt.Sprintf("Load profile: %s, %d stage(s), %s in total, about %.0f request(s) cycling through the jobs below",
+				description, len(profile), profile.Duration(), profile.Hits(profile.Duration())))
+	}
+
+	// Por padrão nenhum resultado individual é impresso, apenas o resumo ao final
+	sinks, err := openSinks(sinkSpecs, labels)
+	if err != nil {
+		return err
+	}
+
+	p, err := probes.newPool(*qtyWorkers, *timeout)
+	if err != nil {
+		return err
+	}
+	pc.apply(p)
+	defer p.Close()
+
+	// O teste percorre repetidamente a sequência ponderada, então os grupos com peso maior recebem
+	// proporcionalmente mais requisições
+	fmt.Printf("Load testing %d URL(s) %s\n", len(jobs), description)
+	summary := loadtest.Run(p, pool.Schedule(jobs), loadtest.Options{
+		Profile:  profile,
+		Interval: *interval,
+		Tags:     tagIndex(jobs),
+		OnResult: func(result pool.Result) { writeResult(sinks, result) },
+		Progress: *progress,
+		OnProgress: func(progress loadtest.Progress) {
+			printProgress(progress)
+			flushSinks(sinks)
+		},
+	})
+	flushSinks(sinks)
+
+	printSummary(summary)
+	return nil
+}
+
+// printProgress mostra o acumulado do teste e o que foi concluído desde o resumo parcial anterior
+func printProgress(p loadtest.Progress) {
+	fmt.Printf("[%s] total: %d requests, %d errors, p50 %s, p99 %s | last: %d requests, %d errors, p50 %s, p99 %s\n",
+		p.Elapsed.Round(time.Second),
+		p.Total.Requests, p.Total.Errors, p.Total.Latencies.P50, p.Total.Latencies.P99,
+		p.Last.Requests, p.Last.Errors, p.Last.Latencies.P50, p.Last.Latencies.P99)
+}
+
+func printSummary(s loadtest.Summary) {
+	fmt.Printf("Requests    [total, rate, throughput]  %d, %.2f req/s, %.2f req/s\n", s.Requests, s.Rate, s.Throughput)
+	fmt.Printf("Duration    [total, sending, wait]     %s, %s, %s\n", s.Duration+s.Wait, s.Duration, s.Wait)
+	l := s.Latencies
+	fmt.Printf("Latencies   [min, mean, 50, 90, 95, 99, max]  %s, %s, %s, %s, %s, %s, %s\n", l.Min, l.Mean, l.P50, l.P90, l.P95, l.P99, l.Max)
+	// A espera na fila fica separada do tempo das requisições: é ela que cresce quando faltam workers
+	q := s.QueueWait
+	fmt.Printf("Queue wait  [min, mean, 50, 90, 95, 99, max]  %s, %s, %s, %s, %s, %s, %s\n", q.Min, q.Mean, q.P50, q.P90, q.P95, q.P99, q.Max)
+	fmt.Printf("Errors      [total, rate]  %d, %.2f%%\n", s.Errors, 100*s.ErrorRate())
+
+	// Linha do tempo, para identificar a partir de qual taxa a latência piora
+	fmt.Printf("\n%-10s %10s %9s %7s %14s %14s %14s\n", "Start", "Target", "Requests", "Errors", "p50", "p99", "queue p99")
+	for _, i := range s.Intervals {
+		fmt.Printf("%-10s %6.1f r/s %9d %7d %14s %14s %14s\n", i.Start, i.Rate, i.Requests, i.Errors, i.Latencies.P50, i.Latencies.P99, i.QueueWait.P99)
+	}
+
+	// Resumo por estágio, quando o perfil tem mais de um (ex: baseline, spike e recovery)
+	if len(s.Phases) > 1 {
+		fmt.Printf("\n%-10s %-10s %10s %9s %7s %14s %14s\n", "Phase", "Start", "Duration", "Requests", "Errors", "p50", "p99")
+		for _, p := range s.Phases {
+			fmt.Printf("%-10s %-10s %10s %9d %7d %14s %14s\n", p.Name, p.Start, p.Duration, p.Requests, p.Errors, p.Latencies.P50, p.Latencies.P99)
+		}
+	}
+
+	// Latência separada entre respostas servidas pelo cache da CDN e as que foram até a origem
+	if len(s.Cache) > 0 {
+		statuses := make([]string, 0, len(s.Cache))
+		for status := range s.Cache {
+			statuses = append(statuses, status)
+		}
+		sort.Strings(statuses)
+		fmt.Printf("\n%-10s %9s %14s %14s %14s\n", "Cache", "Requests", "mean", "p50", "p99")
+		for _, status := range statuses {
+			c := s.Cache[status]
+			fmt.Printf("%-10s %9d %14s %14s %14s\n", status, c.Requests, c.Latencies.Mean, c.Latencies.P50, c.Latencies.P99)
+		}
+	}
+
+	// Estatísticas por tag das URLs, permitindo acompanhar separadamente os endpoints críticos
+	if len(s.Tags) > 0 {
+		tags := make([]string, 0, len(s.Tags))
+		for tag := range s.Tags {
+			tags = append(tags, tag)
+		}
+		sort.Strings(tags)
+		fmt.Printf("\n%-16s %9s %9s %14s %14s %14s\n", "Tag", "Requests", "Errors", "mean", "p50", "p99")
+		for _, tag := range tags {
+			t := s.Tags[tag]
+			fmt.Printf("%-16s %9d %9d %14s %14s %14s\n", "#"+tag, t.Requests, t.Errors, t.Latencies.Mean, t.Latencies.P50, t.Latencies.P99)
+		}
+	}
+
+	// Fases informadas pelos servidores no cabeçalho Server-Timing, separando o tempo do servidor
+	// ("server") do tempo da rede ("network")
+	if len(s.ServerTiming) > 0 {
+		names := make([]string, 0, len(s.ServerTiming))
+		for name := range s.ServerTiming {
+			if name != "server" && name != "network" {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		names = append(names, "server", "network")
+		fmt.Printf("\n%-16s %9s %14s %14s %14s\n", "Server-Timing", "Requests", "mean", "p50", "p99")
+		for _, name := range names {
+			t := s.ServerTiming[name]
+			fmt.Printf("%-16s %9d %14s %14s %14s\n", name, t.Requests, t.Latencies.Mean, t.Latencies.P50, t.Latencies.P99)
+		}
+	}
+
+	// Lista os erros mais frequentes
+	messages := make([]string, 0, len(s.ErrorCounts))
+	for message := range s.ErrorCounts {
+		messages = append(messages, message)
+	}
+	sort.Slice(messages, func(i, j int) bool { return s.ErrorCounts[messages[i]] > s.ErrorCounts[messages[j]] })
+	if len(messages) > 10 {
+		messages = messages[:10]
+	}
+	if len(messages) > 0 {
+		fmt.Println()
+	}
+	for _, message := range messages {
+		fmt.Printf("  %6d  %s\n", s.ErrorCounts[message], message)
+	}
+}
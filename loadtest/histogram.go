@@ -0,0 +1,112 @@
+package loadtest
+
+import (
+	"math"
+	"sort"
+	"time"
+)
+
+// histogramGrowth é a razão entre os limites de duas faixas consecutivas do histograma, o que
+// limita o erro dos percentis a cerca de 1%
+const histogramGrowth = 1.02
+
+// Histogram acumula tempos de resposta em faixas logarítmicas, permitindo calcular os percentis com
+// memória constante, independentemente da quantidade de amostras. É o que torna viáveis os testes de
+// várias horas, em que guardar cada amostra faria o consumo de memória crescer sem limite
+type Histogram struct {
+	count   int64
+	sum     time.Duration
+	min     time.Duration
+	max     time.Duration
+	buckets map[int]int64
+}
+
+// NewHistogram cria um histograma vazio
+func NewHistogram() *Histogram {
+	return &Histogram{buckets: make(map[int]int64)}
+}
+
+// Add registra uma amostra
+func (h *Histogram) Add(d time.Duration) {
+	if h.count == 0 || d < h.min {
+		h.min = d
+	}
+	if d > h.max {
+		h.max = d
+	}
+	h.count++
+	h.sum += d
+	h.buckets[bucketOf(d)]++
+}
+
+// Count é a quantidade de amostras registradas
+func (h *Histogram) Count() int64 {
+	return h.count
+}
+
+// Quantile devolve o valor abaixo do qual está a fração q das amostras (ex: 0.99 para o percentil 99)
+func (h *Histogram) Quantile(q float64) time.Duration {
+	if h.count == 0 {
+		return 0
+	}
+	rank := int64(math.Ceil(q * float64(h.count)))
+	if rank < 1 {
+		rank = 1
+	}
+	keys := make([]int, 0, len(h.buckets))
+	for b := range h.buckets {
+		keys = append(keys, b)
+	}
+	sort.Ints(keys)
+
+	var seen int64
+	for _, b := range keys {
+		seen += h.buckets[b]
+		if seen >= rank {
+			// O valor representante da faixa é limitado aos extremos observados
+			v := bucketValue(b)
+			if v < h.min {
+				v = h.min
+			}
+			if v > h.max {
+				v = h.max
+			}
+			return v
+		}
+	}
+	return h.max
+}
+
+// Latencies resume as amostras do histograma
+func (h *Histogram) Latencies() Latencies {
+	if h.count == 0 {
+		return Latencies{}
+	}
+	return Latencies{
+		Min:  h.min,
+		Mean: h.sum / time.Duration(h.count),
+		P50:  h.Quantile(0.50),
+		P90:  h.Quantile(0.90),
+		P95:  h.Quantile(0.95),
+		P99:  h.Quantile(0.99),
+		Max:  h.max,
+	}
+}
+
+// bucketOf é a faixa de uma duração: a faixa 0 agrupa tudo abaixo de um microssegundo e a faixa i
+// vai de histogramGrowth^(i-1) até histogramGrowth^i microssegundos
+func bucketOf(d time.Duration) int {
+	if d < time.Microsecond {
+		return 0
+	}
+	return int(math.Log(float64(d)/float64(time.Microsecond))/math.Log(histogramGrowth)) + 1
+}
+
+// bucketValue é o ponto médio da faixa
+func bucketValue(b int) time.Duration {
+	if b == 0 {
+		return 0
+	}
+	low := math.Pow(histogramGrowth, float64(b-1))
+	return time.Duration(low * (1 + histogramGrowth) / 2 * float64(time.Microsecond))
+}
@@ -0,0 +1,83 @@
+package loadtest
+
+import (
+	"math"
+	"math/rand/v2"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestHistogramQuantilesMatchSortedReference(t *testing.T) {
+	rng := rand.New(rand.NewPCG(1, 2))
+	distributions := []struct {
+		name   string
+		sample func() time.Duration
+	}{
+		// Latências de requisições costumam ter cauda longa, como a log-normal
+		{"lognormal", func() time.Duration {
+			return time.Duration(math.Exp(rng.NormFloat64()*0.8+math.Log(50)) * float64(time.Millisecond))
+		}},
+		{"uniform", func() time.Duration { return time.Duration(rng.Int64N(int64(2 * time.Second))) }},
+		{"bimodal", func() time.Duration {
+			if rng.IntN(10) == 0 {
+				return 2*time.Second + time.Duration(rng.Int64N(int64(100*time.Millisecond)))
+			}
+			return 5*time.Millisecond + time.Duration(rng.Int64N(int64(time.Millisecond)))
+		}},
+	}
+	for _, dist := range distributions {
+		t.Run(dist.name, func(t *testing.T) {
+			h := NewHistogram()
+			samples := make([]time.Duration, 100000)
+			var sum time.Duration
+			for i := range samples {
+				samples[i] = dist.sample()
+				sum += samples[i]
+				h.Add(samples[i])
+			}
+			sort.Slice(samples, func(i, j int) bool { return samples[i] < samples[j] })
+
+			for _, q := range []float64{0.50, 0.90, 0.95, 0.99, 0.999} {
+				want := samples[int(math.Ceil(q*float64(len(samples))))-1]
+				got := h.Quantile(q)
+				if diff := math.Abs(float64(got-want)) / float64(want); diff > 0.015 {
+					t.Errorf("p%g = %s, reference %s (%.2f%% off)", q*100, got, want, diff*100)
+				}
+			}
+			l := h.Latencies()
+			if l.Min != samples[0] || l.Max != samples[len(samples)-1] {
+				t.Errorf("min/max = %s/%s, want %s/%s", l.Min, l.Max, samples[0], samples[len(samples)-1])
+			}
+			if want := sum / time.Duration(len(samples)); l.Mean != want {
+				t.Errorf("mean = %s, want %s", l.Mean, want)
+			}
+			if h.Count() != int64(len(samples)) {
+				t.Errorf("count = %d, want %d", h.Count(), len(samples))
+			}
+		})
+	}
+}
+
+func TestHistogramEdgeCases(t *testing.T) {
+	if got := NewHistogram().Latencies(); got != (Latencies{}) {
+		t.Errorf("empty histogram latencies = %+v, want zero", got)
+	}
+
+	// Com uma única amostra, todos os percentis são a própria amostra
+	h := NewHistogram()
+	h.Add(123 * time.Millisecond)
+	for _, q := range []float64{0, 0.5, 1} {
+		if got := h.Quantile(q); got != 123*time.Millisecond {
+			t.Errorf("single sample Quantile(%g) = %s, want 123ms", q, got)
+		}
+	}
+
+	// Amostras abaixo de 1µs ficam na primeira faixa
+	h = NewHistogram()
+	h.Add(0)
+	h.Add(500 * time.Nanosecond)
+	if got := h.Quantile(0.99); got > 500*time.Nanosecond {
+		t.Errorf("sub-microsecond p99 = %s, want at most 500ns", got)
+	}
+}
@@ -0,0 +1,395 @@
+package loadtest
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/clock"
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// Options configura um teste de carga
+type Options struct {
+	// Profile define a taxa de requisições por segundo ao longo do teste (ex: Constant(50, time.Minute))
+	Profile Profile
+	// Interval é o tamanho das janelas em que os resultados são agrupados na linha do tempo do resumo;
+	// quando não informado, o teste é dividido em 10 janelas de no mínimo um segundo
+	Interval time.Duration
+	// OnResult, se informado, recebe cada resultado assim que ele fica pronto
+	OnResult func(pool.Result)
+	// OnProgress, se informado, recebe um resumo parcial a cada Progress, o que permite acompanhar
+	// testes longos (soak) e perceber uma degradação lenta sem esperar o fim do teste
+	Progress   time.Duration
+	OnProgress func(Progress)
+	// Clock é o relógio que conduz os disparos e as janelas do resumo; quando não informado, é usado
+	// o relógio do sistema. Com um relógio falso, o ritmo e os cálculos podem ser testados sem esperas
+	Clock clock.Clock
+	// Tags associa as URLs às suas tags (ex: "critical"); quando informado, o resumo também traz as
+	// estatísticas de cada tag
+	Tags map[string][]string
+}
+
+// Progress é um resumo parcial de um teste em andamento
+type Progress struct {
+	// Elapsed é o tempo desde o início do teste
+	Elapsed time.Duration
+	// Total acumula todas as requisições concluídas até o momento e Last somente as concluídas desde o
+	// resumo parcial anterior
+	Total Totals
+	Last  Totals
+}
+
+// Totals resume um conjunto de requisições concluídas
+type Totals struct {
+	Requests  int
+	Errors    int
+	Latencies Latencies
+}
+
+// Summary resume um teste de carga
+type Summary struct {
+	// Requests é a quantidade de requisições disparadas e Errors quantas delas falharam
+	Requests int
+	Errors   int
+	// Duration é o tempo gasto disparando as requisições e Wait o tempo aguardando as últimas respostas
+	Duration time.Duration
+	Wait     time.Duration
+	// Rate é a taxa de disparo efetivamente atingida e Throughput a taxa de respostas com sucesso
+	Rate       float64
+	Throughput float64
+	Latencies  Latencies
+	// QueueWait resume o tempo que as requisições passaram na fila do pool antes de serem pegas por um
+	// worker (ver pool.Result.QueueWait), incluindo as que falharam. Enquanto os workers dão conta da
+	// taxa, ela fica perto de zero; quando o pool satura, é ela que cresce, e não Latencies
+	QueueWait Latencies
+	// ErrorCounts agrupa as falhas pela mensagem de erro
+	ErrorCounts map[string]int
+	// Intervals é a linha do tempo do teste, permitindo ver em que taxa a latência começa a piorar
+	Intervals []Interval
+	// Phases resume cada estágio do perfil, considerando as requisições disparadas durante o estágio
+	Phases []Phase
+	// Cache separa as respostas com sucesso pela situação no cache da CDN (detalhe "cache" dos
+	// resultados: HIT, MISS ou UNKNOWN); fica vazio quando a medição não informa essa situação
+	Cache map[string]Totals
+	// Tags agrupa as requisições pelas tags das URLs (ver Options.Tags); uma URL com várias tags entra
+	// em todas elas
+	Tags map[string]Totals
+	// ServerTiming traz a duração das fases informadas pelos servidores no cabeçalho Server-Timing
+	// (detalhes "server_timing_<fase>_ms"), além das entradas "server" e "network", que separam o
+	// tempo gasto no servidor do tempo gasto na rede; fica vazio quando nenhum servidor envia o cabeçalho
+	ServerTiming map[string]Totals
+}
+
+// Phase resume as requisições disparadas durante um estágio do perfil
+type Phase struct {
+	Name      string
+	Start     time.Duration
+	Duration  time.Duration
+	Requests  int
+	Errors    int
+	Latencies Latencies
+}
+
+// Interval resume os resultados concluídos em uma janela do teste
+type Interval struct {
+	// Start é o início da janela em relação ao início do teste e Rate a taxa alvo no meio da janela
+	Start     time.Duration
+	Rate      float64
+	Requests  int
+	Errors    int
+	Latencies Latencies
+	QueueWait Latencies
+}
+
+// Latencies resume os tempos de resposta das requisições com sucesso
+type Latencies struct {
+	Min  time.Duration
+	Mean time.Duration
+	P50  time.Duration
+	P90  time.Duration
+	P95  time.Duration
+	P99  time.Duration
+	Max  time.Duration
+}
+
+// ErrorRate é a fração das requisições que falharam
+func (s Summary) ErrorRate() float64 {
+	if s.Requests == 0 {
+		return 0
+	}
+	return float64(s.Errors) / float64(s.Requests)
+}
+
+// Run dispara jobs no pool percorrendo a lista de jobs repetidamente, seguindo a taxa do perfil
+// configurado, e devolve o resumo quando todas as respostas tiverem chegado. Cada job é submetido
+// como está, então as opções de cada entrada, como o timeout próprio, continuam valendo.
+// O disparo segue o relógio: se o pool ficar saturado, Submit bloqueia e as requisições atrasadas
+// são disparadas assim que houver espaço, o que aparece no resumo como uma taxa abaixo da configurada
+func Run(p *pool.Pool, jobs []pool.Job, opts Options) Summary {
+	clk := opts.Clock
+	if clk == nil {
+		clk = clock.Real
+	}
+	duration := opts.Profile.Duration()
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = duration / 10
+		if interval < time.Second {
+			interval = time.Second
+		}
+	}
+
+	total := newAggregate()
+	cache := make(map[string]*aggregate)
+	tags := make(map[string]*aggregate)
+	serverTiming := make(map[string]*aggregate)
+	intervals := make([]*aggregate, int((duration+interval-1)/interval))
+	for i := range intervals {
+		intervals[i] = newAggregate()
+	}
+	phases := make([]*aggregate, len(opts.Profile))
+	for i := range phases {
+		phases[i] = newAggregate()
+	}
+
+	// Cada estágio tem o seu próprio canal de respostas, para que os resultados sejam atribuídos ao
+	// estágio em que a requisição foi disparada mesmo que ela termine no estágio seguinte
+	collected := make(chan phaseResult, 1024)
+	replies := make([]chan pool.Result, len(opts.Profile))
+	var forwarding sync.WaitGroup
+	forwarding.Add(len(replies))
+	for i := range replies {
+		replies[i] = make(chan pool.Result, 1024)
+		go func(phase int) {
+			defer forwarding.Done()
+			for result := range replies[phase] {
+				collected <- phaseResult{phase: phase, result: result}
+			}
+		}(i)
+	}
+
+	var done sync.WaitGroup
+	start := clk.Now()
+
+	// Coleta os resultados enquanto os jobs são disparados; cada resultado entra na janela em que
+	// foi concluído e os que chegam após o fim do teste ficam na última janela
+	// Os resumos parciais também são gerados por esta goroutine, que é a única a acessar os agregados
+	var progress clock.Timer
+	if opts.OnProgress != nil && opts.Progress > 0 {
+		progress = clk.Timer(opts.Progress)
+	}
+	var collecting sync.WaitGroup
+	collecting.Add(1)
+	go func() {
+		defer collecting.Done()
+		last := newAggregate()
+		var tick <-chan time.Time
+		if progress != nil {
+			defer func() { progress.Stop() }()
+			tick = progress.C()
+		}
+		for {
+			select {
+			case c, ok := <-collected:
+				if !ok {
+					return
+				}
+				result := c.result
+				total.add(result)
+				last.add(result)
+				phases[c.phase].add(result)
+				if status, ok := result.Details["cache"]; ok && result.Err == nil {
+					if cache[status] == nil {
+						cache[status] = newAggregate()
+					}
+					cache[status].add(result)
+				}
+				if result.Err == nil {
+					for name, d := range serverPhases(result.Details) {
+						if serverTiming[name] == nil {
+							serverTiming[name] = newAggregate()
+						}
+						serverTiming[name].add(pool.Result{URL: result.URL, TimeTooked: d, Timestamp: result.Timestamp})
+					}
+				}
+				for _, tag := range opts.Tags[result.URL] {
+					if tags[tag] == nil {
+						tags[tag] = newAggregate()
+					}
+					tags[tag].add(result)
+				}
+				if len(intervals) > 0 {
+					i := int(result.Timestamp.Sub(start) / interval)
+					if i >= len(intervals) {
+						i = len(intervals) - 1
+					}
+					intervals[i].add(result)
+				}
+				if opts.OnResult != nil {
+					opts.OnResult(result)
+				}
+				done.Done()
+			case <-tick:
+				opts.OnProgress(Progress{Elapsed: clk.Since(start), Total: total.totals(), Last: last.totals()})
+				last = newAggregate()
+				progress = clk.Timer(opts.Progress)
+				tick = progress.C()
+			}
+		}
+	}()
+
+	// Dispara os jobs de acordo com o relógio: a cada volta, são enviados os jobs que já deveriam
+	// ter sido disparados até o momento
+	sent := 0
+	for len(jobs) > 0 {
+		elapsed := clk.Since(start)
+		if elapsed >= duration {
+			break
+		}
+		expected := int(opts.Profile.Hits(elapsed))
+		for ; sent < expected && clk.Since(start) < duration; sent++ {
+			done.Add(1)
+			p.Submit(jobs[sent%len(jobs)], replies[opts.Profile.stageAt(clk.Since(start))])
+		}
+		clock.Sleep(clk, nextHit(opts.Profile.Rate(elapsed)))
+	}
+
+	summary := Summary{Duration: clk.Since(start), Requests: sent}
+
+	// Aguarda as respostas das requisições em andamento
+	done.Wait()
+	summary.Wait = clk.Since(start) - summary.Duration
+	for _, reply := range replies {
+		close(reply)
+	}
+	forwarding.Wait()
+	close(collected)
+	collecting.Wait()
+
+	summary.Errors = total.errors
+	summary.ErrorCounts = total.errorCounts
+	summary.Latencies = total.latencies.Latencies()
+	summary.QueueWait = total.queueWait.Latencies()
+	if summary.Duration > 0 {
+		summary.Rate = float64(summary.Requests) / summary.Duration.Seconds()
+		summary.Throughput = float64(total.latencies.Count()) / (summary.Duration + summary.Wait).Seconds()
+	}
+	for i, agg := range intervals {
+		at := time.Duration(i) * interval
+		summary.Intervals = append(summary.Intervals, Interval{
+			Start:     at,
+			Rate:      opts.Profile.Rate(at + interval/2),
+			Requests:  agg.requests,
+			Errors:    agg.errors,
+			Latencies: agg.latencies.Latencies(),
+			QueueWait: agg.queueWait.Latencies(),
+		})
+	}
+	if len(cache) > 0 {
+		summary.Cache = make(map[string]Totals, len(cache))
+		for status, agg := range cache {
+			summary.Cache[status] = agg.totals()
+		}
+	}
+	if len(tags) > 0 {
+		summary.Tags = make(map[string]Totals, len(tags))
+		for tag, agg := range tags {
+			summary.Tags[tag] = agg.totals()
+		}
+	}
+	if len(serverTiming) > 0 {
+		summary.ServerTiming = make(map[string]Totals, len(serverTiming))
+		for name, agg := range serverTiming {
+			summary.ServerTiming[name] = agg.totals()
+		}
+	}
+	var at time.Duration
+	for i, stage := range opts.Profile {
+		summary.Phases = append(summary.Phases, Phase{
+			Name:      stage.label(i),
+			Start:     at,
+			Duration:  stage.Duration,
+			Requests:  phases[i].requests,
+			Errors:    phases[i].errors,
+			Latencies: phases[i].latencies.Latencies(),
+		})
+		at += stage.Duration
+	}
+	return summary
+}
+
+// phaseResult é um resultado acompanhado do estágio em que a requisição foi disparada
+type phaseResult struct {
+	phase  int
+	result pool.Result
+}
+
+// aggregate acumula os resultados de um trecho do teste. Os tempos de resposta e de espera na fila
+// ficam em histogramas, então a memória usada não cresce com a duração do teste
+type aggregate struct {
+	requests    int
+	errors      int
+	errorCounts map[string]int
+	latencies   *Histogram
+	queueWait   *Histogram
+}
+
+func newAggregate() *aggregate {
+	return &aggregate{errorCounts: make(map[string]int), latencies: NewHistogram(), queueWait: NewHistogram()}
+}
+
+func (a *aggregate) totals() Totals {
+	return Totals{Requests: a.requests, Errors: a.errors, Latencies: a.latencies.Latencies()}
+}
+
+func (a *aggregate) add(result pool.Result) {
+	a.requests++
+	a.queueWait.Add(result.QueueWait)
+	if result.Err != nil {
+		a.errors++
+		a.errorCounts[result.Err.Error()]++
+		return
+	}
+	a.latencies.Add(result.TimeTooked)
+}
+
+// nextHit é o tempo até o próximo disparo, limitado para que o laço reaja rapidamente às mudanças de
+// taxa e ao fim do teste
+func nextHit(rate float64) time.Duration {
+	const maxWait = 10 * time.Millisecond
+	if rate <= 0 {
+		return maxWait
+	}
+	wait := time.Duration(float64(time.Second) / rate)
+	if wait > maxWait {
+		return maxWait
+	}
+	return wait
+}
+
+// serverPhases lê dos detalhes do resultado as durações do cabeçalho Server-Timing (ver
+// probe.AddServerTiming)
+func serverPhases(details map[string]string) map[string]time.Duration {
+	phases := make(map[string]time.Duration)
+	for key, value := range details {
+		var name string
+		switch {
+		case key == "server_time_ms":
+			name = "server"
+		case key == "network_time_ms":
+			name = "network"
+		case strings.HasPrefix(key, "server_timing_") && strings.HasSuffix(key, "_ms"):
+			name = strings.TrimSuffix(strings.TrimPrefix(key, "server_timing_"), "_ms")
+		default:
+			continue
+		}
+		ms, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			continue
+		}
+		phases[name] = time.Duration(ms * float64(time.Millisecond))
+	}
+	return phases
+}
@@ -0,0 +1,197 @@
+package loadtest
+
+import (
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/clock"
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// runFake executa o teste com um relógio falso, avançando-o sempre que o laço de disparo estiver
+// aguardando e o pool não tiver nenhum job pendente, para que cada resultado seja registrado no
+// momento simulado em que foi disparado
+func runFake(t *testing.T, workers int, jobs []pool.Job, opts Options, step time.Duration) Summary {
+	t.Helper()
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	p := pool.New(workers, func(job pool.Job) pool.Result {
+		return pool.Result{URL: job.URL, TimeTooked: 5 * time.Millisecond}
+	})
+	p.SetClock(fake)
+	defer p.Close()
+	opts.Clock = fake
+
+	finished := make(chan Summary)
+	go func() { finished <- Run(p, jobs, opts) }()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		select {
+		case s := <-finished:
+			return s
+		default:
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("load test did not finish")
+		}
+		if stats := p.Stats(); fake.Waiting() > 0 && stats.QueueDepth == 0 && stats.Busy == 0 {
+			fake.Advance(step)
+			continue
+		}
+		time.Sleep(50 * time.Microsecond)
+	}
+}
+
+func TestRunPacesConstantRate(t *testing.T) {
+	s := runFake(t, 2, pool.JobsFromURLs([]string{"http://a", "http://b"}), Options{
+		Profile:  Constant(10, 2*time.Second),
+		Interval: time.Second,
+	}, 10*time.Millisecond)
+
+	// Os disparos acontecem a cada 100ms a partir do primeiro; no instante final o teste já acabou
+	if s.Requests != 19 {
+		t.Errorf("Requests = %d, want 19", s.Requests)
+	}
+	if s.Duration != 2*time.Second {
+		t.Errorf("Duration = %s, want 2s", s.Duration)
+	}
+	if s.Errors != 0 {
+		t.Errorf("Errors = %d, want 0", s.Errors)
+	}
+	if math.Abs(s.Rate-9.5) > 1e-9 {
+		t.Errorf("Rate = %f, want 9.5", s.Rate)
+	}
+	if s.Latencies.P50 < 4900*time.Microsecond || s.Latencies.P50 > 5100*time.Microsecond {
+		t.Errorf("P50 = %s, want about 5ms", s.Latencies.P50)
+	}
+
+	want := []int{9, 10}
+	if len(s.Intervals) != len(want) {
+		t.Fatalf("got %d intervals, want %d", len(s.Intervals), len(want))
+	}
+	for i, w := range want {
+		if got := s.Intervals[i]; got.Requests != w || got.Start != time.Duration(i)*time.Second {
+			t.Errorf("interval %d = %d requests at %s, want %d at %s", i, got.Requests, got.Start, w, time.Duration(i)*time.Second)
+		}
+	}
+}
+
+func TestRunAttributesResultsToStages(t *testing.T) {
+	s := runFake(t, 4, pool.JobsFromURLs([]string{"http://a"}), Options{
+		Profile:  Spike(10, 50, time.Second, time.Second, time.Second),
+		Interval: time.Second,
+	}, 10*time.Millisecond)
+
+	// 10 + 50 + 10 disparos, menos o que cairia exatamente no fim do teste
+	if s.Requests != 69 {
+		t.Errorf("Requests = %d, want 69", s.Requests)
+	}
+	want := []struct {
+		name     string
+		requests int
+	}{{"baseline", 9}, {"spike", 50}, {"recovery", 10}}
+	if len(s.Phases) != len(want) {
+		t.Fatalf("got %d phases, want %d", len(s.Phases), len(want))
+	}
+	for i, w := range want {
+		if got := s.Phases[i]; got.Name != w.name || got.Requests != w.requests {
+			t.Errorf("phase %d = %s with %d requests, want %s with %d", i, got.Name, got.Requests, w.name, w.requests)
+		}
+	}
+}
+
+func TestProfileHitsAndRate(t *testing.T) {
+	ramp, err := ParseProfile("0-100rps/10s,100rps/5s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		elapsed time.Duration
+		rate    float64
+		hits    float64
+	}{
+		{0, 0, 0},
+		{5 * time.Second, 50, 125},
+		{10 * time.Second, 100, 500},
+		{12 * time.Second, 100, 700},
+		{15 * time.Second, 0, 1000},
+	}
+	for _, tt := range tests {
+		if got := ramp.Rate(tt.elapsed); math.Abs(got-tt.rate) > 1e-9 {
+			t.Errorf("Rate(%s) = %f, want %f", tt.elapsed, got, tt.rate)
+		}
+		if got := ramp.Hits(tt.elapsed); math.Abs(got-tt.hits) > 1e-9 {
+			t.Errorf("Hits(%s) = %f, want %f", tt.elapsed, got, tt.hits)
+		}
+	}
+}
+
+func TestRunSubmitsJobsAsIs(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	timeouts := make(chan time.Duration, 100)
+	p := pool.New(1, func(job pool.Job) pool.Result {
+		timeouts <- job.Timeout
+		return pool.Result{URL: job.URL}
+	})
+	p.SetClock(fake)
+	defer p.Close()
+
+	finished := make(chan Summary)
+	jobs := []pool.Job{{URL: "http://download", Timeout: 30 * time.Second}}
+	go func() { finished <- Run(p, jobs, Options{Profile: Constant(10, time.Second), Clock: fake}) }()
+	for {
+		select {
+		case s := <-finished:
+			close(timeouts)
+			if s.Requests == 0 {
+				t.Fatal("no request was sent")
+			}
+			for timeout := range timeouts {
+				if timeout != 30*time.Second {
+					t.Fatalf("job submitted with timeout %s, want the entry's 30s", timeout)
+				}
+			}
+			return
+		default:
+		}
+		if fake.Waiting() > 0 {
+			fake.Advance(10 * time.Millisecond)
+		}
+		time.Sleep(50 * time.Microsecond)
+	}
+}
+
+func TestProfileValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		profile Profile
+		wantErr string
+	}{
+		{"constant", Constant(10, time.Second), ""},
+		{"spike", Spike(10, 50, time.Second, time.Second, time.Second), ""},
+		{"ramp from zero", Profile{{From: 0, To: 10, Duration: time.Second}}, ""},
+		{"empty", nil, "no stages"},
+		{"zero duration", Constant(10, 0), "duration must be greater than zero"},
+		{"negative duration", Constant(10, -time.Second), "duration must be greater than zero"},
+		{"zero rate", Constant(0, time.Minute), "sends no requests"},
+		{"negative rate", Constant(-5, time.Minute), "invalid rate"},
+		{"NaN rate", Constant(math.NaN(), time.Minute), "invalid rate"},
+		{"infinite rate", Constant(math.Inf(1), time.Minute), "invalid rate"},
+		{"named stage", Spike(10, 50, time.Second, 0, time.Second), "spike: duration"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.profile.Validate()
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("Validate() = %v, want nil", err)
+				}
+				return
+			}
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("Validate() = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
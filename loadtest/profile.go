@@ -0,0 +1,171 @@
+package loadtest
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Stage é um trecho do teste em que a taxa varia linearmente de From até To requisições por
+// segundo ao longo de Duration. Uma taxa constante é um estágio com From igual a To
+type Stage struct {
+	// Name identifica o estágio no resumo; quando vazio, o estágio é identificado pela sua posição
+	Name     string
+	From     float64
+	To       float64
+	Duration time.Duration
+}
+
+func (s Stage) label(i int) string {
+	if s.Name != "" {
+		return s.Name
+	}
+	return fmt.Sprintf("stage %d", i+1)
+}
+
+// rate é a taxa do estágio após elapsed do seu início
+func (s Stage) rate(elapsed time.Duration) float64 {
+	if s.Duration <= 0 {
+		return s.To
+	}
+	return s.From + (s.To-s.From)*elapsed.Seconds()/s.Duration.Seconds()
+}
+
+// hits é a quantidade de disparos do estágio até elapsed do seu início, ou seja, a área sob a rampa
+func (s Stage) hits(elapsed time.Duration) float64 {
+	return (s.From + s.rate(elapsed)) / 2 * elapsed.Seconds()
+}
+
+// Profile é a sequência de estágios de um teste de carga
+type Profile []Stage
+
+// Constant é um perfil com uma única taxa fixa
+func Constant(rate float64, duration time.Duration) Profile {
+	return Profile{{From: rate, To: rate, Duration: duration}}
+}
+
+// Spike é um perfil que mantém a taxa baseline por before, sobe de repente para peak por burst e
+// volta para baseline por after, útil para validar o autoscaling e a limitação de taxa dos serviços
+func Spike(baseline, peak float64, before, burst, after time.Duration) Profile {
+	return Profile{
+		{Name: "baseline", From: baseline, To: baseline, Duration: before},
+		{Name: "spike", From: peak, To: peak, Duration: burst},
+		{Name: "recovery", From: baseline, To: baseline, Duration: after},
+	}
+}
+
+// Duration é a duração total do perfil
+func (p Profile) Duration() time.Duration {
+	var total time.Duration
+	for _, s := range p {
+		total += s.Duration
+	}
+	return total
+}
+
+// Rate é a taxa alvo após elapsed do início do teste
+func (p Profile) Rate(elapsed time.Duration) float64 {
+	for _, s := range p {
+		if elapsed < s.Duration {
+			return s.rate(elapsed)
+		}
+		elapsed -= s.Duration
+	}
+	return 0
+}
+
+// stageAt é o índice do estágio em andamento após elapsed do início do teste; depois do fim do
+// perfil, é considerado o último estágio
+func (p Profile) stageAt(elapsed time.Duration) int {
+	for i, s := range p {
+		if elapsed < s.Duration {
+			return i
+		}
+		elapsed -= s.Duration
+	}
+	return len(p) - 1
+}
+
+// Hits é a quantidade de disparos que deveriam ter sido feitos até elapsed do início do teste
+func (p Profile) Hits(elapsed time.Duration) float64 {
+	var total float64
+	for _, s := range p {
+		if elapsed < s.Duration {
+			return total + s.hits(elapsed)
+		}
+		total += s.hits(s.Duration)
+		elapsed -= s.Duration
+	}
+	return total
+}
+
+// Validate verifica se o perfil pode ser executado: cada estágio precisa ter duração positiva e taxas
+// finitas e não negativas, e o perfil precisa disparar ao menos uma requisição
+func (p Profile) Validate() error {
+	if len(p) == 0 {
+		return errors.New("load profile has no stages")
+	}
+	for i, s := range p {
+		if s.Duration <= 0 {
+			return fmt.Errorf("%s: duration must be greater than zero", s.label(i))
+		}
+		for _, rate := range []float64{s.From, s.To} {
+			if rate < 0 || math.IsNaN(rate) || math.IsInf(rate, 0) {
+				return fmt.Errorf("%s: invalid rate %v", s.label(i), rate)
+			}
+		}
+	}
+	if p.Hits(p.Duration()) < 1 {
+		return errors.New("load profile sends no requests: rates must be greater than zero")
+	}
+	return nil
+}
+
+// ParseProfile interpreta um perfil no formato "0-100rps/60s", em que a taxa sobe linearmente de 0 a
+// 100 requisições por segundo em 60 segundos. Uma taxa fixa é escrita como "50rps/2m" e vários
+// estágios podem ser encadeados separados por vírgula (ex: "0-100rps/60s,100rps/5m,100-0rps/30s")
+func ParseProfile(spec string) (Profile, error) {
+	var profile Profile
+	for _, part := range strings.Split(spec, ",") {
+		stage, err := parseStage(strings.TrimSpace(part))
+		if err != nil {
+			return nil, fmt.Errorf("invalid stage %q: %w", part, err)
+		}
+		profile = append(profile, stage)
+	}
+	return profile, nil
+}
+
+func parseStage(spec string) (Stage, error) {
+	rates, duration, ok := strings.Cut(spec, "/")
+	if !ok {
+		return Stage{}, fmt.Errorf("expected <rate>rps/<duration>")
+	}
+	d, err := time.ParseDuration(duration)
+	if err != nil {
+		return Stage{}, err
+	}
+	if d <= 0 {
+		return Stage{}, fmt.Errorf("duration must be greater than zero")
+	}
+	rates = strings.TrimSuffix(rates, "rps")
+	from, to, isRamp := strings.Cut(rates, "-")
+	if !isRamp {
+		to = from
+	}
+	fromRate, err := strconv.ParseFloat(from, 64)
+	if err != nil {
+		return Stage{}, err
+	}
+	toRate, err := strconv.ParseFloat(to, 64)
+	if err != nil {
+		return Stage{}, err
+	}
+	if fromRate < 0 || toRate < 0 {
+		return Stage{}, fmt.Errorf("rates must not be negative")
+	}
+	return Stage{From: fromRate, To: toRate, Duration: d}, nil
+}
@@ -1,13 +1,24 @@
 package main
 
 import (
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"flag"
 	"fmt"
+	"io"
 	"net/http"
+	"os"
+	"strings"
 	"sync"
 	"time"
 
-	"github.com/joaomarcelofa/entendendo-worker-pool/urls"
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+	"github.com/joaomarcelofa/entendendo-worker-pool/probe"
+	"github.com/joaomarcelofa/entendendo-worker-pool/report"
+	"github.com/joaomarcelofa/entendendo-worker-pool/sheets"
+	"github.com/joaomarcelofa/entendendo-worker-pool/sink"
+	"github.com/joaomarcelofa/entendendo-worker-pool/upload"
 )
 
 // Result é uma estrutura de dados que representa um par de URL x Tempo de reposta
@@ -17,21 +28,211 @@ type Result struct {
 }
 
 func main() {
+	var err error
+	// Verifica se algum modo foi solicitado na linha de comando; sem modo, a comparação entre os
+	// métodos é executada, aceitando as suas próprias flags
+	if len(os.Args) > 1 && !strings.HasPrefix(os.Args[1], "-") {
+		err = runCommand(os.Args[1], os.Args[2:])
+	} else {
+		err = runComparison(os.Args[1:])
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", err.Error())
+		os.Exit(1)
+	}
+}
+
+func runComparison(args []string) error {
+	var reports, sinkSpecs stringList
+	fs := flag.NewFlagSet("entendendo-worker-pool", flag.ExitOnError)
+	sinkFlag(fs, &sinkSpecs)
+	labels := labelFlag(fs)
+	fs.Var(&reports, "report", "write a report of the run to this file (the extension picks the format: .json, .html, .md, .csv or .txt); may be repeated")
+	uploadTo := fs.String("upload", "", "upload the generated reports to object storage (s3://bucket/prefix/ or gs://bucket/prefix/)")
+	sheetID := fs.String("sheet-id", "", "append the results to this Google Sheets spreadsheet")
+	sheetRange := fs.String("sheet-range", "Sheet1", "sheet (or A1 range) receiving the appended rows")
+	sheetRows := fs.String("sheet-rows", "run", "rows appended to the spreadsheet: \"run\" (one per method) or \"url\" (one per visit)")
+	ping := fs.Bool("ping", false, "also ping every host (ICMP echo), so the network RTT appears next to the HTTP latency")
+	connsPerHost := fs.Bool("conn-stats", false, "show, per host, how many requests reused a connection and how many opened a new one")
+	dryRun := dryRunFlag(fs)
+	format := formatFlag(fs)
+	profile := profileFlag(fs)
+	tags := tagsFlag(fs)
+	auditSecurity := fs.Bool("audit-security", false, "also audit the security headers of every response (HSTS, CSP, X-Content-Type-Options...) and show a score per URL")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if *sheetRows != "run" && *sheetRows != "url" {
+		return fmt.Errorf("invalid -sheet-rows %q", *sheetRows)
+	}
+	// Os jobs mantêm as opções de cada entrada do perfil, como o timeout próprio da URL
+	list, err := profileJobs(*profile)
+	if err == nil {
+		list, err = filterTags(list, *tags)
+	}
+	if err != nil {
+		return err
+	}
+	if *dryRun {
+		return printPlan(fs, list, 5*time.Second)
+	}
+	output, err := startOutput(*format)
+	if err != nil {
+		return err
+	}
+	defer output.restore()
+
+	sinks, err := openSinks(sinkSpecs, labels, "stdout")
+	if err != nil {
+		return err
+	}
+	rep := report.New()
+	rep.Labels = labels
+
 	fmt.Println("Method 1 - Sequential")
+	rec := &recorder{sinks: sinks}
 	start := time.Now()
-	result := getFastestURLSequential(urls.List)
+	result := getFastestURLSequential(list, rec)
 	elapsed := time.Since(start)
 	fmt.Printf("Fastest URL: %s - %s\n", result.URL, result.TimeTooked)
 	fmt.Printf("Total time tooked on Method 1: %s\n", elapsed)
+	rec.conns.print(*connsPerHost)
+	rep.Add(rec.method("Sequential", elapsed, result))
 
 	fmt.Printf("\n\n\n")
 
 	fmt.Println("Method 2 - Worker pool")
+	rec = &recorder{sinks: sinks}
 	start = time.Now()
-	result = getFastestURLWorkerPool(urls.List)
+	result = getFastestURLWorkerPool(list, rec)
 	elapsed = time.Since(start)
 	fmt.Printf("Fastest URL: %s - %s\n", result.URL, result.TimeTooked)
 	fmt.Printf("Total time tooked on Method 2: %s\n", elapsed)
+	rec.conns.print(*connsPerHost)
+	rep.Add(rec.method("Worker pool", elapsed, result))
+
+	// Mede o RTT da rede para os mesmos hosts, permitindo comparar com a latência HTTP no mesmo relatório
+	if *ping {
+		fmt.Printf("\n\n\n")
+
+		fmt.Println("Method 3 - ICMP ping")
+		rec = &recorder{sinks: sinks}
+		start = time.Now()
+		result = getFastestPing(list, rec)
+		elapsed = time.Since(start)
+		fmt.Printf("Fastest host: %s - %s\n", result.URL, result.TimeTooked)
+		fmt.Printf("Total time tooked on Method 3: %s\n", elapsed)
+		rep.Add(rec.method("ICMP ping", elapsed, result))
+	}
+
+	// Audita os cabeçalhos de segurança das respostas, mostrando a pontuação ao lado da latência
+	if *auditSecurity {
+		fmt.Printf("\n\n\n")
+
+		fmt.Println("Security headers audit")
+		rep.Security = auditSecurityHeaders(list)
+		printSecurityAudit(rep.Security)
+	}
+	flushSinks(sinks)
+	if err := output.write(rep); err != nil {
+		return err
+	}
+
+	// Gera os relatórios solicitados e, opcionalmente, envia para o armazenamento de objetos
+	for _, path := range reports {
+		if err := rep.WriteFile(path); err != nil {
+			return err
+		}
+		fmt.Printf("Report written to %s\n", path)
+		if *uploadTo != "" {
+			location, err := upload.File(path, *uploadTo)
+			if err != nil {
+				return err
+			}
+			fmt.Printf("Report uploaded to %s\n", location)
+		}
+	}
+
+	// Exporta os resultados para o Google Sheets
+	if *sheetID != "" {
+		rows := sheets.RowsPerRun(rep)
+		if *sheetRows == "url" {
+			rows = sheets.RowsPerURL(rep)
+		}
+		if err := sheets.Append(*sheetID, *sheetRange, rows); err != nil {
+			return err
+		}
+		fmt.Printf("%d row(s) appended to spreadsheet %s\n", len(rows), *sheetID)
+	}
+	return nil
+}
+
+// recorder guarda todas as visitas feitas por um método, para que possam ser incluídas nos relatórios,
+// e as envia para os sinks configurados (por padrão, a saída padrão)
+// Como os workers registram as visitas simultaneamente, o acesso é protegido por um mutex
+type recorder struct {
+	mux     sync.Mutex
+	sinks   sink.Sink
+	results []pool.Result
+	// conns conta o reaproveitamento das conexões HTTP feitas pelo método
+	conns connStats
+}
+
+func (r *recorder) add(url string, elapsed time.Duration, err error) {
+	r.record(pool.Result{URL: url, TimeTooked: elapsed, Err: err, Timestamp: time.Now()})
+}
+
+func (r *recorder) record(result pool.Result) {
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	r.results = append(r.results, result)
+	writeResult(r.sinks, result)
+}
+
+func (r *recorder) method(name string, elapsed time.Duration, fastest Result) report.Method {
+	return report.Method{
+		Name:    name,
+		Elapsed: elapsed,
+		Fastest: pool.Result{URL: fastest.URL, TimeTooked: fastest.TimeTooked},
+		Results: r.results,
+	}
+}
+
+// runCommand executa o modo solicitado na linha de comando
+// commandNames são os subcomandos aceitos por runCommand, usados na geração do autocompletar
+var commandNames = []string{"monitor", "serve", "agent", "coordinate", "consume", "enqueue", "loadtest", "compare", "matrix", "links", "mock", "completion", "version", "report"}
+
+func runCommand(name string, args []string) error {
+	switch name {
+	case "monitor":
+		return runMonitor(args)
+	case "serve", "agent":
+		return runServe(args)
+	case "coordinate":
+		return runCoordinate(args)
+	case "consume":
+		return runConsume(args)
+	case "enqueue":
+		return runEnqueue(args)
+	case "loadtest":
+		return runLoadTest(args)
+	case "compare":
+		return runCompare(args)
+	case "matrix":
+		return runMatrix(args)
+	case "links":
+		return runLinks(args)
+	case "mock":
+		return runMock(args)
+	case "completion":
+		return runCompletion(args)
+	case "version":
+		return runVersion(args)
+	case "report":
+		return runReport(args)
+	default:
+		return fmt.Errorf("unknown command %q", name)
+	}
 }
 
 func createSimpleHTTPClient(timeout int) *http.Client {
@@ -41,44 +242,105 @@ func createSimpleHTTPClient(timeout int) *http.Client {
 	}
 }
 
-func visitURL(client *http.Client, url string) (time.Duration, error) {
+// httpVisit é a medição padrão dos workers: a visita a uma URL, assim como nos métodos do artigo.
+// O detalhe "cache" indica se a resposta veio do cache de uma CDN (HIT, MISS ou UNKNOWN) e, quando o
+// servidor envia o cabeçalho Server-Timing, as suas fases também entram nos detalhes (ver probe.AddServerTiming)
+func httpVisit(httpClient probe.Doer) pool.VisitFunc {
+	return func(job pool.Job) pool.Result {
+		elapsed, header, err := fetchURL(probe.Client(httpClient, job), job.URL)
+		result := pool.Result{URL: job.URL, TimeTooked: elapsed, Err: err}
+		if err == nil {
+			result.Details = map[string]string{"cache": probe.CacheStatus(header)}
+			probe.AddServerTiming(result.Details, header, elapsed)
+		}
+		return result
+	}
+}
+
+// httpHashVisit é a visita HTTP padrão que também lê o corpo da resposta, registrando o seu hash
+// SHA-256 no detalhe "body_sha256" para a detecção de mudanças de conteúdo. O tempo medido continua
+// sendo o da visita padrão, até a chegada dos cabeçalhos
+func httpHashVisit(httpClient probe.Doer) pool.VisitFunc {
+	return func(job pool.Job) pool.Result {
+		elapsed, resp, err := openURL(probe.Client(httpClient, job), job.URL)
+		if err != nil {
+			return pool.Result{URL: job.URL, Err: err}
+		}
+		defer resp.Body.Close()
+		hash := sha256.New()
+		if _, err := io.Copy(hash, resp.Body); err != nil {
+			return pool.Result{URL: job.URL, Err: err}
+		}
+		details := map[string]string{
+			"cache":       probe.CacheStatus(resp.Header),
+			"body_sha256": hex.EncodeToString(hash.Sum(nil)),
+		}
+		probe.AddServerTiming(details, resp.Header, elapsed)
+		return pool.Result{URL: job.URL, TimeTooked: elapsed, Details: details}
+	}
+}
+
+func visitURL(client probe.Doer, url string) (time.Duration, error) {
+	elapsed, _, err := fetchURL(client, url)
+	return elapsed, err
+}
+
+// fetchURL visita a URL devolvendo também os cabeçalhos da resposta
+func fetchURL(client probe.Doer, url string) (time.Duration, http.Header, error) {
+	elapsed, resp, err := openURL(client, url)
+	if err != nil {
+		return time.Duration(0), nil, err
+	}
+	resp.Body.Close()
+	return elapsed, resp.Header, nil
+}
+
+// openURL visita a URL e devolve a resposta com o corpo ainda aberto, que deve ser fechado por quem chama
+func openURL(client probe.Doer, url string) (time.Duration, *http.Response, error) {
 	// Monta a requisição
 	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return time.Duration(0), nil, err
+	}
 	// Começa a contar o tempo
 	start := time.Now()
 	// Efetua a requisição
 	resp, err := client.Do(req)
 	if err != nil {
-		return time.Duration(0), err
+		return time.Duration(0), nil, err
 	}
 	// Finaliza a contagem do tempo
 	elapsed := time.Since(start)
 	// Verifica se a requisição teve sucesso de acordo com o código retornado
 	if resp.StatusCode != 200 {
-		return time.Duration(0), errors.New("Status code 200 not returned")
+		resp.Body.Close()
+		return time.Duration(0), nil, errors.New("Status code 200 not returned")
 	}
-	return elapsed, nil
+	return elapsed, resp, nil
 }
 
-func getFastestURLSequential(urls []string) Result {
+func getFastestURLSequential(jobs []pool.Job, rec *recorder) Result {
 	// Declarando a variável que irá armazenar a URL com o tempo de resposta mais rápida e
 	// o próprio tempo de resposta
 	var fastestTime time.Duration
 	fastestURL := ""
 
 	httpClient := createSimpleHTTPClient(5)
+	rec.conns.track(httpClient)
 
 	// Visitando todas as URLs da lista de URLs
-	for _, url := range urls {
-		// Visitando a URL medindo o tempo de resposta
-		elapsed, err := visitURL(httpClient, url)
+	for _, job := range jobs {
+		url := job.URL
+		// Visitando a URL medindo o tempo de resposta; uma entrada com timeout próprio usa uma cópia
+		// do cliente com esse timeout
+		elapsed, err := visitURL(probe.Client(httpClient, job), url)
+		// Registrando a visita, que é enviada para os sinks (saída padrão, arquivos, webhooks...)
+		rec.add(url, elapsed, err)
 		// Verificando se houve erro com a requisição
 		if err != nil {
 			// Em caso de erro, o tempo de solicitação será desconsiderado
-			fmt.Printf("Error at getting url %s\nError: %s\n", url, err.Error())
 			continue
 		}
-		fmt.Printf("Visited %s - Took: %s\n", url, elapsed)
 
 		// Atualizando o menor tempo
 		if fastestTime == time.Duration(0) {
@@ -99,11 +361,11 @@ func getFastestURLSequential(urls []string) Result {
 	}
 }
 
-func getFastestURLWorkerPool(urls []string) Result {
+func getFastestURLWorkerPool(jobs []pool.Job, rec *recorder) Result {
 	// 1. Declarando um waiting group para sincronizar todos os workers
 	// Obs: O grupo de espera deve ter o mesmo tamanho da lista de URLs recebidas
 	var wg sync.WaitGroup
-	wg.Add(len(urls))
+	wg.Add(len(jobs))
 
 	// 2. Declarando a variável compatilhada para armazenar o resultado da URL mais rápida
 	// Apesar desta variável ser compartilhada, sua declaração não difere das outras, pois
@@ -115,17 +377,17 @@ func getFastestURLWorkerPool(urls []string) Result {
 
 	// 4. Declarando os workers
 	qtyWorkers := 8 // Altere o número de workers aqui
-	urlCh := make(chan string, qtyWorkers)
+	jobCh := make(chan pool.Job, qtyWorkers)
 
 	// 5. Criando os workers
 	for i := 0; i < qtyWorkers; i++ {
 		// Criando uma goroutine para cada worker
-		go getFastestURLByWorker(urlCh, &wg, &mux, &fastestResult)
+		go getFastestURLByWorker(jobCh, &wg, &mux, &fastestResult, rec)
 	}
 
 	// 6. Distribuindo as URLs para os workers através do channel
-	for _, url := range urls {
-		urlCh <- url
+	for _, job := range jobs {
+		jobCh <- job
 	}
 
 	// 7. Ponto de espera até que o waiting group tenha sua condição satisfeita, ou seja,
@@ -135,19 +397,67 @@ func getFastestURLWorkerPool(urls []string) Result {
 	return fastestResult
 }
 
-// A função getFastestURLByWorker deve receber o canal de Urls, assim como as referências do grupo de espera,
-// da variável de controle de acesso à variável compartilhada e a referência da variável compartilhada
-func getFastestURLByWorker(urlCh <-chan string, wg *sync.WaitGroup, mux *sync.Mutex, fastestResult *Result) {
+// getFastestPing envia um eco ICMP para o host de cada URL através do worker pool
+func getFastestPing(jobs []pool.Job, rec *recorder) Result {
+	p := pool.New(8, probe.ICMP(5*time.Second))
+	defer p.Close()
+
+	var fastestResult Result
+	for result := range p.Stream(jobs) {
+		rec.record(result)
+		if result.Err != nil {
+			continue
+		}
+		if fastestResult.TimeTooked == time.Duration(0) || result.TimeTooked < fastestResult.TimeTooked {
+			fastestResult = Result{URL: result.URL, TimeTooked: result.TimeTooked}
+		}
+	}
+	return fastestResult
+}
+
+// auditSecurityHeaders visita cada URL através do worker pool auditando os cabeçalhos de segurança.
+// Os resultados seguem a ordem da lista de URLs
+func auditSecurityHeaders(jobs []pool.Job) []pool.Result {
+	p := pool.New(8, probe.SecurityHeaders(createSimpleHTTPClient(5)))
+	defer p.Close()
+
+	byURL := make(map[string]pool.Result, len(jobs))
+	for result := range p.Stream(jobs) {
+		byURL[result.URL] = result
+	}
+	results := make([]pool.Result, 0, len(jobs))
+	for _, job := range jobs {
+		results = append(results, byURL[job.URL])
+	}
+	return results
+}
+
+func printSecurityAudit(results []pool.Result) {
+	fmt.Printf("%-50s %14s %6s  %s\n", "URL", "Latency", "Score", "Failed checks")
+	for _, r := range results {
+		if r.Err != nil {
+			fmt.Printf("%-50s %14s %6s  %s\n", r.URL, "-", "-", r.Err)
+			continue
+		}
+		fmt.Printf("%-50s %14s %6s  %s\n", r.URL, r.TimeTooked, r.Details["score"], r.Details["failed"])
+	}
+}
+
+// A função getFastestURLByWorker deve receber o canal de jobs, assim como as referências do grupo de espera,
+// da variável de controle de acesso à variável compartilhada e a referência da variável compartilhada.
+// O recorder recebe todas as visitas para a geração dos relatórios
+func getFastestURLByWorker(jobCh <-chan pool.Job, wg *sync.WaitGroup, mux *sync.Mutex, fastestResult *Result, rec *recorder) {
 	httpClient := createSimpleHTTPClient(5)
+	rec.conns.track(httpClient)
 	// Visitando a URL recebida pelo channel
-	for url := range urlCh {
-		// Visitando a URL medindo o tempo de resposta
-		elapsed, err := visitURL(httpClient, url)
-		// Verificando se houve erro com a requisição
-		if err != nil {
-			fmt.Printf("Error at getting url %s\nError: %s\n", url, err.Error())
-		} else {
-			fmt.Printf("Visited %s - Took: %s\n", url, elapsed)
+	for job := range jobCh {
+		url := job.URL
+		// Visitando a URL medindo o tempo de resposta, com o timeout próprio da entrada, se houver
+		elapsed, err := visitURL(probe.Client(httpClient, job), url)
+		// Registrando a visita, que é enviada para os sinks (saída padrão, arquivos, webhooks...)
+		rec.add(url, elapsed, err)
+		// Verificando se houve erro com a requisição; em caso de erro, o tempo de solicitação será desconsiderado
+		if err == nil {
 			// Restringindo o acesso simultâneo a variável compartilhada
 			mux.Lock()
 
@@ -0,0 +1,166 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// stubDoer responde todas as requisições com a mesma resposta (ou erro), após delay
+type stubDoer struct {
+	status int
+	header http.Header
+	body   io.Reader
+	err    error
+	delay  time.Duration
+	// requests guarda as requisições recebidas
+	requests []*http.Request
+}
+
+func (d *stubDoer) Do(req *http.Request) (*http.Response, error) {
+	d.requests = append(d.requests, req)
+	time.Sleep(d.delay)
+	if d.err != nil {
+		return nil, d.err
+	}
+	header := d.header
+	if header == nil {
+		header = http.Header{}
+	}
+	body := d.body
+	if body == nil {
+		body = strings.NewReader("")
+	}
+	return &http.Response{StatusCode: d.status, Header: header, Body: io.NopCloser(body), Request: req}, nil
+}
+
+// failingReader devolve um erro no meio da leitura do corpo
+type failingReader struct{}
+
+func (failingReader) Read([]byte) (int, error) { return 0, errors.New("connection reset") }
+
+func TestVisitURL(t *testing.T) {
+	transportErr := errors.New("dial tcp: connection refused")
+	tests := []struct {
+		name    string
+		doer    *stubDoer
+		wantErr string
+		minTime time.Duration
+	}{
+		{name: "success", doer: &stubDoer{status: 200, delay: 20 * time.Millisecond}, minTime: 20 * time.Millisecond},
+		{name: "not found", doer: &stubDoer{status: 404}, wantErr: "Status code 200 not returned"},
+		{name: "redirect is not followed as success", doer: &stubDoer{status: 301}, wantErr: "Status code 200 not returned"},
+		{name: "transport error", doer: &stubDoer{err: transportErr}, wantErr: transportErr.Error()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			elapsed, err := visitURL(tt.doer, "http://example.test/")
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				if elapsed != 0 {
+					t.Errorf("elapsed = %s on error, want 0", elapsed)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+			if elapsed < tt.minTime {
+				t.Errorf("elapsed = %s, want at least %s", elapsed, tt.minTime)
+			}
+			if len(tt.doer.requests) != 1 || tt.doer.requests[0].Method != "GET" || tt.doer.requests[0].URL.String() != "http://example.test/" {
+				t.Errorf("requests = %v, want a single GET to the URL", tt.doer.requests)
+			}
+		})
+	}
+}
+
+func TestHTTPVisitResult(t *testing.T) {
+	tests := []struct {
+		name        string
+		doer        *stubDoer
+		wantErr     bool
+		wantDetails map[string]string
+	}{
+		{
+			name: "success with cache and server timing",
+			doer: &stubDoer{status: 200, delay: 10 * time.Millisecond, header: http.Header{
+				"X-Cache":       {"Hit from cloudfront"},
+				"Server-Timing": {"db;dur=2, app;dur=3"},
+			}},
+			wantDetails: map[string]string{"cache": "HIT", "server_time_ms": "5.000", "server_timing_db_ms": "2.000"},
+		},
+		{name: "server error", doer: &stubDoer{status: 503}, wantErr: true},
+		{name: "transport error", doer: &stubDoer{err: errors.New("i/o timeout")}, wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := httpVisit(tt.doer)(pool.Job{URL: "http://example.test/"})
+			if result.URL != "http://example.test/" {
+				t.Errorf("URL = %q", result.URL)
+			}
+			if tt.wantErr {
+				if result.Err == nil {
+					t.Fatal("Err = nil, want an error")
+				}
+				if result.Details != nil {
+					t.Errorf("Details = %v on error, want nil", result.Details)
+				}
+				return
+			}
+			if result.Err != nil {
+				t.Fatalf("Err = %v", result.Err)
+			}
+			if result.TimeTooked < tt.doer.delay {
+				t.Errorf("TimeTooked = %s, want at least %s", result.TimeTooked, tt.doer.delay)
+			}
+			for k, want := range tt.wantDetails {
+				if got := result.Details[k]; got != want {
+					t.Errorf("Details[%q] = %q, want %q", k, got, want)
+				}
+			}
+		})
+	}
+}
+
+func TestHTTPHashVisit(t *testing.T) {
+	tests := []struct {
+		name     string
+		doer     *stubDoer
+		wantErr  string
+		wantHash string
+	}{
+		{
+			name: "hashes the body",
+			doer: &stubDoer{status: 200, body: strings.NewReader("hello")},
+			// sha256("hello")
+			wantHash: "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824",
+		},
+		{name: "body read error", doer: &stubDoer{status: 200, body: failingReader{}}, wantErr: "connection reset"},
+		{name: "status error", doer: &stubDoer{status: 500}, wantErr: "Status code 200 not returned"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := httpHashVisit(tt.doer)(pool.Job{URL: "http://example.test/"})
+			if tt.wantErr != "" {
+				if result.Err == nil || !strings.Contains(result.Err.Error(), tt.wantErr) {
+					t.Fatalf("Err = %v, want %q", result.Err, tt.wantErr)
+				}
+				return
+			}
+			if result.Err != nil {
+				t.Fatalf("Err = %v", result.Err)
+			}
+			if got := result.Details["body_sha256"]; got != tt.wantHash {
+				t.Errorf("body_sha256 = %q, want %q", got, tt.wantHash)
+			}
+		})
+	}
+}
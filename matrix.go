@@ -0,0 +1,179 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+	"github.com/joaomarcelofa/entendendo-worker-pool/report"
+	"github.com/joaomarcelofa/entendendo-worker-pool/urls"
+)
+
+// vantage é um ponto de medição: um proxy, normalmente em uma região diferente, ou a conexão direta
+type vantage struct {
+	name  string
+	proxy *url.URL
+}
+
+// runMatrix mede cada URL através de cada proxy da lista, gerando uma matriz URL x região
+func runMatrix(args []string) error {
+	var reports, sinkSpecs stringList
+	fs := flag.NewFlagSet("matrix", flag.ExitOnError)
+	sinkFlag(fs, &sinkSpecs)
+	labels := labelFlag(fs)
+	proxies := fs.String("proxies", "", "file with one proxy per line, as \"<region> <proxy URL>\" or just the proxy URL")
+	direct := fs.Bool("direct", false, "also measure without a proxy, as a baseline column")
+	targetList := targetFlags(fs)
+	qtyWorkers := fs.Int("workers", 8, "number of workers for each proxy")
+	timeout := fs.Int("timeout", 5, "HTTP client timeout in seconds")
+	dryRun := dryRunFlag(fs)
+	format := formatFlag(fs)
+	fs.Var(&reports, "report", "write a report with the latency matrix to this file (the extension picks the format: .json, .html, .md, .csv or .txt); may be repeated")
+	pc := pacingFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	if *proxies == "" {
+		return errors.New("no proxy list informed (-proxies)")
+	}
+	vantages, err := readProxies(*proxies)
+	if err != nil {
+		return err
+	}
+	if *direct {
+		vantages = append([]vantage{{name: "direct"}}, vantages...)
+	}
+	jobs, err := targetList.jobs()
+	if err != nil {
+		return err
+	}
+	targets := urls.URLs(jobs)
+	if *dryRun {
+		var names []string
+		for _, v := range vantages {
+			if v.proxy != nil {
+				names = append(names, v.name+" ("+v.proxy.Redacted()+")")
+			} else {
+				names = append(names, v.name)
+			}
+		}
+		return printPlan(fs, jobs, time.Duration(*timeout)*time.Second,
+			fmt.Sprintf("Each job is measured through %d vantage point(s): %s", len(vantages), strings.Join(names, ", ")))
+	}
+	sinks, err := openSinks(sinkSpecs, labels)
+	if err != nil {
+		return err
+	}
+	output, err := startOutput(*format)
+	if err != nil {
+		return err
+	}
+	defer output.restore()
+
+	// Cada ponto de medição tem o seu próprio pool, com um cliente HTTP que passa pelo proxy
+	rep := report.New()
+	rep.Labels = labels
+	for _, v := range vantages {
+		client := createSimpleHTTPClient(*timeout)
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.Proxy = http.ProxyURL(v.proxy)
+		client.Transport = transport
+		p := pool.New(*qtyWorkers, httpVisit(client))
+		pc.apply(p)
+
+		fmt.Printf("Measuring %d URL(s) through %s\n", len(targets), v.name)
+		start := time.Now()
+		results := p.Collect(jobs)
+		elapsed := time.Since(start)
+		p.Close()
+
+		var fastest pool.Result
+		for _, r := range results {
+			writeResult(sinks, r)
+			if r.Err == nil && (fastest.TimeTooked == 0 || r.TimeTooked < fastest.TimeTooked) {
+				fastest = r
+			}
+		}
+		rep.Add(report.Method{Name: v.name, Elapsed: elapsed, Fastest: fastest, Results: results})
+	}
+	flushSinks(sinks)
+
+	rep.Matrix = report.NewMatrix(targets, rep.Methods)
+	printMatrix(rep.Matrix)
+	if err := output.write(rep); err != nil {
+		return err
+	}
+
+	for _, path := range reports {
+		if err := rep.WriteFile(path); err != nil {
+			return err
+		}
+		fmt.Printf("Report written to %s\n", path)
+	}
+	return nil
+}
+
+// readProxies lê a lista de proxies; sem o nome da região, é usado o host do proxy
+func readProxies(path string) ([]vantage, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var vantages []vantage
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+		raw := fields[len(fields)-1]
+		proxy, err := url.Parse(raw)
+		if err != nil || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy %q", raw)
+		}
+		name := proxy.Host
+		if len(fields) > 1 {
+			name = fields[0]
+		}
+		vantages = append(vantages, vantage{name: name, proxy: proxy})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(vantages) == 0 {
+		return nil, fmt.Errorf("no proxies found in %s", path)
+	}
+	return vantages, nil
+}
+
+func printMatrix(m *report.Matrix) {
+	fmt.Printf("\n%-40s", "URL")
+	for _, c := range m.Columns {
+		fmt.Printf(" %14s", c)
+	}
+	fmt.Println()
+	for _, row := range m.Rows {
+		fmt.Printf("%-40s", row.URL)
+		for _, cell := range row.Cells {
+			switch {
+			case cell.Err != nil:
+				fmt.Printf(" %14s", "error")
+			case cell.URL == "":
+				fmt.Printf(" %14s", "-")
+			default:
+				fmt.Printf(" %14s", cell.TimeTooked)
+			}
+		}
+		fmt.Println()
+	}
+}
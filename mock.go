@@ -0,0 +1,42 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/mockserver"
+)
+
+// runMock sobe o servidor de testes com injeção de falhas, útil como alvo do pool para observar o
+// seu comportamento com um serviço instável
+func runMock(args []string) error {
+	var faults mockserver.Faults
+	fs := flag.NewFlagSet("mock", flag.ExitOnError)
+	addr := fs.String("addr", ":8081", "address the mock server listens on")
+	delay := fs.String("delay", "0s", "delay applied to delayed requests, fixed (200ms) or a random range (100ms-1s)")
+	fs.Float64Var(&faults.DelayRate, "delay-rate", 0, "fraction of the requests that are delayed (0 to 1)")
+	fs.Float64Var(&faults.DropRate, "drop-rate", 0, "fraction of the connections closed without a response (0 to 1)")
+	fs.Float64Var(&faults.ErrorRate, "error-rate", 0, "fraction of the requests answered with a random 5xx (0 to 1)")
+	fs.DurationVar(&faults.BurstEvery, "burst-every", 0, "start a burst of 503 responses at this interval (0 disables bursts)")
+	fs.DurationVar(&faults.BurstFor, "burst-for", 0, "how long each burst of 503 responses lasts")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	var err error
+	if faults.MinDelay, faults.MaxDelay, err = mockserver.ParseDelay(*delay); err != nil {
+		return fmt.Errorf("invalid -delay %q: %w", *delay, err)
+	}
+	// Um atraso informado sem taxa vale para todas as requisições
+	if faults.MaxDelay > 0 && faults.DelayRate == 0 {
+		faults.DelayRate = 1
+	}
+	if err := faults.Validate(); err != nil {
+		return err
+	}
+
+	srv := mockserver.New(faults)
+	fmt.Printf("Serving mock server on %s (faults at /_chaos)\n", *addr)
+	return http.ListenAndServe(*addr, srv.Handler())
+}
@@ -0,0 +1,266 @@
+// Package mockserver implementa um servidor HTTP de testes com injeção de falhas configurável
+// (atrasos aleatórios, conexões derrubadas e rajadas de erros 5xx), para observar como o pool e as
+// medições se comportam quando o alvo falha
+package mockserver
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Faults configura as falhas injetadas nas respostas. As taxas são frações entre 0 e 1
+type Faults struct {
+	// DelayRate é a fração das requisições que esperam um tempo aleatório entre MinDelay e MaxDelay
+	DelayRate float64
+	MinDelay  time.Duration
+	MaxDelay  time.Duration
+	// DropRate é a fração das conexões encerradas sem nenhuma resposta
+	DropRate float64
+	// ErrorRate é a fração das requisições respondidas com um erro 5xx aleatório
+	ErrorRate float64
+	// A cada BurstEvery, todas as requisições são respondidas com 503 durante BurstFor, simulando uma
+	// indisponibilidade
+	BurstEvery time.Duration
+	BurstFor   time.Duration
+}
+
+// Validate verifica se as taxas e os tempos são coerentes
+func (f Faults) Validate() error {
+	for name, rate := range map[string]float64{"delay-rate": f.DelayRate, "drop-rate": f.DropRate, "error-rate": f.ErrorRate} {
+		if rate < 0 || rate > 1 {
+			return fmt.Errorf("%s must be between 0 and 1", name)
+		}
+	}
+	if f.MinDelay < 0 || f.MaxDelay < f.MinDelay {
+		return fmt.Errorf("invalid delay range %s-%s", f.MinDelay, f.MaxDelay)
+	}
+	if f.BurstEvery < 0 || f.BurstFor < 0 || (f.BurstEvery > 0 && f.BurstFor >= f.BurstEvery) {
+		return fmt.Errorf("burst-for must be shorter than burst-every")
+	}
+	return nil
+}
+
+// ParseDelay interpreta um atraso fixo ("200ms") ou um intervalo ("100ms-1s")
+func ParseDelay(spec string) (time.Duration, time.Duration, error) {
+	from, to, isRange := strings.Cut(spec, "-")
+	min, err := time.ParseDuration(strings.TrimSpace(from))
+	if err != nil {
+		return 0, 0, err
+	}
+	if !isRange {
+		return min, min, nil
+	}
+	max, err := time.ParseDuration(strings.TrimSpace(to))
+	if err != nil {
+		return 0, 0, err
+	}
+	return min, max, nil
+}
+
+// Counters conta as requisições recebidas e as falhas injetadas
+type Counters struct {
+	Requests int64 `json:"requests"`
+	Delayed  int64 `json:"delayed"`
+	Dropped  int64 `json:"dropped"`
+	Errors   int64 `json:"errors"`
+	Burst    int64 `json:"burst"`
+}
+
+// Server é o servidor de testes. As falhas podem ser alteradas com ele em execução
+type Server struct {
+	mux      sync.Mutex
+	faults   Faults
+	counters Counters
+	started  time.Time
+}
+
+// New cria um servidor com as falhas informadas
+func New(faults Faults) *Server {
+	return &Server{faults: faults, started: time.Now()}
+}
+
+// Faults devolve as falhas configuradas no momento
+func (s *Server) Faults() Faults {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	return s.faults
+}
+
+// SetFaults troca as falhas injetadas a partir da próxima requisição
+func (s *Server) SetFaults(faults Faults) error {
+	if err := faults.Validate(); err != nil {
+		return err
+	}
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	s.faults = faults
+	return nil
+}
+
+// Handler devolve o http.Handler do servidor:
+//
+//	GET  /_chaos  mostra as falhas configuradas e os contadores
+//	POST /_chaos  altera as falhas, com os mesmos nomes das flags (ex: ?error-rate=0.5&delay=100ms-1s)
+//	*    /...     qualquer outro caminho responde 200, sujeito às falhas configuradas
+//
+// Cada requisição também aceita falhas próprias na query: delay (ex: 500ms), status (ex: 503) e
+// size, o tamanho do corpo da resposta em bytes
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/_chaos", s.handleChaos)
+	mux.HandleFunc("/", s.handleRequest)
+	return mux
+}
+
+func (s *Server) handleChaos(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+	case http.MethodPost:
+		if err := r.ParseForm(); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		faults, err := applyParams(s.Faults(), r.Form)
+		if err == nil {
+			err = s.SetFaults(faults)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	s.mux.Lock()
+	f, counters := s.faults, s.counters
+	s.mux.Unlock()
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]interface{}{
+		"delay_rate":     f.DelayRate,
+		"min_delay_ms":   f.MinDelay.Milliseconds(),
+		"max_delay_ms":   f.MaxDelay.Milliseconds(),
+		"drop_rate":      f.DropRate,
+		"error_rate":     f.ErrorRate,
+		"burst_every_ms": f.BurstEvery.Milliseconds(),
+		"burst_for_ms":   f.BurstFor.Milliseconds(),
+		"counters":       counters,
+	})
+}
+
+// applyParams altera as falhas a partir dos parâmetros informados, mantendo os demais valores
+func applyParams(f Faults, params url.Values) (Faults, error) {
+	var err error
+	for name := range params {
+		value := params.Get(name)
+		switch name {
+		case "delay":
+			f.MinDelay, f.MaxDelay, err = ParseDelay(value)
+		case "delay-rate":
+			f.DelayRate, err = strconv.ParseFloat(value, 64)
+		case "drop-rate":
+			f.DropRate, err = strconv.ParseFloat(value, 64)
+		case "error-rate":
+			f.ErrorRate, err = strconv.ParseFloat(value, 64)
+		case "burst-every":
+			f.BurstEvery, err = time.ParseDuration(value)
+		case "burst-for":
+			f.BurstFor, err = time.ParseDuration(value)
+		default:
+			err = fmt.Errorf("unknown parameter")
+		}
+		if err != nil {
+			return f, fmt.Errorf("invalid %s %q: %w", name, value, err)
+		}
+	}
+	return f, nil
+}
+
+// serverErrors são os códigos usados nos erros aleatórios
+var serverErrors = []int{
+	http.StatusInternalServerError,
+	http.StatusBadGateway,
+	http.StatusServiceUnavailable,
+	http.StatusGatewayTimeout,
+}
+
+func (s *Server) handleRequest(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	s.mux.Lock()
+	f := s.faults
+	s.counters.Requests++
+	inBurst := f.BurstEvery > 0 && time.Since(s.started)%f.BurstEvery < f.BurstFor
+	drop := !inBurst && rand.Float64() < f.DropRate
+	var delay time.Duration
+	if !inBurst && !drop && rand.Float64() < f.DelayRate {
+		delay = f.MinDelay + rand.N(f.MaxDelay-f.MinDelay+1)
+	}
+	status := http.StatusOK
+	switch {
+	case inBurst:
+		status = http.StatusServiceUnavailable
+		s.counters.Burst++
+	case !drop && rand.Float64() < f.ErrorRate:
+		status = serverErrors[rand.IntN(len(serverErrors))]
+		s.counters.Errors++
+	}
+	if drop {
+		s.counters.Dropped++
+	}
+	if delay > 0 {
+		s.counters.Delayed++
+	}
+	s.mux.Unlock()
+
+	// As falhas pedidas na própria requisição têm precedência sobre as sorteadas
+	if v := query.Get("delay"); v != "" {
+		if d, err := time.ParseDuration(v); err == nil {
+			delay = d
+		}
+	}
+	if v := query.Get("status"); v != "" {
+		if code, err := strconv.Atoi(v); err == nil && code >= 100 && code <= 999 {
+			status = code
+		}
+	}
+
+	if drop {
+		// A conexão é encerrada sem resposta, como um servidor que caiu no meio da requisição
+		if hijacker, ok := w.(http.Hijacker); ok {
+			if conn, _, err := hijacker.Hijack(); err == nil {
+				conn.Close()
+				return
+			}
+		}
+		panic(http.ErrAbortHandler)
+	}
+	if delay > 0 {
+		select {
+		case <-time.After(delay):
+		case <-r.Context().Done():
+			return
+		}
+	}
+
+	body := "ok\n"
+	if v := query.Get("size"); v != "" {
+		if size, err := strconv.Atoi(v); err == nil && size >= 0 {
+			body = strings.Repeat("x", size)
+		}
+	}
+	if status != http.StatusOK {
+		body = http.StatusText(status) + "\n"
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(status)
+	w.Write([]byte(body))
+}
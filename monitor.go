@@ -0,0 +1,307 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/alert"
+	"github.com/joaomarcelofa/entendendo-worker-pool/anomaly"
+	"github.com/joaomarcelofa/entendendo-worker-pool/history"
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+	"github.com/joaomarcelofa/entendendo-worker-pool/server"
+	"github.com/joaomarcelofa/entendendo-worker-pool/sink"
+)
+
+// monitorConfig agrupa as opções do modo monitor
+type monitorConfig struct {
+	interval    time.Duration
+	threshold   time.Duration
+	qtyWorkers  int
+	timeout     int
+	pagerDuty   string
+	opsgenie    string
+	opsgenieURL string
+	mqttBroker  string
+	mqttTopic   string
+	mqttQoS     int
+	dashboard   string
+	origins     stringList
+	history     string
+	historyKeep string
+	// anomaly ativa a detecção de picos de latência (ver pacote anomaly); com anomalyAlert, os picos
+	// também abrem incidentes, como as violações do limite fixo
+	anomaly          bool
+	anomalyWindow    int
+	anomalyThreshold float64
+	anomalyAlert     bool
+	sinks            stringList
+	labels           labelList
+}
+
+func runMonitor(args []string) error {
+	var cfg monitorConfig
+	fs := flag.NewFlagSet("monitor", flag.ExitOnError)
+	fs.DurationVar(&cfg.interval, "interval", 30*time.Second, "time between measurement rounds")
+	fs.DurationVar(&cfg.threshold, "threshold", time.Second, "response time above which a URL is considered in breach")
+	fs.IntVar(&cfg.qtyWorkers, "workers", 8, "number of workers")
+	fs.IntVar(&cfg.timeout, "timeout", 5, "HTTP client timeout in seconds")
+	fs.StringVar(&cfg.pagerDuty, "pagerduty-key", "", "PagerDuty Events API v2 routing key")
+	fs.StringVar(&cfg.opsgenie, "opsgenie-key", "", "Opsgenie API key")
+	fs.StringVar(&cfg.opsgenieURL, "opsgenie-url", alert.OpsgenieAlertsURL, "Opsgenie Alert API endpoint")
+	fs.StringVar(&cfg.mqttBroker, "mqtt-broker", "", "MQTT broker to publish each result to (tcp://[user:pass@]host:port)")
+	fs.StringVar(&cfg.mqttTopic, "mqtt-topic", "entendendo-worker-pool/results", "MQTT topic for published results")
+	fs.IntVar(&cfg.mqttQoS, "mqtt-qos", 0, "MQTT QoS level for published results (0 or 1)")
+	sinkFlag(fs, &cfg.sinks)
+	cfg.labels = labelFlag(fs)
+	fs.StringVar(&cfg.dashboard, "dashboard", "", "address to serve the web dashboard on (e.g. :8080)")
+	fs.Var(&cfg.origins, "allow-origin", "host of another origin allowed to open the dashboard WebSocket (e.g. admin.example.com:443); may be repeated")
+	dryRun := dryRunFlag(fs)
+	targetList := targetFlags(fs)
+	fs.StringVar(&cfg.history, "history", "", "file keeping a hash of each response body, to report URLs whose content changed since the last run")
+	fs.StringVar(&cfg.historyKeep, "history-keep", "", "history retention, counted per URL (the history has one entry per URL, not per run): drop URLs not checked within a period (e.g. 90d) and/or keep only the N most recently checked URLs (e.g. 500urls, or 90d,500urls)")
+	fs.BoolVar(&cfg.anomaly, "anomaly", false, "flag latency spikes that are statistically anomalous for each URL, compared with its recent measurements")
+	fs.IntVar(&cfg.anomalyWindow, "anomaly-window", 30, "number of recent measurements of each URL used as the baseline for -anomaly")
+	fs.Float64Var(&cfg.anomalyThreshold, "anomaly-threshold", anomaly.DefaultThreshold, "modified z-score above which a measurement is anomalous")
+	fs.BoolVar(&cfg.anomalyAlert, "anomaly-alert", false, "treat anomalies as breaches, opening incidents on the configured alerting services (implies -anomaly)")
+	pc := pacingFlags(fs)
+	probes := probeFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if cfg.anomalyWindow < 3 || cfg.anomalyThreshold <= 0 {
+		return errors.New("-anomaly-window must be at least 3 and -anomaly-threshold greater than zero")
+	}
+	retention, err := history.ParseRetention(cfg.historyKeep)
+	if err != nil {
+		return err
+	}
+	targets, err := targetList.jobs()
+	if err != nil {
+		return err
+	}
+	targets = pc.weigh(targets)
+	if *dryRun {
+		if _, err := probes.visit(cfg.timeout); err != nil {
+			return err
+		}
+		return printPlan(fs, targets, time.Duration(cfg.timeout)*time.Second,
+			fmt.Sprintf("Rounds of %d visit(s) every %s, breach above %s", len(pool.Schedule(targets)), cfg.interval, cfg.threshold))
+	}
+
+	// Com o histórico, as respostas são lidas por completo para que o hash do conteúdo seja comparado
+	var store *history.Store
+	if cfg.history != "" {
+		var err error
+		if store, err = history.Open(cfg.history); err != nil {
+			return err
+		}
+		probes.hashBody = true
+		// Aplica a retenção já na abertura, para que um histórico antigo encolha antes da primeira rodada
+		store.Prune(retention, time.Now())
+	}
+
+	// Monta a lista de serviços de incidentes que serão notificados
+	var alerters []alert.Alerter
+	if cfg.pagerDuty != "" {
+		alerters = append(alerters, alert.NewPagerDuty(cfg.pagerDuty))
+	}
+	if cfg.opsgenie != "" {
+		og := alert.NewOpsgenie(cfg.opsgenie)
+		og.URL = cfg.opsgenieURL
+		alerters = append(alerters, og)
+	}
+
+	// Os resultados de cada rodada são enviados para os sinks; as flags -mqtt-* continuam
+	// disponíveis como atalho para o sink mqtt
+	sinks, err := openSinks(cfg.sinks, cfg.labels)
+	if err != nil {
+		return err
+	}
+	if cfg.mqttBroker != "" {
+		mqttSink, err := sink.Open(fmt.Sprintf("mqtt:%s/%s?qos=%d", strings.TrimSuffix(cfg.mqttBroker, "/"), cfg.mqttTopic, cfg.mqttQoS))
+		if err != nil {
+			return err
+		}
+		sinks.Add(mqttSink)
+	}
+
+	p, err := probes.newPool(cfg.qtyWorkers, cfg.timeout)
+	if err != nil {
+		return err
+	}
+	pc.apply(p)
+	defer p.Close()
+
+	// Serve o dashboard em segundo plano, alimentado pelos resultados de cada rodada
+	var dashboard *server.Dashboard
+	if cfg.dashboard != "" {
+		dashboard = server.NewDashboard(p)
+		dashboard.AllowedOrigins = cfg.origins
+		go func() {
+			fmt.Printf("Serving dashboard on %s\n", cfg.dashboard)
+			if err := http.ListenAndServe(cfg.dashboard, dashboard.Handler()); err != nil {
+				fmt.Printf("Error at serving dashboard\nError: %s\n", err.Error())
+			}
+		}()
+	}
+
+	// Guarda quais URLs estão com incidente aberto, para que apenas as transições
+	// (normal -> violação e violação -> normal) gerem eventos
+	breached := make(map[string]bool)
+	var detector *anomaly.Detector
+	if cfg.anomaly || cfg.anomalyAlert {
+		detector = anomaly.New(cfg.anomalyWindow, cfg.anomalyThreshold)
+	}
+	// Os grupos com peso maior aparecem mais vezes em cada rodada, intercalados com os demais
+	jobs := pool.Schedule(targets)
+	tags := tagIndex(targets)
+
+	for n := 1; ; n++ {
+		// A lista remota é atualizada a cada rodada; uma falha mantém a lista anterior
+		if n > 1 {
+			updated, changed, err := targetList.refresh()
+			switch {
+			case err != nil:
+				fmt.Printf("Error at refreshing the URL list\nError: %s\n", err.Error())
+			case changed:
+				targets = pc.weigh(updated)
+				jobs, tags = pool.Schedule(targets), tagIndex(targets)
+				fmt.Printf("URL list updated: %d URL(s)\n", len(targets))
+				// Os incidentes das URLs que saíram da lista são encerrados
+				current := make(map[string]bool, len(targets))
+				for _, job := range targets {
+					current[job.URL] = true
+				}
+				if detector != nil {
+					detector.Keep(current)
+				}
+				for url := range breached {
+					if !current[url] {
+						delete(breached, url)
+						fmt.Printf("REMOVED %s - no longer in the URL list\n", url)
+						notifyResolve(alerters, url)
+					}
+				}
+			}
+		}
+		fmt.Printf("Monitor round started at %s\n", time.Now().Format(time.RFC3339))
+		changed := 0
+		round := make(tagRound)
+		for result := range p.Stream(jobs) {
+			round.add(tags[result.URL], result)
+			if store != nil && detectChange(store, result) {
+				changed++
+			}
+			if dashboard != nil {
+				dashboard.Record(result)
+				r := result
+				dashboard.Publish(server.Event{Type: server.EventResult, Result: &r})
+			}
+			reason := breachReason(result, cfg.threshold)
+			if spike := detectAnomaly(detector, &result); spike != "" && reason == "" && cfg.anomalyAlert {
+				reason = spike
+			}
+			writeResult(sinks, result)
+			switch {
+			case reason != "" && !breached[result.URL]:
+				breached[result.URL] = true
+				fmt.Printf("BREACH %s - %s\n", result.URL, reason)
+				notifyTrigger(alerters, result, reason)
+			case reason == "" && breached[result.URL]:
+				delete(breached, result.URL)
+				fmt.Printf("RECOVERED %s - Took: %s\n", result.URL, result.TimeTooked)
+				notifyResolve(alerters, result.URL)
+			}
+		}
+		flushSinks(sinks)
+		if store != nil {
+			if pruned := store.Prune(retention, time.Now()); pruned > 0 {
+				fmt.Printf("Pruned %d URL(s) from the history\n", pruned)
+			}
+			if err := store.Save(); err != nil {
+				fmt.Printf("Error at saving history\nError: %s\n", err.Error())
+			}
+			fmt.Printf("Monitor round finished: %d URL(s) in breach, %d URL(s) changed\n", len(breached), changed)
+		} else {
+			fmt.Printf("Monitor round finished: %d URL(s) in breach\n", len(breached))
+		}
+		round.print(tags, breached)
+		time.Sleep(cfg.interval)
+	}
+}
+
+// detectChange compara o hash do conteúdo do resultado com o histórico, avisando quando ele mudou.
+// O detalhe "changed" é adicionado ao resultado para que a mudança também chegue aos sinks
+func detectChange(store *history.Store, result pool.Result) bool {
+	hash, ok := result.Details["body_sha256"]
+	if !ok || result.Err != nil {
+		return false
+	}
+	previous, changed := store.Update(result.URL, hash, time.Now())
+	result.Details["changed"] = strconv.FormatBool(changed)
+	if changed {
+		fmt.Printf("CHANGED %s - content changed since the check at %s\n", result.URL, previous.Seen.Format(time.RFC3339))
+	}
+	return changed
+}
+
+// detectAnomaly avalia o resultado contra a linha de base da URL, avisando quando ele é um pico
+// anômalo. A pontuação vai para os detalhes do resultado, para que os picos também cheguem aos sinks
+func detectAnomaly(detector *anomaly.Detector, result *pool.Result) string {
+	if detector == nil || result.Err != nil {
+		return ""
+	}
+	spike, ok := detector.Observe(result.URL, result.TimeTooked)
+	if !ok {
+		return ""
+	}
+	reason := fmt.Sprintf("Took %s, anomalous for a baseline of %s (score %.1f)", result.TimeTooked, spike.Baseline, spike.Score)
+	if result.Details == nil {
+		result.Details = make(map[string]string)
+	}
+	result.Details["anomaly_score"] = strconv.FormatFloat(spike.Score, 'f', 1, 64)
+	fmt.Printf("ANOMALY %s - %s\n", result.URL, reason)
+	return reason
+}
+
+// breachReason devolve o motivo pelo qual o resultado viola o limite, ou "" caso esteja normal
+func breachReason(result pool.Result, threshold time.Duration) string {
+	if result.Err != nil {
+		return result.Err.Error()
+	}
+	if result.TimeTooked > threshold {
+		return fmt.Sprintf("took %s (threshold %s)", result.TimeTooked, threshold)
+	}
+	return ""
+}
+
+// dedupKey gera uma chave estável por URL, para que o serviço agrupe eventos repetidos em um único incidente
+func dedupKey(url string) string {
+	return "entendendo-worker-pool:" + url
+}
+
+func notifyTrigger(alerters []alert.Alerter, result pool.Result, reason string) {
+	summary := fmt.Sprintf("%s is in breach: %s", result.URL, reason)
+	details := map[string]string{
+		"url":    result.URL,
+		"reason": reason,
+	}
+	for _, a := range alerters {
+		if err := a.Trigger(dedupKey(result.URL), summary, details); err != nil {
+			fmt.Printf("Error at triggering incident for %s\nError: %s\n", result.URL, err.Error())
+		}
+	}
+}
+
+func notifyResolve(alerters []alert.Alerter, url string) {
+	for _, a := range alerters {
+		if err := a.Resolve(dedupKey(url)); err != nil {
+			fmt.Printf("Error at resolving incident for %s\nError: %s\n", url, err.Error())
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/report"
+)
+
+// formatFlag registra a flag -format, que escolhe o formato do relatório escrito na saída padrão
+func formatFlag(fs *flag.FlagSet) *string {
+	return fs.String("format", "", fmt.Sprintf("write the report of the run to stdout in this format, one of: %s; the progress output moves to stderr", strings.Join(report.FormatNames(), ", ")))
+}
+
+// reportOutput escreve o relatório de um comando na saída padrão, no formato escolhido por -format
+type reportOutput struct {
+	formatter report.Formatter
+	stdout    *os.File
+}
+
+// startOutput prepara a saída no formato informado. Sem formato, o comando mantém a saída de sempre e
+// nenhum relatório é escrito. Com um formato, a saída legível do comando (progresso, tabelas) passa
+// para o stderr, deixando o stdout apenas com o relatório, que pode ser redirecionado ou encadeado
+// com outros programas. O os.Stdout original volta a valer com restore, que o comando deve chamar
+// (com defer) ao terminar
+func startOutput(format string) (*reportOutput, error) {
+	if format == "" {
+		return nil, nil
+	}
+	formatter, err := report.LookupFormat(format)
+	if err != nil {
+		return nil, err
+	}
+	out := &reportOutput{formatter: formatter, stdout: os.Stdout}
+	os.Stdout = os.Stderr
+	return out, nil
+}
+
+// restore devolve ao os.Stdout a saída padrão original; não faz nada quando nenhum formato foi escolhido
+func (o *reportOutput) restore() {
+	if o == nil {
+		return
+	}
+	os.Stdout = o.stdout
+}
+
+// write escreve o relatório na saída padrão original; não faz nada quando nenhum formato foi escolhido
+func (o *reportOutput) write(rep *report.Report) error {
+	if o == nil {
+		return nil
+	}
+	return o.formatter.Format(o.stdout, rep)
+}
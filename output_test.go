@@ -0,0 +1,39 @@
+package main
+
+import (
+	"os"
+	"testing"
+)
+
+func TestStartOutputRestoresStdout(t *testing.T) {
+	original := os.Stdout
+	defer func() { os.Stdout = original }()
+
+	output, err := startOutput("json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if os.Stdout != os.Stderr {
+		t.Error("progress output was not moved to stderr")
+	}
+	output.restore()
+	if os.Stdout != original {
+		t.Error("restore did not bring back the original stdout")
+	}
+
+	// Sem formato, nada muda e restore pode ser chamado assim mesmo
+	output, err = startOutput("")
+	if err != nil || output != nil {
+		t.Fatalf("startOutput(\"\") = %v, %v", output, err)
+	}
+	output.restore()
+	if os.Stdout != original {
+		t.Error("restore without a format changed stdout")
+	}
+	if _, err := startOutput("yaml"); err == nil {
+		t.Error("unknown format accepted")
+	}
+	if os.Stdout != original {
+		t.Error("an invalid format changed stdout")
+	}
+}
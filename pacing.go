@@ -0,0 +1,80 @@
+package main
+
+import (
+	"flag"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/config"
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// pacing guarda a pausa dos workers entre jobs consecutivos, configurada pelas flags -think-time e
+// -jitter, e os limites de taxa por host do arquivo de configuração (flag -config)
+type pacing struct {
+	thinkTime time.Duration
+	jitter    time.Duration
+	config    configFile
+}
+
+// pacingFlags registra as flags -think-time, -jitter e -config
+func pacingFlags(fs *flag.FlagSet) *pacing {
+	pc := &pacing{}
+	fs.DurationVar(&pc.thinkTime, "think-time", 0, "delay of each worker between consecutive jobs")
+	fs.DurationVar(&pc.jitter, "jitter", 0, "random variation (plus or minus) applied to -think-time")
+	fs.Var(&pc.config, "config", "JSON configuration file with per-host rate limits (\"ratelimits\") and weighted URL groups (\"groups\")")
+	return pc
+}
+
+func (pc *pacing) apply(p *pool.Pool) {
+	p.SetThinkTime(pc.thinkTime, pc.jitter)
+	if pc.config.cfg != nil {
+		for host, rate := range pc.config.cfg.RateLimits {
+			p.SetRateLimit(host, float64(rate))
+		}
+	}
+}
+
+// reload lê novamente o arquivo de -config, se houver, para que o modo -watch use a versão atual.
+// Em caso de erro, a configuração anterior é mantida
+func (pc *pacing) reload() error {
+	if pc.config.path == "" {
+		return nil
+	}
+	return pc.config.Set(pc.config.path)
+}
+
+// weigh aplica aos jobs sem peso próprio o peso dos grupos do arquivo de configuração; a sequência
+// de despacho dos modos contínuos é montada a partir deles com pool.Schedule
+func (pc *pacing) weigh(jobs []pool.Job) []pool.Job {
+	if pc.config.cfg == nil || len(pc.config.cfg.Groups) == 0 {
+		return jobs
+	}
+	weighted := make([]pool.Job, len(jobs))
+	for i, job := range jobs {
+		if job.Weight == 0 {
+			job.Weight = pc.config.cfg.Weight(job.URL)
+		}
+		weighted[i] = job
+	}
+	return weighted
+}
+
+// configFile é a flag -config: o arquivo é lido e validado durante a interpretação das flags, para
+// que um erro na configuração seja apontado antes de qualquer medição
+type configFile struct {
+	path string
+	cfg  *config.Config
+}
+
+func (c *configFile) String() string {
+	return c.path
+}
+
+func (c *configFile) Set(path string) error {
+	cfg, err := config.Load(path)
+	if err != nil {
+		return err
+	}
+	c.path, c.cfg = path, cfg
+	return nil
+}
@@ -0,0 +1,421 @@
+package pool
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/clock"
+	"github.com/joaomarcelofa/entendendo-worker-pool/internal/failpoint"
+)
+
+// Job representa uma unidade de trabalho a ser executada por um worker
+type Job struct {
+	URL string
+	// Timeout, quando maior que zero, substitui o timeout padrão da medição para este job
+	Timeout time.Duration
+	// Weight é a frequência relativa de amostragem do job nos modos contínuos (ver Schedule); zero
+	// equivale a 1
+	Weight int
+	// Tags são os rótulos da entrada na lista de URLs (ex: "critical"), usados para filtrar a execução
+	// e agrupar as estatísticas
+	Tags []string
+}
+
+// Result é uma estrutura de dados que representa o resultado da visita de um worker a uma URL
+type Result struct {
+	URL        string
+	TimeTooked time.Duration
+	Err        error
+	// Timestamp é o momento em que o worker terminou o job
+	Timestamp time.Time
+	// QueueWait é o tempo que o job passou na fila, da submissão até ser pego por um worker; somado a
+	// TimeTooked, o tempo da medição em si, dá o tempo total percebido por quem submeteu o job. É a
+	// espera na fila que muda quando a quantidade de workers muda
+	QueueWait time.Duration
+	// Details guarda informações específicas do tipo de medição (ex: bytes baixados em um teste de download)
+	Details map[string]string
+	// Labels identifica a execução que produziu o resultado (ex: env=staging, build=1234), para que
+	// os dados guardados possam ser separados por ambiente ou versão
+	Labels map[string]string
+}
+
+// MarshalJSON representa o resultado com o tempo em milissegundos e o erro como texto
+func (r Result) MarshalJSON() ([]byte, error) {
+	out := struct {
+		URL          string            `json:"url"`
+		TimeTookedMs float64           `json:"time_tooked_ms"`
+		Error        string            `json:"error,omitempty"`
+		Timestamp    time.Time         `json:"timestamp"`
+		QueueWaitMs  float64           `json:"queue_wait_ms,omitempty"`
+		Details      map[string]string `json:"details,omitempty"`
+		Labels       map[string]string `json:"labels,omitempty"`
+	}{
+		URL:          r.URL,
+		TimeTookedMs: float64(r.TimeTooked) / float64(time.Millisecond),
+		Timestamp:    r.Timestamp,
+		QueueWaitMs:  float64(r.QueueWait) / float64(time.Millisecond),
+		Details:      r.Details,
+		Labels:       r.Labels,
+	}
+	if r.Err != nil {
+		out.Error = r.Err.Error()
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON reconstrói um resultado serializado por MarshalJSON (por exemplo, vindo de um agente remoto)
+func (r *Result) UnmarshalJSON(data []byte) error {
+	var in struct {
+		URL          string            `json:"url"`
+		TimeTookedMs float64           `json:"time_tooked_ms"`
+		Error        string            `json:"error"`
+		Timestamp    time.Time         `json:"timestamp"`
+		QueueWaitMs  float64           `json:"queue_wait_ms"`
+		Details      map[string]string `json:"details"`
+		Labels       map[string]string `json:"labels"`
+	}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*r = Result{
+		URL:        in.URL,
+		TimeTooked: time.Duration(in.TimeTookedMs * float64(time.Millisecond)),
+		Timestamp:  in.Timestamp,
+		QueueWait:  time.Duration(in.QueueWaitMs * float64(time.Millisecond)),
+		Details:    in.Details,
+		Labels:     in.Labels,
+	}
+	if in.Error != "" {
+		r.Err = errors.New(in.Error)
+	}
+	return nil
+}
+
+// ErrJobTimeout é o erro dos jobs que passaram do seu Job.Timeout antes de a medição começar
+var ErrJobTimeout = errors.New("job timeout exceeded")
+
+// VisitFunc é a função executada pelos workers para cada job recebido
+type VisitFunc func(job Job) Result
+
+// task associa um job ao canal por onde seu resultado deve ser devolvido; enqueued é o momento em
+// que o job foi submetido, para o cálculo de Result.QueueWait
+type task struct {
+	job      Job
+	reply    chan<- Result
+	enqueued time.Time
+}
+
+// Pool é um worker pool de vida longa: os workers ficam aguardando jobs até que o pool seja fechado
+type Pool struct {
+	// Contadores atualizados atomicamente, usados por Stats. Ficam no início da estrutura para
+	// garantir o alinhamento de 64 bits exigido pelo pacote atomic em arquiteturas de 32 bits
+	pending   int64
+	busy      int64
+	processed int64
+	// thinkTime e jitter definem a pausa de cada worker entre dois jobs consecutivos (ver SetThinkTime)
+	thinkTime int64
+	jitter    int64
+
+	queue      chan task
+	wg         sync.WaitGroup
+	qtyWorkers int
+	// clock marca o término dos jobs e conta as pausas entre eles (ver SetClock)
+	clock clock.Clock
+
+	// paused é um canal aberto enquanto o pool está pausado; ele é fechado ao retomar,
+	// liberando os workers que estavam aguardando
+	pauseMux sync.Mutex
+	paused   chan struct{}
+
+	// limits guarda os limites de taxa por host (ver SetRateLimit) e delayed acompanha os jobs que
+	// aguardam o seu horário para entrar na fila
+	limitMux sync.Mutex
+	limits   map[string]*hostLimit
+	delayed  sync.WaitGroup
+
+	hooks Hooks
+}
+
+// Hooks são funções chamadas nos eventos do ciclo de vida do pool, para que quem usa o pacote possa
+// ligar suas próprias métricas e logs sem alterar o loop dos workers. Qualquer campo pode ficar nil.
+// As funções são chamadas a partir das goroutines dos workers (e de quem chama Submit), então precisam
+// ser seguras para uso concorrente e rápidas, já que atrasam o worker enquanto executam. worker é o
+// índice do worker, de 0 a qtyWorkers-1
+type Hooks struct {
+	// OnWorkerStart e OnWorkerStop marcam o início da goroutine do worker e o seu término, quando a
+	// fila é fechada
+	OnWorkerStart func(worker int)
+	OnWorkerStop  func(worker int)
+	// OnWorkerIdle é chamado quando o worker encontra a fila vazia e passa a aguardar um job
+	OnWorkerIdle func(worker int)
+	// OnJobStart e OnJobDone marcam a execução de cada job pelo worker
+	OnJobStart func(worker int, job Job)
+	OnJobDone  func(worker int, result Result)
+	// OnQueueFull é chamado quando um job encontra a fila cheia e precisa aguardar um worker livre
+	// para entrar nela: é o momento em que a contrapressão chega a quem submete os jobs
+	OnQueueFull func(job Job)
+}
+
+// Stats é uma fotografia do estado do pool
+type Stats struct {
+	// Workers é a quantidade total de workers e Busy quantos estão executando um job neste momento
+	Workers int `json:"workers"`
+	Busy    int `json:"busy"`
+	// QueueDepth é a quantidade de jobs submetidos que ainda não foram pegos por nenhum worker
+	QueueDepth int `json:"queue_depth"`
+	// Processed é a quantidade de jobs concluídos desde a criação do pool
+	Processed int64 `json:"processed"`
+	Paused    bool  `json:"paused"`
+}
+
+// New cria um pool com qtyWorkers workers executando a função visit
+func New(qtyWorkers int, visit VisitFunc) *Pool {
+	return NewWithHooks(qtyWorkers, visit, Hooks{})
+}
+
+// NewWithHooks cria um pool como New, chamando as funções de hooks nos eventos dos workers e da
+// fila. Os hooks são recebidos na criação para que o início dos workers também seja observado
+func NewWithHooks(qtyWorkers int, visit VisitFunc, hooks Hooks) *Pool {
+	if qtyWorkers < 1 {
+		qtyWorkers = 1
+	}
+	p := &Pool{
+		queue:      make(chan task, qtyWorkers),
+		qtyWorkers: qtyWorkers,
+		clock:      clock.Real,
+		hooks:      hooks,
+	}
+	p.wg.Add(qtyWorkers)
+	for i := 0; i < qtyWorkers; i++ {
+		// Criando uma goroutine para cada worker
+		go p.worker(i, visit)
+	}
+	return p
+}
+
+func (p *Pool) worker(id int, visit VisitFunc) {
+	defer p.wg.Done()
+	if p.hooks.OnWorkerStart != nil {
+		p.hooks.OnWorkerStart(id)
+	}
+	if p.hooks.OnWorkerStop != nil {
+		defer p.hooks.OnWorkerStop(id)
+	}
+	// Cada worker consome a fila até que ela seja fechada
+	for {
+		// O failpoint permite simular workers travados, enchendo a fila para exercitar a contrapressão
+		failpoint.Eval("pool/queue-stall")
+		t, ok := p.next(id)
+		if !ok {
+			return
+		}
+		// Enquanto o pool estiver pausado, o job fica retido sem ser executado; esse tempo também
+		// conta como espera na fila
+		p.waitIfPaused()
+		picked := p.clock.Now()
+		atomic.AddInt64(&p.pending, -1)
+		atomic.AddInt64(&p.busy, 1)
+		if p.hooks.OnJobStart != nil {
+			p.hooks.OnJobStart(id, t.job)
+		}
+		result := run(visit, t.job)
+		if result.Timestamp.IsZero() {
+			result.Timestamp = p.clock.Now()
+		}
+		result.QueueWait = picked.Sub(t.enqueued)
+		atomic.AddInt64(&p.busy, -1)
+		atomic.AddInt64(&p.processed, 1)
+		if p.hooks.OnJobDone != nil {
+			p.hooks.OnJobDone(id, result)
+		}
+		t.reply <- result
+		p.think()
+	}
+}
+
+// next pega o próximo job da fila, avisando OnWorkerIdle quando o worker precisa aguardar por ele
+func (p *Pool) next(id int) (task, bool) {
+	if p.hooks.OnWorkerIdle != nil {
+		select {
+		case t, ok := <-p.queue:
+			return t, ok
+		default:
+			p.hooks.OnWorkerIdle(id)
+		}
+	}
+	t, ok := <-p.queue
+	return t, ok
+}
+
+// enqueue coloca a tarefa na fila, avisando OnQueueFull quando ela precisa aguardar espaço
+func (p *Pool) enqueue(t task) {
+	if p.hooks.OnQueueFull != nil {
+		select {
+		case p.queue <- t:
+			return
+		default:
+			p.hooks.OnQueueFull(t.job)
+		}
+	}
+	p.queue <- t
+}
+
+// run executa o job, convertendo um panic da função de visita em um resultado com erro para que um
+// job problemático não derrube o worker nem o programa
+func run(visit VisitFunc, job Job) (result Result) {
+	defer func() {
+		if r := recover(); r != nil {
+			result = Result{URL: job.URL, Err: fmt.Errorf("worker panic: %v", r)}
+		}
+	}()
+	// Um job atrasado pelo failpoint além do seu próprio timeout termina como terminaria um job
+	// realmente lento: com o erro de timeout, sem chegar a executar a medição
+	start := time.Now()
+	failpoint.Eval("pool/slow-job")
+	if elapsed := time.Since(start); job.Timeout > 0 && elapsed >= job.Timeout {
+		return Result{URL: job.URL, TimeTooked: elapsed, Err: fmt.Errorf("%w (%s)", ErrJobTimeout, job.Timeout)}
+	}
+	failpoint.Eval("pool/worker-panic")
+	return visit(job)
+}
+
+// SetThinkTime faz cada worker aguardar delay, com uma variação aleatória de até jitter para mais ou
+// para menos, entre dois jobs consecutivos. Isso simula um ritmo humano em vez de disparar as
+// requisições uma atrás da outra. Com os dois valores zerados, os workers não fazem pausa
+func (p *Pool) SetThinkTime(delay, jitter time.Duration) {
+	atomic.StoreInt64(&p.thinkTime, int64(delay))
+	atomic.StoreInt64(&p.jitter, int64(jitter))
+}
+
+func (p *Pool) think() {
+	delay := time.Duration(atomic.LoadInt64(&p.thinkTime))
+	if jitter := atomic.LoadInt64(&p.jitter); jitter > 0 {
+		delay += time.Duration(rand.Int64N(2*jitter+1) - jitter)
+	}
+	clock.Sleep(p.clock, delay)
+}
+
+// SetClock troca o relógio usado para marcar o término dos jobs e nas pausas entre eles, permitindo
+// usar um relógio falso nos testes. Deve ser chamado antes de submeter os jobs
+func (p *Pool) SetClock(c clock.Clock) {
+	p.clock = c
+}
+
+// Submit coloca um job na fila; o resultado será enviado para reply
+func (p *Pool) Submit(job Job, reply chan<- Result) {
+	// O job é contabilizado como pendente mesmo enquanto aguarda espaço na fila
+	atomic.AddInt64(&p.pending, 1)
+	t := task{job: job, reply: reply, enqueued: p.clock.Now()}
+	// Um job de um host com limite de taxa que ainda não pode começar entra na fila só no seu horário
+	if delay := p.reserve(job); delay > 0 {
+		p.delayed.Add(1)
+		timer := p.clock.Timer(delay)
+		go func() {
+			defer p.delayed.Done()
+			<-timer.C()
+			// A espera pelo horário do limite de taxa é intencional e não conta como espera na fila
+			t.enqueued = p.clock.Now()
+			p.enqueue(t)
+		}()
+		return
+	}
+	p.enqueue(t)
+}
+
+// Stats devolve a utilização atual dos workers e a profundidade da fila
+func (p *Pool) Stats() Stats {
+	return Stats{
+		Workers:    p.qtyWorkers,
+		Busy:       int(atomic.LoadInt64(&p.busy)),
+		QueueDepth: int(atomic.LoadInt64(&p.pending)),
+		Processed:  atomic.LoadInt64(&p.processed),
+		Paused:     p.Paused(),
+	}
+}
+
+// Pause impede que os workers iniciem novos jobs; os jobs em andamento são concluídos normalmente
+func (p *Pool) Pause() {
+	p.pauseMux.Lock()
+	defer p.pauseMux.Unlock()
+	if p.paused == nil {
+		p.paused = make(chan struct{})
+	}
+}
+
+// Resume libera os workers para continuar consumindo a fila
+func (p *Pool) Resume() {
+	p.pauseMux.Lock()
+	defer p.pauseMux.Unlock()
+	if p.paused != nil {
+		close(p.paused)
+		p.paused = nil
+	}
+}
+
+// Paused informa se o pool está pausado
+func (p *Pool) Paused() bool {
+	p.pauseMux.Lock()
+	defer p.pauseMux.Unlock()
+	return p.paused != nil
+}
+
+func (p *Pool) waitIfPaused() {
+	p.pauseMux.Lock()
+	paused := p.paused
+	p.pauseMux.Unlock()
+	if paused != nil {
+		<-paused
+	}
+}
+
+// Stream distribui os jobs para os workers e devolve um canal com os resultados à medida que eles
+// ficam prontos. O canal é fechado quando todos os jobs tiverem sido processados
+func (p *Pool) Stream(jobs []Job) <-chan Result {
+	reply := make(chan Result)
+	out := make(chan Result)
+	go func() {
+		for _, job := range jobs {
+			p.Submit(job, reply)
+		}
+	}()
+	go func() {
+		for range jobs {
+			out <- <-reply
+		}
+		close(out)
+	}()
+	return out
+}
+
+// Collect executa todos os jobs e devolve os resultados quando todos terminarem
+func (p *Pool) Collect(jobs []Job) []Result {
+	results := make([]Result, 0, len(jobs))
+	for r := range p.Stream(jobs) {
+		results = append(results, r)
+	}
+	return results
+}
+
+// Close encerra a fila e aguarda os workers terminarem os jobs em andamento
+func (p *Pool) Close() {
+	// Um pool pausado é retomado para que os workers possam terminar
+	p.Resume()
+	// Os jobs que aguardam um limite de taxa ainda precisam entrar na fila antes de ela ser fechada
+	p.delayed.Wait()
+	close(p.queue)
+	p.wg.Wait()
+}
+
+// JobsFromURLs converte uma lista de URLs em jobs
+func JobsFromURLs(urls []string) []Job {
+	jobs := make([]Job, len(urls))
+	for i, url := range urls {
+		jobs[i] = Job{URL: url}
+	}
+	return jobs
+}
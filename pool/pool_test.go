@@ -0,0 +1,261 @@
+package pool_test
+
+import (
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/clock"
+	"github.com/joaomarcelofa/entendendo-worker-pool/internal/failpoint"
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// enable ativa o failpoint durante o teste, desativando-o ao final
+func enable(t *testing.T, name, spec string) {
+	t.Helper()
+	if err := failpoint.Enable(name, spec); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { failpoint.Disable(name) })
+}
+
+func ok(job pool.Job) pool.Result {
+	return pool.Result{URL: job.URL, TimeTooked: time.Millisecond}
+}
+
+func TestWorkerPanicBecomesResultError(t *testing.T) {
+	enable(t, "pool/worker-panic", "1*panic(boom)")
+	p := pool.New(1, ok)
+	defer p.Close()
+
+	// Com um único worker, o segundo job só é concluído se o worker sobreviver ao panic do primeiro
+	results := p.Collect(pool.JobsFromURLs([]string{"http://a", "http://b"}))
+	if len(results) != 2 {
+		t.Fatalf("got %d results, want 2", len(results))
+	}
+	if results[0].Err == nil || !strings.Contains(results[0].Err.Error(), "worker panic: boom") {
+		t.Errorf("first result error = %v, want the recovered panic", results[0].Err)
+	}
+	if results[0].URL != "http://a" {
+		t.Errorf("first result URL = %q, want http://a", results[0].URL)
+	}
+	if results[1].Err != nil {
+		t.Errorf("second result error = %v, want nil", results[1].Err)
+	}
+	if stats := p.Stats(); stats.Busy != 0 || stats.Processed != 2 {
+		t.Errorf("stats = %+v, want 2 processed and no busy worker", stats)
+	}
+}
+
+func TestSlowJobHitsItsTimeout(t *testing.T) {
+	enable(t, "pool/slow-job", "sleep(50ms)")
+	p := pool.New(2, ok)
+	defer p.Close()
+
+	results := p.Collect([]pool.Job{
+		{URL: "http://short", Timeout: 10 * time.Millisecond},
+		{URL: "http://long", Timeout: time.Second},
+	})
+	for _, r := range results {
+		switch r.URL {
+		case "http://short":
+			if !errors.Is(r.Err, pool.ErrJobTimeout) {
+				t.Errorf("short job error = %v, want ErrJobTimeout", r.Err)
+			}
+			if r.TimeTooked < 10*time.Millisecond {
+				t.Errorf("short job took %s, want at least its timeout", r.TimeTooked)
+			}
+		case "http://long":
+			if r.Err != nil {
+				t.Errorf("long job error = %v, want nil", r.Err)
+			}
+		}
+	}
+}
+
+func TestQueueStallFiresBackpressure(t *testing.T) {
+	enable(t, "pool/queue-stall", "block")
+	full := make(chan pool.Job, 1)
+	p := pool.NewWithHooks(1, ok, pool.Hooks{
+		OnQueueFull: func(job pool.Job) {
+			select {
+			case full <- job:
+			default:
+			}
+		},
+	})
+	defer p.Close()
+
+	// A fila tem espaço para um job por worker; com o worker parado, o segundo não cabe
+	reply := make(chan pool.Result, 2)
+	submitted := make(chan struct{})
+	go func() {
+		p.Submit(pool.Job{URL: "http://a"}, reply)
+		p.Submit(pool.Job{URL: "http://b"}, reply)
+		close(submitted)
+	}()
+
+	select {
+	case job := <-full:
+		if job.URL != "http://b" {
+			t.Errorf("OnQueueFull got %q, want http://b", job.URL)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("OnQueueFull was not called")
+	}
+	select {
+	case <-submitted:
+		t.Fatal("Submit returned while the queue was full")
+	default:
+	}
+	if depth := p.Stats().QueueDepth; depth != 2 {
+		t.Errorf("queue depth = %d, want 2", depth)
+	}
+
+	// Liberando os workers, a fila volta a andar e os dois jobs são concluídos
+	failpoint.Disable("pool/queue-stall")
+	<-submitted
+	for i := 0; i < 2; i++ {
+		select {
+		case <-reply:
+		case <-time.After(5 * time.Second):
+			t.Fatal("jobs were not processed after the stall")
+		}
+	}
+}
+
+// waitFor aguarda a condição, que depende de goroutines do pool, por no máximo alguns segundos
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(50 * time.Microsecond)
+	}
+}
+
+func TestThinkTimeWaitsOnTheClock(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	var started atomic.Int32
+	p := pool.New(1, func(job pool.Job) pool.Result {
+		started.Add(1)
+		return pool.Result{URL: job.URL}
+	})
+	p.SetClock(fake)
+	p.SetThinkTime(100*time.Millisecond, 0)
+
+	reply := make(chan pool.Result, 2)
+	p.Submit(pool.Job{URL: "http://a"}, reply)
+	p.Submit(pool.Job{URL: "http://b"}, reply)
+
+	first := <-reply
+	if !first.Timestamp.Equal(start) {
+		t.Errorf("first job finished at %s, want %s", first.Timestamp, start)
+	}
+	// O worker pausa no relógio falso antes do segundo job, que só começa quando a pausa termina
+	waitFor(t, "the think time", func() bool { return fake.Waiting() == 1 })
+	fake.Advance(99 * time.Millisecond)
+	if n := started.Load(); n != 1 {
+		t.Fatalf("%d jobs started before the end of the think time, want 1", n)
+	}
+	fake.Advance(time.Millisecond)
+	second := <-reply
+	if want := start.Add(100 * time.Millisecond); !second.Timestamp.Equal(want) {
+		t.Errorf("second job finished at %s, want %s", second.Timestamp, want)
+	}
+	// O segundo job mostra o tempo que ele aguardou na fila durante a pausa
+	if second.QueueWait != 100*time.Millisecond {
+		t.Errorf("second job queue wait = %s, want 100ms", second.QueueWait)
+	}
+
+	// Libera a pausa após o último job para que Close não fique esperando o worker
+	waitFor(t, "the last think time", func() bool { return fake.Waiting() == 1 })
+	fake.Advance(100 * time.Millisecond)
+	p.Close()
+}
+
+func TestRateLimitSpacesJobsOfTheHost(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	fake := clock.NewFake(start)
+	p := pool.New(2, ok)
+	p.SetClock(fake)
+	p.SetRateLimit("Slow.Test", 10)
+
+	reply := make(chan pool.Result, 10)
+	for _, u := range []string{"http://slow.test/1", "http://fast.test/1", "https://slow.test:8443/2", "http://fast.test/2", "http://slow.test/3"} {
+		p.Submit(pool.Job{URL: u}, reply)
+	}
+
+	// O primeiro job do host limitado e os hosts sem limite não esperam
+	finished := make(map[string]time.Time)
+	for i := 0; i < 3; i++ {
+		r := <-reply
+		finished[r.URL] = r.Timestamp
+	}
+	for _, u := range []string{"http://slow.test/1", "http://fast.test/1", "http://fast.test/2"} {
+		if at, done := finished[u]; !done || !at.Equal(start) {
+			t.Errorf("%s finished at %v (done %v), want right away", u, at, done)
+		}
+	}
+
+	// Os demais jobs do host (com qualquer porta) saem a cada 100ms, na ordem em que foram submetidos
+	waitFor(t, "the delayed jobs", func() bool { return fake.Waiting() == 2 })
+	for i, u := range []string{"https://slow.test:8443/2", "http://slow.test/3"} {
+		fake.Advance(99 * time.Millisecond)
+		select {
+		case r := <-reply:
+			t.Fatalf("%s ran before its slot", r.URL)
+		case <-time.After(10 * time.Millisecond):
+		}
+		fake.Advance(time.Millisecond)
+		r := <-reply
+		if want := start.Add(time.Duration(i+1) * 100 * time.Millisecond); r.URL != u || !r.Timestamp.Equal(want) {
+			t.Errorf("got %s at %s, want %s at %s", r.URL, r.Timestamp, u, want)
+		}
+		// A espera pelo limite não conta como espera na fila
+		if r.QueueWait != 0 {
+			t.Errorf("%s queue wait = %s, want 0", r.URL, r.QueueWait)
+		}
+	}
+
+	// Close aguarda os jobs que ainda esperam o seu horário
+	fake.Advance(time.Second)
+	p.Submit(pool.Job{URL: "http://slow.test/4"}, reply)
+	p.Submit(pool.Job{URL: "http://slow.test/5"}, reply)
+	<-reply
+	closed := make(chan struct{})
+	go func() {
+		p.Close()
+		close(closed)
+	}()
+	waitFor(t, "the last delayed job", func() bool { return fake.Waiting() == 1 })
+	select {
+	case <-closed:
+		t.Fatal("Close returned before the delayed job ran")
+	case <-time.After(10 * time.Millisecond):
+	}
+	fake.Advance(100 * time.Millisecond)
+	<-closed
+	if r := <-reply; r.URL != "http://slow.test/5" {
+		t.Errorf("last result = %s, want the delayed job", r.URL)
+	}
+}
+
+func TestRateLimitRemoved(t *testing.T) {
+	fake := clock.NewFake(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC))
+	p := pool.New(1, ok)
+	p.SetClock(fake)
+	defer p.Close()
+	p.SetRateLimit("slow.test", 1)
+	p.SetRateLimit("slow.test", 0)
+
+	results := p.Collect(pool.JobsFromURLs([]string{"http://slow.test/1", "http://slow.test/2", "http://slow.test/3"}))
+	if len(results) != 3 || fake.Waiting() != 0 {
+		t.Errorf("got %d results with %d pending timers, want 3 without waiting", len(results), fake.Waiting())
+	}
+}
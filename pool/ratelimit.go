@@ -0,0 +1,60 @@
+package pool
+
+import (
+	"net/url"
+	"strings"
+	"time"
+)
+
+// hostLimit espaça os jobs de um host: next é o momento a partir do qual o próximo job pode começar
+type hostLimit struct {
+	interval time.Duration
+	next     time.Time
+}
+
+// SetRateLimit limita os jobs do host informado (sem a porta, ex: "example.com") a rps jobs por
+// segundo; com rps zerado, o limite é removido. O limite é aplicado no despacho: um job que ainda não
+// pode começar aguarda fora da fila, sem ocupar um worker, enquanto os jobs dos outros hosts seguem
+// normalmente. Os jobs de um host limitado são liberados na ordem em que foram submetidos
+func (p *Pool) SetRateLimit(host string, rps float64) {
+	p.limitMux.Lock()
+	defer p.limitMux.Unlock()
+	host = strings.ToLower(host)
+	if rps <= 0 {
+		delete(p.limits, host)
+		return
+	}
+	if p.limits == nil {
+		p.limits = make(map[string]*hostLimit)
+	}
+	p.limits[host] = &hostLimit{interval: time.Duration(float64(time.Second) / rps)}
+}
+
+// reserve reserva o próximo horário livre do host do job e devolve quanto tempo falta até ele
+func (p *Pool) reserve(job Job) time.Duration {
+	p.limitMux.Lock()
+	defer p.limitMux.Unlock()
+	if len(p.limits) == 0 {
+		return 0
+	}
+	limit := p.limits[jobHost(job.URL)]
+	if limit == nil {
+		return 0
+	}
+	now := p.clock.Now()
+	if limit.next.Before(now) {
+		limit.next = now
+	}
+	delay := limit.next.Sub(now)
+	limit.next = limit.next.Add(limit.interval)
+	return delay
+}
+
+// jobHost é o host usado para encontrar o limite de um job; entradas que não são URLs (ex: um nome
+// no modo dns) são usadas como estão
+func jobHost(raw string) string {
+	if u, err := url.Parse(raw); err == nil && u.Hostname() != "" {
+		return strings.ToLower(u.Hostname())
+	}
+	return strings.ToLower(raw)
+}
@@ -0,0 +1,40 @@
+package pool
+
+// Schedule monta a sequência de despacho de uma rodada: cada job aparece Weight vezes (uma vez quando
+// o peso não foi informado) e as repetições são intercaladas de forma proporcional, em vez de ficarem
+// agrupadas. Assim, um endpoint crítico com peso 3 é amostrado três vezes mais que os demais, em
+// intervalos regulares ao longo da rodada. A intercalação segue o round-robin ponderado suave: a cada
+// passo, é escolhido o job com o maior crédito acumulado
+func Schedule(jobs []Job) []Job {
+	total := 0
+	weighted := false
+	for _, job := range jobs {
+		total += job.weight()
+		weighted = weighted || job.weight() > 1
+	}
+	if !weighted {
+		return jobs
+	}
+
+	credits := make([]int, len(jobs))
+	sequence := make([]Job, 0, total)
+	for len(sequence) < total {
+		best := 0
+		for i, job := range jobs {
+			credits[i] += job.weight()
+			if credits[i] > credits[best] {
+				best = i
+			}
+		}
+		credits[best] -= total
+		sequence = append(sequence, jobs[best])
+	}
+	return sequence
+}
+
+func (j Job) weight() int {
+	if j.Weight < 1 {
+		return 1
+	}
+	return j.Weight
+}
@@ -0,0 +1,49 @@
+package probe
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Situação da resposta no cache de uma CDN, registrada no detalhe "cache" dos resultados HTTP
+const (
+	CacheHit     = "HIT"
+	CacheMiss    = "MISS"
+	CacheUnknown = "UNKNOWN"
+)
+
+// CacheStatus identifica se a resposta veio do cache de uma CDN a partir dos cabeçalhos mais comuns,
+// nesta ordem: CF-Cache-Status (Cloudflare), X-Cache (CloudFront, Fastly, Varnish, Squid) e Age.
+// Quando nenhum deles está presente, a situação é CacheUnknown
+func CacheStatus(h http.Header) string {
+	if status := h.Get("CF-Cache-Status"); status != "" {
+		switch strings.ToUpper(status) {
+		case "HIT", "STALE", "REVALIDATED", "UPDATING":
+			return CacheHit
+		default:
+			// MISS, EXPIRED, BYPASS e DYNAMIC vão até a origem
+			return CacheMiss
+		}
+	}
+	if values := h.Values("X-Cache"); len(values) > 0 {
+		// Com várias camadas de cache (ex: "MISS, HIT" no Fastly), basta um acerto para que a
+		// requisição não tenha chegado à origem
+		xcache := strings.ToUpper(strings.Join(values, ","))
+		switch {
+		case strings.Contains(xcache, "HIT"):
+			return CacheHit
+		case strings.Contains(xcache, "MISS"):
+			return CacheMiss
+		}
+	}
+	if age := h.Get("Age"); age != "" {
+		if seconds, err := strconv.Atoi(strings.TrimSpace(age)); err == nil {
+			if seconds > 0 {
+				return CacheHit
+			}
+			return CacheMiss
+		}
+	}
+	return CacheUnknown
+}
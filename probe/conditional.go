@@ -0,0 +1,91 @@
+package probe
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// Conditional valida o suporte a requisições condicionais: cada URL é buscada uma vez e, em seguida,
+// requisitada novamente com If-None-Match (ETag) e If-Modified-Since (Last-Modified). O servidor deve
+// responder 304; caso contrário, ou se ele não enviar nenhum validador, o resultado é uma falha.
+// O tempo do resultado é o da requisição validada e os detalhes trazem o tempo da primeira e quanto
+// mais rápida foi a resposta validada
+func Conditional(client Doer) pool.VisitFunc {
+	return func(job pool.Job) pool.Result {
+		result := pool.Result{URL: job.URL}
+		client := Client(client, job)
+		first, resp, err := timedGet(client, job.URL, nil)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		if resp.StatusCode != 200 {
+			result.Err = statusError(resp.StatusCode)
+			return result
+		}
+
+		etag := resp.Header.Get("ETag")
+		lastModified := resp.Header.Get("Last-Modified")
+		result.Details = map[string]string{"first_ms": formatMs(first)}
+		if etag != "" {
+			result.Details["etag"] = etag
+		}
+		if lastModified != "" {
+			result.Details["last_modified"] = lastModified
+		}
+		if etag == "" && lastModified == "" {
+			result.Err = errors.New("no ETag or Last-Modified in the response")
+			return result
+		}
+
+		headers := http.Header{}
+		if etag != "" {
+			headers.Set("If-None-Match", etag)
+		}
+		if lastModified != "" {
+			headers.Set("If-Modified-Since", lastModified)
+		}
+		validated, resp, err := timedGet(client, job.URL, headers)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		result.Details["status"] = strconv.Itoa(resp.StatusCode)
+		if resp.StatusCode != http.StatusNotModified {
+			result.Err = fmt.Errorf("conditional request returned %d instead of 304", resp.StatusCode)
+			return result
+		}
+		result.TimeTooked = validated
+		if validated > 0 {
+			result.Details["speedup"] = strconv.FormatFloat(float64(first)/float64(validated), 'f', 2, 64) + "x"
+		}
+		return result
+	}
+}
+
+// timedGet faz um GET com os cabeçalhos informados e mede o tempo até o corpo ser lido por completo
+func timedGet(client Doer, url string, headers http.Header) (time.Duration, *http.Response, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return 0, nil, err
+	}
+	for k, v := range headers {
+		req.Header[k] = v
+	}
+	start := time.Now()
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	if _, err := io.Copy(io.Discard, resp.Body); err != nil {
+		return 0, nil, err
+	}
+	return time.Since(start), resp, nil
+}
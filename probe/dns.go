@@ -0,0 +1,65 @@
+package probe
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// DNS mede o tempo de resolução do nome de cada alvo. Os alvos são nomes de host ou URLs, das quais
+// é usado apenas o host. Com server vazio é usado o resolvedor do sistema; caso contrário, as
+// consultas são enviadas diretamente para o servidor informado (ex: 1.1.1.1:53)
+func DNS(server string, timeout time.Duration) pool.VisitFunc {
+	resolver := net.DefaultResolver
+	if server != "" {
+		if _, _, err := net.SplitHostPort(server); err != nil {
+			server = net.JoinHostPort(server, "53")
+		}
+		resolver = &net.Resolver{
+			PreferGo: true,
+			Dial: func(ctx context.Context, network, _ string) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, server)
+			},
+		}
+	}
+
+	return func(job pool.Job) pool.Result {
+		result := pool.Result{URL: job.URL}
+		host := hostOf(job.URL)
+
+		ctx, cancel := context.WithTimeout(context.Background(), jobTimeout(timeout, job))
+		defer cancel()
+		start := time.Now()
+		addrs, err := resolver.LookupHost(ctx, host)
+		elapsed := time.Since(start)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+
+		result.TimeTooked = elapsed
+		result.Details = map[string]string{"addresses": strings.Join(addrs, " ")}
+		if server != "" {
+			result.Details["resolver"] = server
+		}
+		return result
+	}
+}
+
+// hostOf extrai o nome do host de um alvo informado como URL, host:porta ou apenas o nome
+func hostOf(target string) string {
+	if strings.Contains(target, "://") {
+		if u, err := url.Parse(target); err == nil {
+			return u.Hostname()
+		}
+	}
+	if host, _, err := net.SplitHostPort(target); err == nil {
+		return host
+	}
+	return target
+}
@@ -0,0 +1,79 @@
+package probe
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// Download mede o download completo do corpo da resposta, otimizado para arquivos grandes: o corpo
+// é lido em streaming, sem ser guardado em memória. O tempo do resultado é o tempo total da
+// transferência e os detalhes trazem o tempo até o primeiro byte, os bytes baixados e a velocidade
+// em MB/s, além da situação no cache da CDN. Com maxBytes maior que zero, o download é interrompido
+// após essa quantidade de bytes
+func Download(client Doer, maxBytes int64) pool.VisitFunc {
+	return func(job pool.Job) pool.Result {
+		result := pool.Result{URL: job.URL}
+		req, err := http.NewRequest("GET", job.URL, nil)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		start := time.Now()
+		resp, err := Client(client, job).Do(req)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != 200 {
+			result.Err = statusError(resp.StatusCode)
+			return result
+		}
+
+		// O primeiro byte é lido separadamente para medir o tempo até o início da transferência
+		var body io.Reader = resp.Body
+		if maxBytes > 0 {
+			body = io.LimitReader(resp.Body, maxBytes)
+		}
+		first := make([]byte, 1)
+		n, err := io.ReadFull(body, first)
+		ttfb := time.Since(start)
+		if err != nil && err != io.EOF {
+			result.Err = err
+			return result
+		}
+		rest, err := io.Copy(io.Discard, body)
+		elapsed := time.Since(start)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+
+		downloaded := int64(n) + rest
+		mbps := 0.0
+		if elapsed > 0 {
+			mbps = float64(downloaded) / (1 << 20) / elapsed.Seconds()
+		}
+		// Ao atingir o limite, um byte a mais é lido para saber se o arquivo realmente foi cortado
+		truncated := false
+		if maxBytes > 0 && downloaded == maxBytes {
+			extra, _ := resp.Body.Read(first)
+			truncated = extra > 0
+		}
+		result.TimeTooked = elapsed
+		result.Details = map[string]string{
+			"ttfb_ms":   formatMs(ttfb),
+			"bytes":     strconv.FormatInt(downloaded, 10),
+			"mb_per_s":  strconv.FormatFloat(mbps, 'f', 2, 64),
+			"truncated": strconv.FormatBool(truncated),
+			"cache":     CacheStatus(resp.Header),
+		}
+		// No download, o que sobra do tempo do servidor até o primeiro byte é a rede
+		AddServerTiming(result.Details, resp.Header, ttfb)
+		return result
+	}
+}
@@ -0,0 +1,85 @@
+package probe
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/grpcwire"
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// grpcHealthCheck é o método da API padrão de health check do gRPC (grpc.health.v1)
+const grpcHealthCheck = "/grpc.health.v1.Health/Check"
+
+// Valores de HealthCheckResponse.ServingStatus
+var grpcServingStatus = map[uint64]string{
+	0: "UNKNOWN",
+	1: "SERVING",
+	2: "NOT_SERVING",
+	3: "SERVICE_UNKNOWN",
+}
+
+// GRPCHealth chama a API padrão de health check do gRPC e mede o tempo de resposta, integrando
+// backends gRPC aos mesmos relatórios. Os alvos são grpc://host:porta/serviço (HTTP/2 em texto puro)
+// ou grpcs://host:porta/serviço (TLS); sem serviço, é consultada a saúde geral do servidor.
+// Qualquer status diferente de SERVING é considerado uma falha
+func GRPCHealth(opts Options) pool.VisitFunc {
+	var h2c http.Protocols
+	h2c.SetUnencryptedHTTP2(true)
+	plainTransport := opts.Transport()
+	plainTransport.Protocols = &h2c
+	plain := &http.Client{Timeout: opts.Timeout, Transport: plainTransport}
+	var h2 http.Protocols
+	h2.SetHTTP2(true)
+	secureTransport := opts.Transport()
+	secureTransport.Protocols = &h2
+	secure := &http.Client{Timeout: opts.Timeout, Transport: secureTransport}
+
+	return func(job pool.Job) pool.Result {
+		result := pool.Result{URL: job.URL}
+		u, err := url.Parse(job.URL)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		client, scheme := plain, "http"
+		if u.Scheme == "grpcs" {
+			client, scheme = secure, "https"
+		}
+		service := strings.TrimPrefix(u.Path, "/")
+
+		var req grpcwire.Encoder
+		req.String(1, service)
+		start := time.Now()
+		resp, err := grpcwire.Invoke(clientFor(client, job), scheme+"://"+u.Host, grpcHealthCheck, req.Bytes())
+		elapsed := time.Since(start)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+
+		var status uint64
+		grpcwire.Decode(resp, func(f grpcwire.Field) {
+			if f.Number == 1 {
+				status = f.Varint
+			}
+		})
+		name, ok := grpcServingStatus[status]
+		if !ok {
+			name = fmt.Sprint(status)
+		}
+		if name != "SERVING" {
+			result.Err = fmt.Errorf("health status %s", name)
+			return result
+		}
+		result.TimeTooked = elapsed
+		result.Details = map[string]string{"status": name}
+		if service != "" {
+			result.Details["service"] = service
+		}
+		return result
+	}
+}
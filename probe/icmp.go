@@ -0,0 +1,144 @@
+package probe
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"net"
+	"os"
+	"sync/atomic"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// icmpSeq numera as mensagens de eco enviadas pelo processo, para que cada worker reconheça a sua resposta
+var icmpSeq uint32
+
+const (
+	icmpEchoRequest = 8
+	icmpEchoReply   = 0
+)
+
+// ICMP envia um eco ICMP (ping) para cada alvo e mede o tempo até a resposta, o que permite comparar o
+// RTT da rede com a latência HTTP dos mesmos hosts. Os alvos são nomes de host, host:porta ou URLs,
+// das quais é usado apenas o host; somente IPv4 é suportado.
+// O socket ICMP bruto exige privilégios; sem eles, é usado o socket ICMP não privilegiado (datagrama),
+// disponível no Linux quando o grupo do processo está em net.ipv4.ping_group_range e no macOS
+func ICMP(timeout time.Duration) pool.VisitFunc {
+	return func(job pool.Job) pool.Result {
+		result := pool.Result{URL: job.URL}
+		timeout := jobTimeout(timeout, job)
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		ips, err := net.DefaultResolver.LookupIP(ctx, "ip4", hostOf(job.URL))
+		cancel()
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		ip := ips[0]
+
+		conn, dst, socket, err := listenICMP(ip)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		defer conn.Close()
+
+		// O conteúdo da mensagem é o instante do envio, conferido na resposta junto com a sequência
+		seq := uint16(atomic.AddUint32(&icmpSeq, 1))
+		payload := make([]byte, 8)
+		binary.BigEndian.PutUint64(payload, uint64(time.Now().UnixNano()))
+		msg := echoRequest(uint16(os.Getpid()), seq, payload)
+
+		conn.SetDeadline(time.Now().Add(timeout))
+		start := time.Now()
+		if _, err := conn.WriteTo(msg, dst); err != nil {
+			result.Err = err
+			return result
+		}
+		reply := make([]byte, 1500)
+		for {
+			n, from, err := conn.ReadFrom(reply)
+			if err != nil {
+				result.Err = err
+				return result
+			}
+			if !sameIP(from, ip) || !isEchoReply(reply[:n], seq, payload) {
+				// O socket bruto recebe todas as mensagens ICMP do host, inclusive as dos outros workers
+				continue
+			}
+			break
+		}
+
+		result.TimeTooked = time.Since(start)
+		result.Details = map[string]string{"address": ip.String(), "socket": socket}
+		return result
+	}
+}
+
+// listenICMP abre o socket bruto e, caso não haja permissão, o não privilegiado. Devolve também o
+// endereço de destino no formato esperado pelo socket e o tipo de socket usado
+func listenICMP(ip net.IP) (net.PacketConn, net.Addr, string, error) {
+	conn, err := net.ListenPacket("ip4:icmp", "0.0.0.0")
+	if err == nil {
+		return conn, &net.IPAddr{IP: ip}, "raw", nil
+	}
+	if !errors.Is(err, os.ErrPermission) {
+		return nil, nil, "", err
+	}
+	conn, err = listenUnprivilegedICMP()
+	if err != nil {
+		return nil, nil, "", err
+	}
+	return conn, &net.UDPAddr{IP: ip}, "udp", nil
+}
+
+// echoRequest monta uma mensagem de eco ICMP com o checksum calculado
+func echoRequest(id, seq uint16, payload []byte) []byte {
+	msg := make([]byte, 8+len(payload))
+	msg[0] = icmpEchoRequest
+	binary.BigEndian.PutUint16(msg[4:], id)
+	binary.BigEndian.PutUint16(msg[6:], seq)
+	copy(msg[8:], payload)
+	binary.BigEndian.PutUint16(msg[2:], checksum(msg))
+	return msg
+}
+
+// isEchoReply confere se a mensagem é a resposta ao eco enviado. O identificador não é conferido,
+// pois o socket não privilegiado o substitui pela sua porta; a sequência e o conteúdo bastam
+func isEchoReply(msg []byte, seq uint16, payload []byte) bool {
+	// Alguns sistemas entregam a mensagem com o cabeçalho IPv4 no socket não privilegiado
+	if len(msg) > 20 && msg[0]>>4 == 4 {
+		msg = msg[int(msg[0]&0x0f)*4:]
+	}
+	return len(msg) >= 8+len(payload) &&
+		msg[0] == icmpEchoReply &&
+		binary.BigEndian.Uint16(msg[6:]) == seq &&
+		bytes.Equal(msg[8:8+len(payload)], payload)
+}
+
+func checksum(b []byte) uint16 {
+	var sum uint32
+	for i := 0; i+1 < len(b); i += 2 {
+		sum += uint32(b[i])<<8 | uint32(b[i+1])
+	}
+	if len(b)%2 == 1 {
+		sum += uint32(b[len(b)-1]) << 8
+	}
+	for sum>>16 != 0 {
+		sum = sum&0xffff + sum>>16
+	}
+	return ^uint16(sum)
+}
+
+func sameIP(addr net.Addr, ip net.IP) bool {
+	switch a := addr.(type) {
+	case *net.IPAddr:
+		return a.IP.Equal(ip)
+	case *net.UDPAddr:
+		return a.IP.Equal(ip)
+	}
+	return false
+}
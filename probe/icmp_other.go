@@ -0,0 +1,13 @@
+//go:build !linux && !darwin
+
+package probe
+
+import (
+	"errors"
+	"net"
+)
+
+// listenUnprivilegedICMP não está disponível fora do Linux e do macOS
+func listenUnprivilegedICMP() (net.PacketConn, error) {
+	return nil, errors.New("unprivileged ICMP sockets are not supported on this platform")
+}
@@ -0,0 +1,116 @@
+package probe
+
+import (
+	"context"
+	"crypto/tls"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// Options reúne as configurações de conexão compartilhadas pelos tipos de medição
+type Options struct {
+	Timeout time.Duration
+	// Insecure aceita certificados TLS inválidos
+	Insecure bool
+	// Resolve direciona as conexões para um "host:porta" ao endereço informado, mantendo o nome original
+	// no cabeçalho Host e no SNI, assim como a opção --resolve do curl. Permite medir servidores atrás
+	// de balanceadores antes da troca do DNS
+	Resolve map[string]string
+	// ServerName, se informado, é enviado no SNI e usado na verificação do certificado no lugar do
+	// host do alvo, permitindo exercitar o roteamento por SNI independentemente do endereço da conexão
+	ServerName string
+}
+
+// forJob devolve as opções a serem usadas em um job, aplicando o timeout próprio do job, se houver
+func (o Options) forJob(job pool.Job) Options {
+	o.Timeout = jobTimeout(o.Timeout, job)
+	return o
+}
+
+// jobTimeout é o timeout de um job: o seu próprio, quando informado, ou o padrão
+func jobTimeout(timeout time.Duration, job pool.Job) time.Duration {
+	if job.Timeout > 0 {
+		return job.Timeout
+	}
+	return timeout
+}
+
+// Doer é o mínimo que as medições HTTP precisam de um cliente. O *http.Client atende a interface,
+// e nos testes ela pode ser substituída por uma implementação falsa, sem rede nem servidor de testes
+type Doer interface {
+	Do(req *http.Request) (*http.Response, error)
+}
+
+// Client devolve o cliente HTTP a ser usado em um job. Com um timeout próprio no job, é usada uma
+// cópia do cliente com esse timeout, que continua compartilhando o transporte e as suas conexões.
+// Clientes que não são *http.Client são usados como estão
+func Client(client Doer, job pool.Job) Doer {
+	if c, ok := client.(*http.Client); ok {
+		return clientFor(c, job)
+	}
+	return client
+}
+
+func clientFor(client *http.Client, job pool.Job) *http.Client {
+	if job.Timeout <= 0 || job.Timeout == client.Timeout {
+		return client
+	}
+	copied := *client
+	copied.Timeout = job.Timeout
+	return &copied
+}
+
+// ParseResolve interpreta substituições no formato "host:porta:endereço" (ex: example.com:443:10.0.0.5)
+func ParseResolve(specs []string) (map[string]string, error) {
+	resolve := make(map[string]string)
+	for _, spec := range specs {
+		host, rest, ok := strings.Cut(spec, ":")
+		port, addr, ok2 := strings.Cut(rest, ":")
+		if !ok || !ok2 || host == "" || port == "" || addr == "" {
+			return nil, fmt.Errorf("invalid resolve %q: expected host:port:address", spec)
+		}
+		addr = strings.TrimSuffix(strings.TrimPrefix(addr, "["), "]")
+		if net.ParseIP(addr) == nil {
+			return nil, fmt.Errorf("invalid resolve %q: %q is not an IP address", spec, addr)
+		}
+		resolve[net.JoinHostPort(host, port)] = addr
+	}
+	return resolve, nil
+}
+
+// target devolve o endereço ao qual a conexão com hostPort deve ser feita
+func (o Options) target(hostPort string) string {
+	if addr, ok := o.Resolve[hostPort]; ok {
+		_, port, _ := net.SplitHostPort(hostPort)
+		return net.JoinHostPort(addr, port)
+	}
+	return hostPort
+}
+
+// DialContext abre uma conexão aplicando as substituições de Resolve
+func (o Options) DialContext(ctx context.Context, network, addr string) (net.Conn, error) {
+	d := net.Dialer{Timeout: o.Timeout}
+	return d.DialContext(ctx, network, o.target(addr))
+}
+
+// tlsConfig é a configuração TLS para uma conexão com o servidor de nome serverName; o ServerName das
+// opções, quando informado, tem precedência
+func (o Options) tlsConfig(serverName string) *tls.Config {
+	if o.ServerName != "" {
+		serverName = o.ServerName
+	}
+	return &tls.Config{ServerName: serverName, InsecureSkipVerify: o.Insecure}
+}
+
+// Transport cria um transporte HTTP que aplica as opções de conexão
+func (o Options) Transport() *http.Transport {
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.DialContext = o.DialContext
+	transport.TLSClientConfig = o.tlsConfig("")
+	return transport
+}
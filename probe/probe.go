@@ -0,0 +1,93 @@
+// Package probe reúne os tipos de medição que os workers podem executar além da visita HTTP
+// tradicional. Cada medição é uma pool.VisitFunc, então pode ser usada diretamente com pool.New
+package probe
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// formatMs formata uma duração em milissegundos, para ser usada nos detalhes dos resultados
+func formatMs(d time.Duration) string {
+	return strconv.FormatFloat(float64(d)/float64(time.Millisecond), 'f', 3, 64)
+}
+
+// statusError é o erro devolvido quando o servidor não responde com 200
+func statusError(code int) error {
+	return fmt.Errorf("status code 200 not returned (got %d)", code)
+}
+
+// HostPort extrai o par host:porta de um alvo, que pode ser informado diretamente como host:porta ou
+// como URL; nesse caso, sem porta explícita, é usada a porta padrão do esquema (80 para http, 443 para
+// https) ou defaultPort
+func HostPort(target, defaultPort string) (string, error) {
+	if strings.Contains(target, "://") {
+		u, err := url.Parse(target)
+		if err != nil {
+			return "", err
+		}
+		if u.Port() != "" {
+			return u.Host, nil
+		}
+		port := defaultPort
+		switch u.Scheme {
+		case "http", "ws":
+			port = "80"
+		case "https", "wss":
+			port = "443"
+		}
+		if port == "" {
+			return "", fmt.Errorf("no port in %q", target)
+		}
+		return net.JoinHostPort(u.Hostname(), port), nil
+	}
+	if _, _, err := net.SplitHostPort(target); err == nil {
+		return target, nil
+	}
+	if defaultPort == "" {
+		return "", fmt.Errorf("no port in %q", target)
+	}
+	return net.JoinHostPort(target, defaultPort), nil
+}
+
+// resolve resolve o host de um par host:porta, devolvendo o endereço com o primeiro IP encontrado e o
+// tempo gasto na resolução, para que ele possa ser descontado das outras medições
+func resolve(hostPort string, timeout time.Duration) (string, time.Duration, error) {
+	host, port, err := net.SplitHostPort(hostPort)
+	if err != nil {
+		return "", 0, err
+	}
+	if net.ParseIP(host) != nil {
+		return hostPort, 0, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	start := time.Now()
+	addrs, err := net.DefaultResolver.LookupHost(ctx, host)
+	elapsed := time.Since(start)
+	if err != nil {
+		return "", elapsed, err
+	}
+	return net.JoinHostPort(addrs[0], port), elapsed, nil
+}
+
+// ByScheme escolhe a medição pelo esquema do alvo (ex: "ws" para ws://...), usando fallback para os
+// alvos sem esquema ou com um esquema não registrado. É o que permite misturar tipos de endpoint
+// diferentes na mesma lista
+func ByScheme(fallback pool.VisitFunc, schemes map[string]pool.VisitFunc) pool.VisitFunc {
+	return func(job pool.Job) pool.Result {
+		if scheme, _, ok := strings.Cut(job.URL, "://"); ok {
+			if visit, ok := schemes[strings.ToLower(scheme)]; ok {
+				return visit(job)
+			}
+		}
+		return fallback(job)
+	}
+}
@@ -0,0 +1,139 @@
+package probe
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// HeaderCheck é o resultado da verificação de um cabeçalho de segurança
+type HeaderCheck struct {
+	Header string
+	Passed bool
+	// Issue explica a falha: o cabeçalho ausente ou com um valor que não protege
+	Issue string
+}
+
+// AuditSecurityHeaders verifica os cabeçalhos de segurança recomendados em uma resposta. O HSTS só é
+// respeitado pelos navegadores em respostas HTTPS, então ele falha quando a resposta veio por HTTP
+func AuditSecurityHeaders(h http.Header, https bool) []HeaderCheck {
+	return []HeaderCheck{
+		checkHSTS(h, https),
+		present(h, "Content-Security-Policy"),
+		checkValue(h, "X-Content-Type-Options", "nosniff"),
+		checkFraming(h),
+		present(h, "Referrer-Policy"),
+		present(h, "Permissions-Policy"),
+	}
+}
+
+func checkHSTS(h http.Header, https bool) HeaderCheck {
+	check := HeaderCheck{Header: "Strict-Transport-Security"}
+	value := h.Get(check.Header)
+	switch {
+	case !https:
+		check.Issue = "not served over HTTPS"
+	case value == "":
+		check.Issue = "missing"
+	case maxAge(value) <= 0:
+		check.Issue = "max-age must be greater than zero"
+	default:
+		check.Passed = true
+	}
+	return check
+}
+
+// maxAge extrai a diretiva max-age do HSTS, devolvendo -1 quando ela não existe
+func maxAge(hsts string) int {
+	for _, directive := range strings.Split(hsts, ";") {
+		name, value, _ := strings.Cut(strings.TrimSpace(directive), "=")
+		if strings.EqualFold(name, "max-age") {
+			if seconds, err := strconv.Atoi(strings.Trim(value, `"`)); err == nil {
+				return seconds
+			}
+		}
+	}
+	return -1
+}
+
+// checkFraming aceita tanto o X-Frame-Options quanto a diretiva frame-ancestors do CSP, que o substitui
+func checkFraming(h http.Header) HeaderCheck {
+	check := HeaderCheck{Header: "X-Frame-Options"}
+	if strings.Contains(strings.ToLower(h.Get("Content-Security-Policy")), "frame-ancestors") {
+		check.Passed = true
+		return check
+	}
+	switch strings.ToUpper(strings.TrimSpace(h.Get(check.Header))) {
+	case "DENY", "SAMEORIGIN":
+		check.Passed = true
+	case "":
+		check.Issue = "missing"
+	default:
+		check.Issue = "expected DENY or SAMEORIGIN"
+	}
+	return check
+}
+
+func present(h http.Header, name string) HeaderCheck {
+	if strings.TrimSpace(h.Get(name)) == "" {
+		return HeaderCheck{Header: name, Issue: "missing"}
+	}
+	return HeaderCheck{Header: name, Passed: true}
+}
+
+func checkValue(h http.Header, name, expected string) HeaderCheck {
+	value := strings.TrimSpace(h.Get(name))
+	switch {
+	case value == "":
+		return HeaderCheck{Header: name, Issue: "missing"}
+	case !strings.EqualFold(value, expected):
+		return HeaderCheck{Header: name, Issue: "expected " + expected}
+	}
+	return HeaderCheck{Header: name, Passed: true}
+}
+
+// SecurityHeaders visita a URL como a medição HTTP padrão e audita os cabeçalhos de segurança da
+// resposta. O detalhe "score" traz quantas verificações passaram (ex: "4/6") e "failed" lista os
+// cabeçalhos que falharam com o motivo
+func SecurityHeaders(client Doer) pool.VisitFunc {
+	return func(job pool.Job) pool.Result {
+		result := pool.Result{URL: job.URL}
+		req, err := http.NewRequest("GET", job.URL, nil)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		start := time.Now()
+		resp, err := Client(client, job).Do(req)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		defer resp.Body.Close()
+		result.TimeTooked = time.Since(start)
+		if resp.StatusCode != 200 {
+			result.Err = statusError(resp.StatusCode)
+			return result
+		}
+
+		// Após redirecionamentos, vale o esquema da resposta final
+		checks := AuditSecurityHeaders(resp.Header, resp.Request.URL.Scheme == "https")
+		passed := 0
+		var failed []string
+		for _, check := range checks {
+			if check.Passed {
+				passed++
+				continue
+			}
+			failed = append(failed, check.Header+": "+check.Issue)
+		}
+		result.Details = map[string]string{"score": strconv.Itoa(passed) + "/" + strconv.Itoa(len(checks))}
+		if len(failed) > 0 {
+			result.Details["failed"] = strings.Join(failed, "; ")
+		}
+		return result
+	}
+}
@@ -0,0 +1,124 @@
+package probe
+
+import (
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TimingMetric é uma das métricas do cabeçalho Server-Timing, como db;dur=53 ou
+// cache;desc="Cache Read";dur=23.2. HasDuration é falso para as métricas sem dur, que apenas sinalizam
+// algo (ex: miss)
+type TimingMetric struct {
+	Name        string
+	Description string
+	Duration    time.Duration
+	HasDuration bool
+}
+
+// ParseServerTiming lê as métricas do cabeçalho Server-Timing
+// (https://www.w3.org/TR/server-timing/), na ordem em que aparecem. Entradas malformadas são
+// ignoradas, já que o cabeçalho é apenas informativo
+func ParseServerTiming(h http.Header) []TimingMetric {
+	var metrics []TimingMetric
+	for _, value := range h.Values("Server-Timing") {
+		for _, entry := range splitQuoted(value, ',') {
+			params := splitQuoted(entry, ';')
+			metric := TimingMetric{Name: strings.TrimSpace(params[0])}
+			if metric.Name == "" {
+				continue
+			}
+			for _, param := range params[1:] {
+				key, val, _ := strings.Cut(param, "=")
+				val = unquote(strings.TrimSpace(val))
+				switch strings.ToLower(strings.TrimSpace(key)) {
+				case "dur":
+					ms, err := strconv.ParseFloat(val, 64)
+					if err == nil && ms >= 0 {
+						metric.Duration = time.Duration(ms * float64(time.Millisecond))
+						metric.HasDuration = true
+					}
+				case "desc":
+					metric.Description = val
+				}
+			}
+			metrics = append(metrics, metric)
+		}
+	}
+	return metrics
+}
+
+// splitQuoted divide o texto pelo separador, sem considerar os separadores dentro de aspas (onde
+// \" é uma aspa escapada)
+func splitQuoted(s string, sep rune) []string {
+	var parts []string
+	quoted, escaped := false, false
+	start := 0
+	for i, c := range s {
+		switch {
+		case escaped:
+			escaped = false
+		case c == '\\' && quoted:
+			escaped = true
+		case c == '"':
+			quoted = !quoted
+		case c == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// unquote remove as aspas de um valor entre aspas, desfazendo os escapes com \
+func unquote(s string) string {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return s
+	}
+	var b strings.Builder
+	escaped := false
+	for _, c := range s[1 : len(s)-1] {
+		if c == '\\' && !escaped {
+			escaped = true
+			continue
+		}
+		escaped = false
+		b.WriteRune(c)
+	}
+	return b.String()
+}
+
+// AddServerTiming registra nos detalhes do resultado as fases informadas pelo servidor no cabeçalho
+// Server-Timing: "server_timing_<fase>_ms" traz a duração de cada fase (ex: server_timing_db_ms),
+// "server_time_ms" o tempo gasto no servidor e "network_time_ms" o restante do tempo medido pelo
+// cliente, que corresponde à rede (conexão, TLS e transferência). O tempo do servidor é a métrica
+// total, quando informada, ou a soma das fases. Sem o cabeçalho, os detalhes não são alterados
+func AddServerTiming(details map[string]string, h http.Header, elapsed time.Duration) {
+	var sum, total time.Duration
+	found, hasTotal := false, false
+	for _, m := range ParseServerTiming(h) {
+		if !m.HasDuration {
+			continue
+		}
+		found = true
+		name := strings.ToLower(timingName.ReplaceAllString(m.Name, "_"))
+		details["server_timing_"+name+"_ms"] = formatMs(m.Duration)
+		if name == "total" {
+			total, hasTotal = m.Duration, true
+			continue
+		}
+		sum += m.Duration
+	}
+	if !found {
+		return
+	}
+	if !hasTotal {
+		total = sum
+	}
+	details["server_time_ms"] = formatMs(total)
+	details["network_time_ms"] = formatMs(max(elapsed-total, 0))
+}
+
+var timingName = regexp.MustCompile(`[^A-Za-z0-9_]`)
@@ -0,0 +1,75 @@
+package probe
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseServerTiming(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name   string
+		values []string
+		want   []TimingMetric
+	}{
+		{"single", []string{"db;dur=53"}, []TimingMetric{{Name: "db", Duration: 53 * ms, HasDuration: true}}},
+		{"fractional", []string{"app;dur=23.2"}, []TimingMetric{{Name: "app", Duration: 23200 * time.Microsecond, HasDuration: true}}},
+		{"quoted desc", []string{`cache;desc="Cache Read";dur=23`}, []TimingMetric{{Name: "cache", Description: "Cache Read", Duration: 23 * ms, HasDuration: true}}},
+		{"desc with separators", []string{`db;desc="users, orders; joins";dur=4, app;dur=1`}, []TimingMetric{
+			{Name: "db", Description: "users, orders; joins", Duration: 4 * ms, HasDuration: true},
+			{Name: "app", Duration: ms, HasDuration: true},
+		}},
+		{"escaped quote", []string{`db;desc="say \"hi\", ok";dur=2`}, []TimingMetric{{Name: "db", Description: `say "hi", ok`, Duration: 2 * ms, HasDuration: true}}},
+		{"missing dur", []string{"miss, db;dur=2"}, []TimingMetric{{Name: "miss"}, {Name: "db", Duration: 2 * ms, HasDuration: true}}},
+		{"invalid dur", []string{"db;dur=abc, app;dur=-1"}, []TimingMetric{{Name: "db"}, {Name: "app"}}},
+		{"spaces and case", []string{" DB ; DUR = 7 "}, []TimingMetric{{Name: "DB", Duration: 7 * ms, HasDuration: true}}},
+		{"several headers", []string{"db;dur=1", "app;dur=2"}, []TimingMetric{
+			{Name: "db", Duration: ms, HasDuration: true},
+			{Name: "app", Duration: 2 * ms, HasDuration: true},
+		}},
+		{"empty entries", []string{", ;dur=3,"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := http.Header{"Server-Timing": tt.values}
+			if got := ParseServerTiming(h); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAddServerTiming(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   map[string]string
+	}{
+		{"sum of phases", "db;dur=20, app;dur=10", map[string]string{
+			"server_timing_db_ms": "20.000", "server_timing_app_ms": "10.000",
+			"server_time_ms": "30.000", "network_time_ms": "70.000",
+		}},
+		{"total metric wins", "db;dur=20, app;dur=10, total;dur=45", map[string]string{
+			"server_timing_db_ms": "20.000", "server_timing_app_ms": "10.000", "server_timing_total_ms": "45.000",
+			"server_time_ms": "45.000", "network_time_ms": "55.000",
+		}},
+		{"names are sanitized", `cache-read;desc="x";dur=5`, map[string]string{
+			"server_timing_cache_read_ms": "5.000", "server_time_ms": "5.000", "network_time_ms": "95.000",
+		}},
+		{"server slower than client", "app;dur=150", map[string]string{
+			"server_timing_app_ms": "150.000", "server_time_ms": "150.000", "network_time_ms": "0.000",
+		}},
+		{"only flags", "miss, edge", map[string]string{}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			details := map[string]string{}
+			AddServerTiming(details, http.Header{"Server-Timing": {tt.header}}, 100*time.Millisecond)
+			if !reflect.DeepEqual(details, tt.want) {
+				t.Errorf("got %v, want %v", details, tt.want)
+			}
+		})
+	}
+}
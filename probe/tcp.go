@@ -0,0 +1,114 @@
+package probe
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// TCP mede apenas o handshake TCP com o alvo, sem nenhuma requisição HTTP, o que permite verificar
+// a alcançabilidade da rede sem o custo do protocolo. Os alvos são pares host:porta (ou URLs, usando
+// a porta do esquema). A resolução do nome é feita antes e não entra no tempo medido, aparecendo
+// separadamente nos detalhes.
+// Alvos no formato tcp://host:porta?send=...&expect=... também verificam o serviço: após a conexão,
+// o conteúdo de send é enviado e os primeiros bytes da resposta são comparados com expect (ex: o
+// banner "220" de um servidor SMTP, ou PING e +PONG no Redis). Nesse caso, o tempo medido vai do
+// início da conexão até a chegada da resposta esperada
+func TCP(opts Options) pool.VisitFunc {
+	return func(job pool.Job) pool.Result {
+		result := pool.Result{URL: job.URL}
+		opts := opts.forJob(job)
+		hostPort, err := HostPort(job.URL, "")
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		send, expect, err := tcpExchange(job.URL)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		addr, resolveTime, err := resolve(opts.target(hostPort), opts.Timeout)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+
+		start := time.Now()
+		conn, err := net.DialTimeout("tcp", addr, opts.Timeout)
+		connected := time.Since(start)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		defer conn.Close()
+
+		result.TimeTooked = connected
+		result.Details = map[string]string{
+			"address":    addr,
+			"resolve_ms": formatMs(resolveTime),
+		}
+		if send == nil && expect == nil {
+			return result
+		}
+
+		conn.SetDeadline(time.Now().Add(opts.Timeout))
+		if len(send) > 0 {
+			if _, err := conn.Write(send); err != nil {
+				result.Err = err
+				return result
+			}
+		}
+		if len(expect) > 0 {
+			response := make([]byte, len(expect))
+			n, err := io.ReadFull(conn, response)
+			if err != nil && n == 0 {
+				result.Err = err
+				return result
+			}
+			if !bytes.Equal(response[:n], expect) {
+				result.Err = fmt.Errorf("unexpected response %q (expected %q)", response[:n], expect)
+				return result
+			}
+			result.Details["response"] = strconv.Quote(string(response[:n]))
+		}
+		result.TimeTooked = time.Since(start)
+		result.Details["connect_ms"] = formatMs(connected)
+		return result
+	}
+}
+
+// tcpExchange lê os parâmetros send e expect de um alvo tcp://. Os valores aceitam a codificação de
+// URLs (%0D%0A) e as sequências de escape do Go (\r\n)
+func tcpExchange(target string) ([]byte, []byte, error) {
+	if !strings.HasPrefix(target, "tcp://") {
+		return nil, nil, nil
+	}
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, nil, err
+	}
+	query := u.Query()
+	var send, expect []byte
+	if query.Has("send") {
+		send = []byte(unescape(query.Get("send")))
+	}
+	if query.Has("expect") {
+		expect = []byte(unescape(query.Get("expect")))
+	}
+	return send, expect, nil
+}
+
+func unescape(s string) string {
+	if unquoted, err := strconv.Unquote(`"` + s + `"`); err == nil {
+		return unquoted
+	}
+	return s
+}
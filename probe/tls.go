@@ -0,0 +1,73 @@
+package probe
+
+import (
+	"crypto/tls"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// TLS faz apenas a conexão TCP e o handshake TLS com cada alvo, sem nenhuma requisição HTTP. O tempo
+// do resultado é o do handshake TLS; os detalhes trazem o tempo da conexão TCP, a versão e a cifra
+// negociadas e as informações da cadeia de certificados. Os alvos são pares host:porta ou URLs (a
+// porta padrão é 443). Com opts.Insecure, certificados inválidos não interrompem a medição
+func TLS(opts Options) pool.VisitFunc {
+	return func(job pool.Job) pool.Result {
+		result := pool.Result{URL: job.URL}
+		opts := opts.forJob(job)
+		hostPort, err := HostPort(job.URL, "443")
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		host, _, _ := net.SplitHostPort(hostPort)
+
+		start := time.Now()
+		conn, err := net.DialTimeout("tcp", opts.target(hostPort), opts.Timeout)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		defer conn.Close()
+		connected := time.Since(start)
+
+		conn.SetDeadline(time.Now().Add(opts.Timeout))
+		config := opts.tlsConfig(host)
+		tlsConn := tls.Client(conn, config)
+		start = time.Now()
+		if err := tlsConn.Handshake(); err != nil {
+			result.Err = err
+			return result
+		}
+		result.TimeTooked = time.Since(start)
+
+		state := tlsConn.ConnectionState()
+		result.Details = map[string]string{
+			"connect_ms": formatMs(connected),
+			"version":    tls.VersionName(state.Version),
+			"cipher":     tls.CipherSuiteName(state.CipherSuite),
+			"sni":        config.ServerName,
+		}
+		if state.NegotiatedProtocol != "" {
+			result.Details["alpn"] = state.NegotiatedProtocol
+		}
+		if len(state.PeerCertificates) > 0 {
+			leaf := state.PeerCertificates[0]
+			result.Details["subject"] = leaf.Subject.CommonName
+			result.Details["issuer"] = leaf.Issuer.CommonName
+			result.Details["dns_names"] = strings.Join(leaf.DNSNames, " ")
+			result.Details["not_after"] = leaf.NotAfter.UTC().Format(time.RFC3339)
+			result.Details["days_left"] = strconv.Itoa(int(time.Until(leaf.NotAfter).Hours() / 24))
+			// A cadeia é descrita pelo nome comum de cada certificado, do servidor até a raiz enviada
+			chain := make([]string, len(state.PeerCertificates))
+			for i, cert := range state.PeerCertificates {
+				chain[i] = cert.Subject.CommonName
+			}
+			result.Details["chain"] = strings.Join(chain, " > ")
+		}
+		return result
+	}
+}
@@ -0,0 +1,43 @@
+package probe
+
+import (
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+	"github.com/joaomarcelofa/entendendo-worker-pool/websocket"
+)
+
+// WebSocket abre uma conexão com o endpoint ws:// ou wss://, envia um ping e mede o tempo até o pong,
+// para que endpoints de tempo real possam ser monitorados junto com os REST. O tempo da conexão e
+// do upgrade aparece separadamente nos detalhes
+func WebSocket(opts Options) pool.VisitFunc {
+	dialer := &websocket.Dialer{
+		Timeout:   opts.Timeout,
+		NetDial:   opts.DialContext,
+		TLSConfig: opts.tlsConfig(""),
+	}
+	return func(job pool.Job) pool.Result {
+		result := pool.Result{URL: job.URL}
+		timeout := jobTimeout(opts.Timeout, job)
+		dialer := *dialer
+		dialer.Timeout = timeout
+		start := time.Now()
+		conn, err := dialer.Dial(job.URL)
+		if err != nil {
+			result.Err = err
+			return result
+		}
+		defer conn.Close()
+		handshake := time.Since(start)
+
+		conn.SetDeadline(time.Now().Add(timeout))
+		start = time.Now()
+		if err := conn.Ping([]byte(start.Format(time.RFC3339Nano))); err != nil {
+			result.Err = err
+			return result
+		}
+		result.TimeTooked = time.Since(start)
+		result.Details = map[string]string{"handshake_ms": formatMs(handshake)}
+		return result
+	}
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+	"github.com/joaomarcelofa/entendendo-worker-pool/probe"
+)
+
+// probeConfig guarda o tipo de medição executado pelos workers, configurado pela flag -mode, e as
+// opções específicas de cada tipo
+type probeConfig struct {
+	mode     string
+	maxBytes int64
+	resolver string
+	insecure bool
+	resolve  stringList
+	sni      string
+	// hashBody faz a medição http ler o corpo das respostas e registrar o seu hash (ver httpHashVisit)
+	hashBody bool
+	// trace imprime os eventos dos workers e da fila (ver traceHooks)
+	trace bool
+}
+
+// probeFlags registra a flag -mode e as opções dos tipos de medição
+func probeFlags(fs *flag.FlagSet) *probeConfig {
+	pc := &probeConfig{}
+	fs.StringVar(&pc.mode, "mode", "http", "what the workers measure: http, download, tcp, dns, icmp, tls or conditional")
+	fs.Int64Var(&pc.maxBytes, "max-bytes", 0, "download mode: stop each download after this many bytes (0 downloads everything)")
+	fs.StringVar(&pc.resolver, "resolver", "", "dns mode: DNS server to query (e.g. 1.1.1.1:53) instead of the system resolver")
+	fs.BoolVar(&pc.insecure, "insecure", false, "accept invalid TLS certificates")
+	fs.Var(&pc.resolve, "resolve", "connect to a specific address while keeping the original Host and SNI, as host:port:address; may be repeated")
+	fs.StringVar(&pc.sni, "sni", "", "TLS server name (SNI) sent instead of the target host, also used to verify the certificate")
+	fs.BoolVar(&pc.trace, "trace", false, "print every worker and queue event (start, idle, job start and end, queue full) to stderr, to follow how the pool distributes the work")
+	return pc
+}
+
+// options monta as opções de conexão dos tipos de medição
+func (pc *probeConfig) options(timeout int) (probe.Options, error) {
+	resolve, err := probe.ParseResolve(pc.resolve)
+	if err != nil {
+		return probe.Options{}, err
+	}
+	return probe.Options{
+		Timeout:    time.Duration(timeout) * time.Second,
+		Insecure:   pc.insecure,
+		Resolve:    resolve,
+		ServerName: pc.sni,
+	}, nil
+}
+
+// httpClient é o cliente HTTP dos workers; o transporte padrão só é substituído quando alguma opção
+// de conexão foi informada
+func (pc *probeConfig) httpClient(opts probe.Options) *http.Client {
+	client := createSimpleHTTPClient(int(opts.Timeout / time.Second))
+	if opts.Insecure || len(opts.Resolve) > 0 || opts.ServerName != "" {
+		client.Transport = opts.Transport()
+	}
+	return client
+}
+
+// newPool cria o worker pool executando a medição configurada
+func (pc *probeConfig) newPool(qtyWorkers, timeout int) (*pool.Pool, error) {
+	visit, err := pc.visit(timeout)
+	if err != nil {
+		return nil, err
+	}
+	if pc.trace {
+		return pool.NewWithHooks(qtyWorkers, visit, traceHooks(os.Stderr)), nil
+	}
+	return pool.New(qtyWorkers, visit), nil
+}
+
+// visit monta a medição configurada, validando o modo e as opções
+func (pc *probeConfig) visit(timeout int) (pool.VisitFunc, error) {
+	opts, err := pc.options(timeout)
+	if err != nil {
+		return nil, err
+	}
+	var visit pool.VisitFunc
+	switch pc.mode {
+	case "http":
+		// Endpoints WebSocket, gRPC e portas TCP podem ser misturados às URLs HTTP na mesma lista
+		ws := probe.WebSocket(opts)
+		grpc := probe.GRPCHealth(opts)
+		web := httpVisit(pc.httpClient(opts))
+		if pc.hashBody {
+			web = httpHashVisit(pc.httpClient(opts))
+		}
+		visit = probe.ByScheme(web, map[string]pool.VisitFunc{
+			"ws":    ws,
+			"wss":   ws,
+			"grpc":  grpc,
+			"grpcs": grpc,
+			"tcp":   probe.TCP(opts),
+		})
+	case "download":
+		visit = probe.Download(pc.httpClient(opts), pc.maxBytes)
+	case "tcp":
+		visit = probe.TCP(opts)
+	case "dns":
+		visit = probe.DNS(pc.resolver, opts.Timeout)
+	case "icmp":
+		visit = probe.ICMP(opts.Timeout)
+	case "tls":
+		visit = probe.TLS(opts)
+	case "conditional":
+		visit = probe.Conditional(pc.httpClient(opts))
+	default:
+		return nil, fmt.Errorf("unknown mode %q", pc.mode)
+	}
+	return visit, nil
+}
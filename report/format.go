@@ -0,0 +1,253 @@
+package report
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// Formatter escreve um relatório em um formato de saída (texto, JSON, CSV...)
+type Formatter interface {
+	Format(w io.Writer, r *Report) error
+}
+
+// FormatterFunc permite usar uma função comum como Formatter
+type FormatterFunc func(w io.Writer, r *Report) error
+
+func (f FormatterFunc) Format(w io.Writer, r *Report) error {
+	return f(w, r)
+}
+
+var (
+	formatsMux sync.Mutex
+	formats    = make(map[string]Formatter)
+)
+
+func init() {
+	RegisterFormat("text", FormatterFunc(formatText))
+	RegisterFormat("json", FormatterFunc(formatJSON))
+	RegisterFormat("csv", FormatterFunc(formatCSV))
+	RegisterFormat("markdown", FormatterFunc(formatMarkdown))
+	RegisterFormat("html", FormatterFunc(func(w io.Writer, r *Report) error { return htmlTemplate.Execute(w, r) }))
+}
+
+// RegisterFormat associa um nome a um Formatter, permitindo que ele seja escolhido pela flag -format
+// ou pela extensão do arquivo em WriteFile. Registrar um nome existente substitui o formato anterior
+func RegisterFormat(name string, f Formatter) {
+	formatsMux.Lock()
+	defer formatsMux.Unlock()
+	formats[name] = f
+}
+
+// FormatNames devolve os nomes dos formatos registrados, em ordem alfabética
+func FormatNames() []string {
+	formatsMux.Lock()
+	defer formatsMux.Unlock()
+	names := make([]string, 0, len(formats))
+	for name := range formats {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LookupFormat devolve o Formatter registrado com o nome informado
+func LookupFormat(name string) (Formatter, error) {
+	formatsMux.Lock()
+	f, ok := formats[name]
+	formatsMux.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown format %q (available: %s)", name, strings.Join(FormatNames(), ", "))
+	}
+	return f, nil
+}
+
+// extensionFormats são as extensões de arquivo cujo nome não coincide com o do formato
+var extensionFormats = map[string]string{"md": "markdown", "txt": "text", "htm": "html"}
+
+// formatForPath escolhe o formato pela extensão do arquivo; extensões desconhecidas geram JSON
+func formatForPath(path string) Formatter {
+	ext := strings.ToLower(strings.TrimPrefix(filepath.Ext(path), "."))
+	if name, ok := extensionFormats[ext]; ok {
+		ext = name
+	}
+	if f, err := LookupFormat(ext); err == nil {
+		return f
+	}
+	return FormatterFunc(formatJSON)
+}
+
+func formatJSON(w io.Writer, r *Report) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(r)
+}
+
+func formatText(w io.Writer, r *Report) error {
+	fmt.Fprintf(w, "Report generated at %s\n", r.GeneratedAt.Format("2006-01-02 15:04:05"))
+	if len(r.Labels) > 0 {
+		fmt.Fprintf(w, "Labels: %s\n", strings.ReplaceAll(detailsText(r.Labels), ";", ", "))
+	}
+	for _, m := range r.Methods {
+		fmt.Fprintf(w, "\n%s\n", m.Name)
+		fmt.Fprintf(w, "Total time: %s\n", m.Elapsed)
+		if m.Fastest.URL != "" {
+			fmt.Fprintf(w, "Fastest URL: %s - %s\n", m.Fastest.URL, m.Fastest.TimeTooked)
+		}
+		for _, result := range m.Results {
+			fmt.Fprintf(w, "  %-50s %s\n", result.URL, resultText(result))
+		}
+	}
+	if r.Matrix != nil {
+		fmt.Fprintf(w, "\nLatency matrix\n%-50s", "URL")
+		for _, column := range r.Matrix.Columns {
+			fmt.Fprintf(w, " %16s", column)
+		}
+		fmt.Fprintln(w)
+		for _, row := range r.Matrix.Rows {
+			fmt.Fprintf(w, "%-50s", row.URL)
+			for _, cell := range row.Cells {
+				fmt.Fprintf(w, " %16s", cellText(cell))
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	if len(r.Security) > 0 {
+		fmt.Fprintf(w, "\nSecurity headers\n")
+		for _, result := range r.Security {
+			if result.Err != nil {
+				fmt.Fprintf(w, "  %-50s error: %s\n", result.URL, result.Err)
+				continue
+			}
+			fmt.Fprintf(w, "  %-50s %s %s\n", result.URL, result.Details["score"], result.Details["failed"])
+		}
+	}
+	return nil
+}
+
+// formatCSV gera uma linha por resultado, identificando o método que o produziu e os labels da execução
+func formatCSV(w io.Writer, r *Report) error {
+	cw := csv.NewWriter(w)
+	cw.Write([]string{"method", "timestamp", "url", "time_tooked_ms", "error", "details", "labels"})
+	labels := detailsText(r.Labels)
+	write := func(method string, result pool.Result) {
+		errMsg := ""
+		if result.Err != nil {
+			errMsg = result.Err.Error()
+		}
+		cw.Write([]string{
+			method,
+			result.Timestamp.Format(time.RFC3339Nano),
+			result.URL,
+			strconv.FormatFloat(float64(result.TimeTooked)/float64(time.Millisecond), 'f', 3, 64),
+			errMsg,
+			detailsText(result.Details),
+			labels,
+		})
+	}
+	for _, m := range r.Methods {
+		for _, result := range m.Results {
+			write(m.Name, result)
+		}
+	}
+	for _, result := range r.Security {
+		write("Security headers", result)
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+func formatMarkdown(w io.Writer, r *Report) error {
+	fmt.Fprintf(w, "# Report generated at %s\n", r.GeneratedAt.Format("2006-01-02 15:04:05"))
+	if len(r.Labels) > 0 {
+		fmt.Fprintf(w, "\nLabels: %s\n", markdownEscape(strings.ReplaceAll(detailsText(r.Labels), ";", ", ")))
+	}
+	if r.Matrix != nil {
+		fmt.Fprintf(w, "\n## Latency matrix\n\n| URL |")
+		for _, column := range r.Matrix.Columns {
+			fmt.Fprintf(w, " %s |", markdownEscape(column))
+		}
+		fmt.Fprintf(w, "\n|---|%s\n", strings.Repeat("---|", len(r.Matrix.Columns)))
+		for _, row := range r.Matrix.Rows {
+			fmt.Fprintf(w, "| %s |", markdownEscape(row.URL))
+			for _, cell := range row.Cells {
+				fmt.Fprintf(w, " %s |", markdownEscape(cellText(cell)))
+			}
+			fmt.Fprintln(w)
+		}
+	}
+	if len(r.Security) > 0 {
+		fmt.Fprintf(w, "\n## Security headers\n\n| URL | Time | Score | Failed checks |\n|---|---|---|---|\n")
+		for _, result := range r.Security {
+			if result.Err != nil {
+				fmt.Fprintf(w, "| %s | error: %s | | |\n", markdownEscape(result.URL), markdownEscape(result.Err.Error()))
+				continue
+			}
+			fmt.Fprintf(w, "| %s | %s | %s | %s |\n", markdownEscape(result.URL), result.TimeTooked,
+				result.Details["score"], markdownEscape(result.Details["failed"]))
+		}
+	}
+	for _, m := range r.Methods {
+		fmt.Fprintf(w, "\n## %s\n\nTotal time: %s", markdownEscape(m.Name), m.Elapsed)
+		if m.Fastest.URL != "" {
+			fmt.Fprintf(w, " — Fastest URL: %s (%s)", markdownEscape(m.Fastest.URL), m.Fastest.TimeTooked)
+		}
+		fmt.Fprintf(w, "\n\n| URL | Time | Error |\n|---|---|---|\n")
+		for _, result := range m.Results {
+			if result.Err != nil {
+				fmt.Fprintf(w, "| %s | - | %s |\n", markdownEscape(result.URL), markdownEscape(result.Err.Error()))
+				continue
+			}
+			fmt.Fprintf(w, "| %s | %s | |\n", markdownEscape(result.URL), result.TimeTooked)
+		}
+	}
+	return nil
+}
+
+func markdownEscape(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+func resultText(result pool.Result) string {
+	if result.Err != nil {
+		return "error: " + result.Err.Error()
+	}
+	if len(result.Details) == 0 {
+		return result.TimeTooked.String()
+	}
+	return fmt.Sprintf("%s (%s)", result.TimeTooked, strings.ReplaceAll(detailsText(result.Details), ";", ", "))
+}
+
+func cellText(cell pool.Result) string {
+	switch {
+	case cell.URL == "":
+		return "-"
+	case cell.Err != nil:
+		return "error"
+	default:
+		return cell.TimeTooked.String()
+	}
+}
+
+// detailsText junta os detalhes em ordem alfabética, para que a saída seja estável
+func detailsText(details map[string]string) string {
+	keys := make([]string, 0, len(details))
+	for k := range details {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + details[k]
+	}
+	return strings.Join(parts, ";")
+}
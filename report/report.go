@@ -0,0 +1,142 @@
+package report
+
+import (
+	"encoding/json"
+	"html/template"
+	"os"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// Method representa a execução da lista de URLs por um dos métodos comparados
+type Method struct {
+	Name    string
+	Elapsed time.Duration
+	Fastest pool.Result
+	Results []pool.Result
+}
+
+// MarshalJSON representa o tempo total em milissegundos, assim como os resultados
+func (m Method) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Name      string        `json:"name"`
+		ElapsedMs float64       `json:"elapsed_ms"`
+		Fastest   pool.Result   `json:"fastest"`
+		Results   []pool.Result `json:"results"`
+	}{m.Name, float64(m.Elapsed) / float64(time.Millisecond), m.Fastest, m.Results})
+}
+
+// Report é o relatório de uma execução completa
+type Report struct {
+	GeneratedAt time.Time `json:"generated_at"`
+	// Labels identifica a execução (ex: env=staging, build=1234), para comparar relatórios de
+	// ambientes ou versões diferentes
+	Labels  map[string]string `json:"labels,omitempty"`
+	Methods []Method          `json:"methods"`
+	// Matrix, quando presente, cruza as URLs com os pontos de medição (ex: proxies de regiões diferentes)
+	Matrix *Matrix `json:"matrix,omitempty"`
+	// Security, quando presente, traz a auditoria dos cabeçalhos de segurança de cada URL, com a
+	// pontuação e as falhas nos detalhes dos resultados
+	Security []pool.Result `json:"security,omitempty"`
+}
+
+// Matrix é uma tabela de resultados com uma linha por URL e uma coluna por ponto de medição
+type Matrix struct {
+	Columns []string    `json:"columns"`
+	Rows    []MatrixRow `json:"rows"`
+}
+
+// MatrixRow traz os resultados de uma URL, na mesma ordem das colunas
+type MatrixRow struct {
+	URL   string        `json:"url"`
+	Cells []pool.Result `json:"cells"`
+}
+
+// NewMatrix monta a matriz a partir dos métodos do relatório, usando o nome de cada método como
+// coluna. As linhas seguem a ordem de urls e as URLs sem resultado em um método ficam com a célula vazia
+func NewMatrix(urls []string, methods []Method) *Matrix {
+	m := &Matrix{}
+	byMethod := make([]map[string]pool.Result, len(methods))
+	for i, method := range methods {
+		m.Columns = append(m.Columns, method.Name)
+		byMethod[i] = make(map[string]pool.Result)
+		for _, r := range method.Results {
+			byMethod[i][r.URL] = r
+		}
+	}
+	for _, u := range urls {
+		row := MatrixRow{URL: u, Cells: make([]pool.Result, len(methods))}
+		for i := range methods {
+			row.Cells[i] = byMethod[i][u]
+		}
+		m.Rows = append(m.Rows, row)
+	}
+	return m
+}
+
+// New cria um relatório vazio
+func New() *Report {
+	return &Report{GeneratedAt: time.Now()}
+}
+
+// Add inclui no relatório o resultado de um método
+func (r *Report) Add(m Method) {
+	r.Methods = append(r.Methods, m)
+}
+
+// WriteFile grava o relatório no caminho informado, no formato correspondente à extensão do arquivo
+// (.html, .md, .csv, .txt ou o nome de qualquer formato registrado); outras extensões geram JSON
+func (r *Report) WriteFile(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	if err := formatForPath(path).Format(f, r); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Entendendo worker pool - report</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+table { border-collapse: collapse; margin-bottom: 2em; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+<h1>Report generated at {{.GeneratedAt.Format "2006-01-02 15:04:05"}}</h1>
+{{with .Labels}}<p>Labels:{{range $k, $v := .}} <code>{{$k}}={{$v}}</code>{{end}}</p>{{end}}
+{{with .Matrix}}
+<h2>Latency matrix</h2>
+<table>
+<tr><th>URL</th>{{range .Columns}}<th>{{.}}</th>{{end}}</tr>
+{{range .Rows}}<tr><td>{{.URL}}</td>{{range .Cells}}<td{{if .Err}} class="error"{{end}}>{{if .Err}}{{.Err}}{{else if .URL}}{{.TimeTooked}}{{else}}-{{end}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}
+{{with .Security}}
+<h2>Security headers</h2>
+<table>
+<tr><th>URL</th><th>Time</th><th>Score</th><th>Failed checks</th></tr>
+{{range .}}<tr><td>{{.URL}}</td>{{if .Err}}<td class="error" colspan="3">{{.Err}}</td>{{else}}<td>{{.TimeTooked}}</td><td>{{index .Details "score"}}</td><td class="error">{{index .Details "failed"}}</td>{{end}}</tr>
+{{end}}</table>
+{{end}}
+{{range .Methods}}
+<h2>{{.Name}}</h2>
+<p>Total time: {{.Elapsed}} &mdash; Fastest URL: {{.Fastest.URL}} ({{.Fastest.TimeTooked}})</p>
+<table>
+<tr><th>URL</th><th>Time</th><th>Error</th></tr>
+{{range .Results}}<tr><td>{{.URL}}</td><td>{{.TimeTooked}}</td><td class="error">{{if .Err}}{{.Err}}{{end}}</td></tr>
+{{end}}</table>
+{{end}}
+</body>
+</html>
+`))
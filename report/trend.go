@@ -0,0 +1,316 @@
+package report
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html/template"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// ReadResults lê os resultados guardados pelos sinks json (JSON Lines) e csv; o formato é escolhido
+// pela extensão do arquivo
+func ReadResults(path string) ([]pool.Result, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	if strings.EqualFold(filepath.Ext(path), ".csv") {
+		return readCSVResults(f, path)
+	}
+	var results []pool.Result
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var result pool.Result
+		if err := json.Unmarshal([]byte(line), &result); err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, n, err)
+		}
+		results = append(results, result)
+	}
+	return results, scanner.Err()
+}
+
+// readCSVResults lê o formato do sink csv: timestamp, url, time_tooked_ms, error, labels e
+// queue_wait_ms
+func readCSVResults(r io.Reader, path string) ([]pool.Result, error) {
+	cr := csv.NewReader(r)
+	// Arquivos criados antes das colunas de labels e de espera na fila têm menos colunas
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	var results []pool.Result
+	for i, record := range records {
+		if i == 0 && len(record) > 0 && record[0] == "timestamp" {
+			continue
+		}
+		if len(record) < 4 {
+			return nil, fmt.Errorf("%s:%d: expected at least 4 columns", path, i+1)
+		}
+		at, err := time.Parse(time.RFC3339Nano, record[0])
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, i+1, err)
+		}
+		ms, err := strconv.ParseFloat(record[2], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %v", path, i+1, err)
+		}
+		result := pool.Result{URL: record[1], Timestamp: at, TimeTooked: time.Duration(ms * float64(time.Millisecond))}
+		if record[3] != "" {
+			result.Err = errors.New(record[3])
+		}
+		if len(record) > 4 && record[4] != "" {
+			result.Labels = make(map[string]string)
+			for _, pair := range strings.Split(record[4], ";") {
+				k, v, _ := strings.Cut(pair, "=")
+				result.Labels[k] = v
+			}
+		}
+		if len(record) > 5 && record[5] != "" {
+			wait, err := strconv.ParseFloat(record[5], 64)
+			if err != nil {
+				return nil, fmt.Errorf("%s:%d: %v", path, i+1, err)
+			}
+			result.QueueWait = time.Duration(wait * float64(time.Millisecond))
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Trend é a evolução da latência de uma URL ao longo do período analisado. Points traz a mediana de
+// cada intervalo do período; os intervalos sem nenhuma resposta bem-sucedida ficam com -1
+type Trend struct {
+	URL    string
+	Points []time.Duration
+	// Errors é a quantidade de resultados com erro, que não entram nas medianas
+	Errors  int
+	Samples int
+	Min     time.Duration
+	Median  time.Duration
+	Max     time.Duration
+	// Change é a variação percentual entre a mediana do primeiro e a do último terço do período,
+	// a medida da degradação (ou melhora) da URL
+	Change float64
+}
+
+// BuildTrends divide o período coberto pelos resultados em width intervalos iguais e calcula a
+// tendência de cada URL, na ordem em que elas aparecem pela primeira vez
+func BuildTrends(results []pool.Result, width int) (trends []Trend, from, to time.Time) {
+	if len(results) == 0 || width < 1 {
+		return nil, from, to
+	}
+	from, to = results[0].Timestamp, results[0].Timestamp
+	var order []string
+	byURL := make(map[string][]pool.Result)
+	for _, r := range results {
+		if r.Timestamp.Before(from) {
+			from = r.Timestamp
+		}
+		if r.Timestamp.After(to) {
+			to = r.Timestamp
+		}
+		if _, ok := byURL[r.URL]; !ok {
+			order = append(order, r.URL)
+		}
+		byURL[r.URL] = append(byURL[r.URL], r)
+	}
+	span := to.Sub(from)
+	bucket := func(at time.Time) int {
+		if span <= 0 {
+			return width - 1
+		}
+		i := int(float64(at.Sub(from)) / float64(span) * float64(width))
+		return min(i, width-1)
+	}
+
+	for _, url := range order {
+		trend := Trend{URL: url, Points: make([]time.Duration, width)}
+		buckets := make([][]time.Duration, width)
+		var all []time.Duration
+		// O primeiro e o último terço do período, para a variação
+		var first, last []time.Duration
+		for _, r := range byURL[url] {
+			if r.Err != nil {
+				trend.Errors++
+				continue
+			}
+			i := bucket(r.Timestamp)
+			buckets[i] = append(buckets[i], r.TimeTooked)
+			all = append(all, r.TimeTooked)
+			switch {
+			case i < width/3:
+				first = append(first, r.TimeTooked)
+			case i >= width-width/3:
+				last = append(last, r.TimeTooked)
+			}
+		}
+		for i, b := range buckets {
+			trend.Points[i] = -1
+			if len(b) > 0 {
+				trend.Points[i] = medianDuration(b)
+			}
+		}
+		trend.Samples = len(all)
+		if len(all) > 0 {
+			sort.Slice(all, func(i, j int) bool { return all[i] < all[j] })
+			trend.Min, trend.Max, trend.Median = all[0], all[len(all)-1], medianDuration(all)
+		}
+		if len(first) > 0 && len(last) > 0 {
+			before, after := medianDuration(first), medianDuration(last)
+			trend.Change = (float64(after) - float64(before)) / float64(before) * 100
+		}
+		trends = append(trends, trend)
+	}
+	return trends, from, to
+}
+
+func medianDuration(values []time.Duration) time.Duration {
+	sorted := append([]time.Duration(nil), values...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	n := len(sorted)
+	if n%2 == 1 {
+		return sorted[n/2]
+	}
+	return (sorted[n/2-1] + sorted[n/2]) / 2
+}
+
+var sparkBlocks = []rune("▁▂▃▄▅▆▇█")
+
+// Sparkline desenha os pontos da tendência com blocos de alturas diferentes, na escala entre a menor
+// e a maior mediana da URL; intervalos sem resposta ficam em branco
+func (t Trend) Sparkline() string {
+	low, high := t.scale()
+	var b strings.Builder
+	for _, p := range t.Points {
+		switch {
+		case p < 0:
+			b.WriteRune(' ')
+		case high == low:
+			b.WriteRune(sparkBlocks[0])
+		default:
+			level := int(float64(p-low) / float64(high-low) * float64(len(sparkBlocks)-1))
+			b.WriteRune(sparkBlocks[level])
+		}
+	}
+	return b.String()
+}
+
+// scale devolve a menor e a maior mediana dos intervalos
+func (t Trend) scale() (low, high time.Duration) {
+	low = -1
+	for _, p := range t.Points {
+		if p < 0 {
+			continue
+		}
+		if low < 0 || p < low {
+			low = p
+		}
+		if p > high {
+			high = p
+		}
+	}
+	return low, high
+}
+
+// WriteTrends imprime uma linha por URL com a sparkline, as latências e a variação no período
+func WriteTrends(w io.Writer, trends []Trend, from, to time.Time) {
+	fmt.Fprintf(w, "Latency trend from %s to %s\n", from.Format("2006-01-02 15:04:05"), to.Format("2006-01-02 15:04:05"))
+	for _, t := range trends {
+		fmt.Fprintf(w, "%-50s %s", t.URL, t.Sparkline())
+		if t.Samples == 0 {
+			fmt.Fprintf(w, "  no successful result, %d error(s)\n", t.Errors)
+			continue
+		}
+		fmt.Fprintf(w, "  min %s, median %s, max %s, %+.0f%%", t.Min.Round(time.Millisecond/10), t.Median.Round(time.Millisecond/10), t.Max.Round(time.Millisecond/10), t.Change)
+		if t.Errors > 0 {
+			fmt.Fprintf(w, ", %d error(s)", t.Errors)
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// Dimensões de cada linha do SVG
+const (
+	svgLabelWidth = 420
+	svgPlotWidth  = 400
+	svgRowHeight  = 40
+)
+
+// svgRow é uma linha do SVG: o rótulo da URL e a polilinha da tendência
+type svgRow struct {
+	Y      int
+	URL    string
+	Stats  string
+	Points string
+}
+
+var trendTemplate = template.Must(template.New("trend").Parse(`<svg xmlns="http://www.w3.org/2000/svg" width="{{.Width}}" height="{{.Height}}" font-family="sans-serif" font-size="12">
+<text x="4" y="16" font-weight="bold">{{.Title}}</text>
+{{range .Rows}}<g transform="translate(0,{{.Y}})">
+<text x="4" y="16">{{.URL}}</text>
+<text x="4" y="32" fill="#666">{{.Stats}}</text>
+<polyline fill="none" stroke="#2563eb" stroke-width="1.5" points="{{.Points}}"/>
+</g>
+{{end}}</svg>
+`))
+
+// WriteTrendSVG grava as tendências como um SVG, com uma linha por URL
+func WriteTrendSVG(path string, trends []Trend, from, to time.Time) error {
+	var rows []svgRow
+	for i, t := range trends {
+		row := svgRow{Y: 24 + i*svgRowHeight, URL: t.URL}
+		if t.Samples > 0 {
+			row.Stats = fmt.Sprintf("median %s, %+.0f%%", t.Median.Round(time.Millisecond/10), t.Change)
+		}
+		low, high := t.scale()
+		var points []string
+		for j, p := range t.Points {
+			if p < 0 {
+				continue
+			}
+			y := float64(svgRowHeight-6) / 2
+			if high > low {
+				// Latências maiores ficam mais acima, como na sparkline
+				y = float64(svgRowHeight-6) * (1 - float64(p-low)/float64(high-low))
+			}
+			x := svgLabelWidth + float64(j)*svgPlotWidth/float64(max(len(t.Points)-1, 1))
+			points = append(points, fmt.Sprintf("%.1f,%.1f", x, y+3))
+		}
+		row.Points = strings.Join(points, " ")
+		rows = append(rows, row)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	err = trendTemplate.Execute(f, map[string]interface{}{
+		"Width":  svgLabelWidth + svgPlotWidth + 10,
+		"Height": 24 + len(rows)*svgRowHeight,
+		"Title":  fmt.Sprintf("Latency trend from %s to %s", from.Format("2006-01-02 15:04:05"), to.Format("2006-01-02 15:04:05")),
+		"Rows":   rows,
+	})
+	if err != nil {
+		return err
+	}
+	return f.Close()
+}
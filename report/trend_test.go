@@ -0,0 +1,161 @@
+package report
+
+import (
+	"errors"
+	"math"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+var trendStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func at(url string, minute int, latency time.Duration) pool.Result {
+	return pool.Result{URL: url, Timestamp: trendStart.Add(time.Duration(minute) * time.Minute), TimeTooked: latency}
+}
+
+func TestBuildTrends(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		name    string
+		results []pool.Result
+		width   int
+		want    []Trend
+	}{
+		{
+			// Período de 0 a 6 minutos em 3 intervalos de 2 minutos; o último instante cai no último intervalo
+			name:  "buckets and change",
+			width: 3,
+			results: []pool.Result{
+				at("a", 0, 10*ms), at("a", 1, 30*ms),
+				at("a", 3, 40*ms),
+				at("a", 5, 50*ms), at("a", 6, 70*ms),
+			},
+			want: []Trend{{URL: "a", Points: []time.Duration{20 * ms, 40 * ms, 60 * ms}, Samples: 5, Min: 10 * ms, Median: 40 * ms, Max: 70 * ms, Change: 200}},
+		},
+		{
+			name:  "gaps and errors",
+			width: 6,
+			results: []pool.Result{
+				at("a", 0, 100*ms),
+				{URL: "a", Timestamp: trendStart.Add(3 * time.Minute), Err: errors.New("timeout")},
+				at("b", 2, 5*ms),
+				at("a", 6, 50*ms),
+			},
+			// Os intervalos sem resposta bem-sucedida ficam com -1; a URL b tem um único ponto, então
+			// não há como calcular a variação
+			want: []Trend{
+				{URL: "a", Points: []time.Duration{100 * ms, -1, -1, -1, -1, 50 * ms}, Errors: 1, Samples: 2, Min: 50 * ms, Median: 75 * ms, Max: 100 * ms, Change: -50},
+				{URL: "b", Points: []time.Duration{-1, -1, 5 * ms, -1, -1, -1}, Samples: 1, Min: 5 * ms, Median: 5 * ms, Max: 5 * ms},
+			},
+		},
+		{
+			// Todos os resultados no mesmo instante: span == 0 coloca tudo no último intervalo
+			name:    "zero span",
+			width:   4,
+			results: []pool.Result{at("a", 1, 10*ms), at("a", 1, 20*ms)},
+			want:    []Trend{{URL: "a", Points: []time.Duration{-1, -1, -1, 15 * ms}, Samples: 2, Min: 10 * ms, Median: 15 * ms, Max: 20 * ms}},
+		},
+		{
+			name:    "only errors",
+			width:   2,
+			results: []pool.Result{{URL: "a", Timestamp: trendStart, Err: errors.New("refused")}},
+			want:    []Trend{{URL: "a", Points: []time.Duration{-1, -1}, Errors: 1}},
+		},
+		{name: "no results", width: 3},
+		{name: "invalid width", width: 0, results: []pool.Result{at("a", 0, ms)}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			trends, _, _ := BuildTrends(tt.results, tt.width)
+			if len(trends) != len(tt.want) {
+				t.Fatalf("got %d trends, want %d: %+v", len(trends), len(tt.want), trends)
+			}
+			for i, want := range tt.want {
+				got := trends[i]
+				if math.Abs(got.Change-want.Change) > 1e-9 {
+					t.Errorf("%s: Change = %f, want %f", want.URL, got.Change, want.Change)
+				}
+				got.Change = want.Change
+				if !reflect.DeepEqual(got, want) {
+					t.Errorf("got %+v, want %+v", got, want)
+				}
+			}
+		})
+	}
+
+	_, from, to := BuildTrends([]pool.Result{at("a", 5, 1), at("b", 2, 1), at("a", 9, 1)}, 3)
+	if !from.Equal(trendStart.Add(2*time.Minute)) || !to.Equal(trendStart.Add(9*time.Minute)) {
+		t.Errorf("period = %s to %s, want the earliest and latest results in any order", from, to)
+	}
+}
+
+func TestSparkline(t *testing.T) {
+	ms := time.Millisecond
+	tests := []struct {
+		points []time.Duration
+		want   string
+	}{
+		{[]time.Duration{10 * ms, 20 * ms, 80 * ms}, "▁▂█"},
+		{[]time.Duration{10 * ms, -1, 10 * ms}, "▁ ▁"},
+		{[]time.Duration{-1, -1}, "  "},
+		{nil, ""},
+	}
+	for _, tt := range tests {
+		if got := (Trend{Points: tt.points}).Sparkline(); got != tt.want {
+			t.Errorf("Sparkline(%v) = %q, want %q", tt.points, got, tt.want)
+		}
+	}
+}
+
+func TestReadCSVResults(t *testing.T) {
+	// Arquivos de versões anteriores têm só as 4 primeiras colunas, ou ainda não têm a espera na
+	// fila; como o sink acrescenta ao arquivo existente, as versões podem se misturar
+	csv := strings.Join([]string{
+		"timestamp,url,time_tooked_ms,error",
+		"2024-01-01T00:00:00Z,http://a,12.500,",
+		"2024-01-01T00:01:00Z,http://a,0.000,connection refused",
+		"2024-01-01T00:02:00Z,http://b,3.000,,env=prod;region=eu",
+		"2024-01-01T00:03:00Z,http://b,4.000,,,1.250",
+		"",
+	}, "\n")
+	path := filepath.Join(t.TempDir(), "results.csv")
+	if err := os.WriteFile(path, []byte(csv), 0644); err != nil {
+		t.Fatal(err)
+	}
+	results, err := ReadResults(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 4 {
+		t.Fatalf("got %d results, want 4", len(results))
+	}
+	if r := results[0]; r.URL != "http://a" || r.TimeTooked != 12500*time.Microsecond || r.Err != nil || !r.Timestamp.Equal(trendStart) {
+		t.Errorf("4-column row = %+v", r)
+	}
+	if r := results[1]; r.Err == nil || r.Err.Error() != "connection refused" {
+		t.Errorf("error row = %+v", r)
+	}
+	if r := results[2]; !reflect.DeepEqual(r.Labels, map[string]string{"env": "prod", "region": "eu"}) || r.QueueWait != 0 {
+		t.Errorf("5-column row = %+v", r)
+	}
+	if r := results[3]; r.Labels != nil || r.QueueWait != 1250*time.Microsecond {
+		t.Errorf("6-column row = %+v", r)
+	}
+
+	for _, bad := range []string{
+		"2024-01-01T00:00:00Z,http://a,12",
+		"yesterday,http://a,12,",
+		"2024-01-01T00:00:00Z,http://a,fast,",
+		"2024-01-01T00:00:00Z,http://a,1,,,slow",
+	} {
+		if _, err := readCSVResults(strings.NewReader(bad), "bad.csv"); err == nil || !strings.HasPrefix(err.Error(), "bad.csv:1:") {
+			t.Errorf("readCSVResults(%q) error = %v, want a bad.csv:1 error", bad, err)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/server"
+)
+
+func runServe(args []string) error {
+	var sinkSpecs, origins stringList
+	fs := flag.NewFlagSet("serve", flag.ExitOnError)
+	sinkFlag(fs, &sinkSpecs)
+	labels := labelFlag(fs)
+	addr := fs.String("addr", ":8080", "address the API listens on")
+	qtyWorkers := fs.Int("workers", 8, "number of workers")
+	timeout := fs.Int("timeout", 5, "HTTP client timeout in seconds")
+	grpcAddr := fs.String("grpc-addr", "", "address for the gRPC service (see proto/workerpool.proto); disabled when empty")
+	fs.Var(&origins, "allow-origin", "host of another origin allowed to open the WebSocket (e.g. admin.example.com:443); may be repeated")
+	pc := pacingFlags(fs)
+	probes := probeFlags(fs)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+
+	// O pool é criado uma única vez e atende todas as execuções submetidas
+	p, err := probes.newPool(*qtyWorkers, *timeout)
+	if err != nil {
+		return err
+	}
+	pc.apply(p)
+	defer p.Close()
+
+	srv := server.New(p)
+	srv.AllowedOrigins = origins
+	// Por padrão os resultados ficam apenas na API; com -sink eles também são enviados aos destinos
+	if len(sinkSpecs) > 0 {
+		sinks, err := openSinks(sinkSpecs, labels)
+		if err != nil {
+			return err
+		}
+		srv.Sink = sinks
+	}
+
+	// O gRPC exige HTTP/2; como o serviço é servido sem TLS, é habilitado o HTTP/2 em texto puro (h2c)
+	if *grpcAddr != "" {
+		var protocols http.Protocols
+		protocols.SetUnencryptedHTTP2(true)
+		grpcServer := &http.Server{
+			Addr:      *grpcAddr,
+			Handler:   srv.GRPCHandler(),
+			Protocols: &protocols,
+		}
+		go func() {
+			fmt.Printf("Serving gRPC on %s\n", *grpcAddr)
+			if err := grpcServer.ListenAndServe(); err != nil {
+				fmt.Printf("Error at serving gRPC\nError: %s\n", err.Error())
+			}
+		}()
+	}
+
+	fmt.Printf("Serving API on %s\n", *addr)
+	return http.ListenAndServe(*addr, srv.Handler())
+}
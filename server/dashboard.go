@@ -0,0 +1,188 @@
+package server
+
+import (
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// Dashboard mantém o último resultado de cada URL e serve uma interface web com a utilização dos
+// workers, a profundidade da fila e o ranking de latência, além dos streams de eventos ao vivo
+type Dashboard struct {
+	// AllowedOrigins lista os hosts (como em "painel.exemplo.com:8080") de outras origens que podem
+	// abrir o WebSocket; por padrão apenas páginas servidas pelo próprio host são aceitas
+	AllowedOrigins []string
+
+	pool   *pool.Pool
+	events *broadcaster
+	mux    sync.Mutex
+	latest map[string]pool.Result
+}
+
+// NewDashboard cria o dashboard para o pool informado
+func NewDashboard(p *pool.Pool) *Dashboard {
+	return &Dashboard{
+		pool:   p,
+		events: newBroadcaster(),
+		latest: make(map[string]pool.Result),
+	}
+}
+
+// Record atualiza o ranking com o resultado mais recente de uma URL
+func (d *Dashboard) Record(result pool.Result) {
+	d.mux.Lock()
+	d.latest[result.URL] = result
+	d.mux.Unlock()
+}
+
+// Publish envia o evento para todos os clientes conectados via SSE ou WebSocket
+func (d *Dashboard) Publish(e Event) {
+	d.events.publish(e)
+}
+
+// Leaderboard devolve o último resultado de cada URL, das mais rápidas para as mais lentas
+// As URLs com erro ficam no final
+func (d *Dashboard) Leaderboard() []pool.Result {
+	d.mux.Lock()
+	results := make([]pool.Result, 0, len(d.latest))
+	for _, r := range d.latest {
+		results = append(results, r)
+	}
+	d.mux.Unlock()
+
+	sort.Slice(results, func(i, j int) bool {
+		if (results[i].Err == nil) != (results[j].Err == nil) {
+			return results[i].Err == nil
+		}
+		if results[i].TimeTooked != results[j].TimeTooked {
+			return results[i].TimeTooked < results[j].TimeTooked
+		}
+		return results[i].URL < results[j].URL
+	})
+	return results
+}
+
+// Register adiciona as rotas do dashboard:
+//
+//	GET /             interface web
+//	GET /stats        estatísticas do pool
+//	GET /leaderboard  ranking de latência (?limit=N)
+//	GET /events       stream (Server-Sent Events) dos jobs concluídos (?run=ID)
+//	GET /ws           WebSocket com os eventos e as estatísticas do pool; aceita comandos de pausa
+func (d *Dashboard) Register(mux *http.ServeMux) {
+	mux.HandleFunc("/", d.handleIndex)
+	mux.HandleFunc("/stats", d.handleStats)
+	mux.HandleFunc("/leaderboard", d.handleLeaderboard)
+	mux.HandleFunc("/events", d.handleEvents)
+	mux.HandleFunc("/ws", d.handleWebSocket)
+}
+
+// Handler devolve um http.Handler contendo apenas as rotas do dashboard
+func (d *Dashboard) Handler() http.Handler {
+	mux := http.NewServeMux()
+	d.Register(mux)
+	return mux
+}
+
+func (d *Dashboard) handleIndex(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path != "/" {
+		http.NotFound(w, r)
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write([]byte(dashboardHTML))
+}
+
+func (d *Dashboard) handleStats(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, d.pool.Stats())
+}
+
+func (d *Dashboard) handleLeaderboard(w http.ResponseWriter, r *http.Request) {
+	results := d.Leaderboard()
+	if limit, err := strconv.Atoi(r.URL.Query().Get("limit")); err == nil && limit >= 0 && limit < len(results) {
+		results = results[:limit]
+	}
+	writeJSON(w, http.StatusOK, results)
+}
+
+// dashboardHTML é a interface web; ela consulta /stats e /leaderboard a cada segundo
+const dashboardHTML = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>Entendendo worker pool - dashboard</title>
+<style>
+body { font-family: sans-serif; margin: 2em; }
+.cards { display: flex; gap: 1em; margin-bottom: 2em; }
+.card { border: 1px solid #ccc; border-radius: 4px; padding: 1em; min-width: 10em; }
+.card .value { font-size: 2em; }
+.bar { background: #eee; height: 8px; margin-top: 4px; }
+.bar div { background: #3a7; height: 8px; }
+table { border-collapse: collapse; }
+th, td { border: 1px solid #ccc; padding: 4px 8px; text-align: left; }
+.error { color: #b00; }
+</style>
+</head>
+<body>
+<h1>Worker pool</h1>
+<div class="cards">
+  <div class="card">Busy workers<div class="value" id="busy">-</div><div class="bar"><div id="utilization" style="width: 0"></div></div></div>
+  <div class="card">Queue depth<div class="value" id="queue">-</div></div>
+  <div class="card">Processed jobs<div class="value" id="processed">-</div></div>
+  <div class="card">Pool<div class="value" id="state">-</div><button id="toggle" disabled>Pause</button></div>
+</div>
+<h2>Latency leaderboard</h2>
+<table>
+<thead><tr><th>#</th><th>URL</th><th>Time (ms)</th><th>Error</th></tr></thead>
+<tbody id="leaderboard"></tbody>
+</table>
+<script>
+function cell(row, text, cls) {
+  var td = document.createElement("td");
+  td.textContent = text;
+  if (cls) td.className = cls;
+  row.appendChild(td);
+}
+function refresh() {
+  fetch("stats").then(function (r) { return r.json(); }).then(function (s) {
+    document.getElementById("busy").textContent = s.busy + " / " + s.workers;
+    document.getElementById("utilization").style.width = (100 * s.busy / s.workers) + "%";
+    document.getElementById("queue").textContent = s.queue_depth;
+    document.getElementById("processed").textContent = s.processed;
+  });
+  fetch("leaderboard?limit=50").then(function (r) { return r.json(); }).then(function (results) {
+    var body = document.getElementById("leaderboard");
+    body.innerHTML = "";
+    results.forEach(function (result, i) {
+      var row = document.createElement("tr");
+      cell(row, i + 1);
+      cell(row, result.url);
+      cell(row, result.error ? "" : result.time_tooked_ms.toFixed(1));
+      cell(row, result.error || "", "error");
+      body.appendChild(row);
+    });
+  });
+}
+var paused = false;
+var ws = new WebSocket((location.protocol === "https:" ? "wss://" : "ws://") + location.host + location.pathname.replace(/\/$/, "") + "/ws");
+ws.onmessage = function (msg) {
+  var e = JSON.parse(msg.data);
+  if (e.type !== "stats") return;
+  paused = e.stats.paused;
+  document.getElementById("state").textContent = paused ? "paused" : "running";
+  var toggle = document.getElementById("toggle");
+  toggle.textContent = paused ? "Resume" : "Pause";
+  toggle.disabled = false;
+};
+document.getElementById("toggle").onclick = function () {
+  ws.send(JSON.stringify({action: paused ? "resume" : "pause"}));
+};
+refresh();
+setInterval(refresh, 1000);
+</script>
+</body>
+</html>
+`
@@ -0,0 +1,105 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// Tipos de evento enviados aos clientes conectados
+const (
+	EventResult  = "result"
+	EventRunDone = "run_done"
+	EventStats   = "stats"
+)
+
+// Event é a mensagem enviada aos clientes que acompanham o servidor ao vivo
+type Event struct {
+	Type   string       `json:"type"`
+	RunID  string       `json:"run_id,omitempty"`
+	Result *pool.Result `json:"result,omitempty"`
+	Stats  *pool.Stats  `json:"stats,omitempty"`
+}
+
+// broadcaster distribui os eventos para todos os inscritos. Clientes lentos não bloqueiam os
+// workers: caso o buffer do inscrito esteja cheio, o evento é descartado para ele
+type broadcaster struct {
+	mux  sync.Mutex
+	subs map[chan Event]struct{}
+}
+
+func newBroadcaster() *broadcaster {
+	return &broadcaster{subs: make(map[chan Event]struct{})}
+}
+
+func (b *broadcaster) subscribe() chan Event {
+	ch := make(chan Event, 64)
+	b.mux.Lock()
+	b.subs[ch] = struct{}{}
+	b.mux.Unlock()
+	return ch
+}
+
+func (b *broadcaster) unsubscribe(ch chan Event) {
+	b.mux.Lock()
+	delete(b.subs, ch)
+	b.mux.Unlock()
+}
+
+func (b *broadcaster) publish(e Event) {
+	b.mux.Lock()
+	defer b.mux.Unlock()
+	for ch := range b.subs {
+		select {
+		case ch <- e:
+		default:
+		}
+	}
+}
+
+// handleEvents transmite os eventos via Server-Sent Events. O parâmetro ?run=ID limita o stream
+// aos eventos de uma execução
+func (d *Dashboard) handleEvents(w http.ResponseWriter, r *http.Request) {
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		writeError(w, http.StatusInternalServerError, "streaming not supported")
+		return
+	}
+	runID := r.URL.Query().Get("run")
+
+	events := d.events.subscribe()
+	defer d.events.unsubscribe(events)
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	w.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	// Comentários periódicos evitam que proxies encerrem a conexão ociosa
+	heartbeat := time.NewTicker(15 * time.Second)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(w, ": heartbeat\n\n")
+		case e := <-events:
+			if runID != "" && e.RunID != runID {
+				continue
+			}
+			data, err := json.Marshal(e)
+			if err != nil {
+				continue
+			}
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", e.Type, data)
+		}
+		flusher.Flush()
+	}
+}
@@ -0,0 +1,216 @@
+package server
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/grpcwire"
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// grpcService é o nome completo do serviço definido em proto/workerpool.proto
+const grpcService = "/workerpool.v1.WorkerPool/"
+
+// grpcHealthCheck é o método da API padrão de health check do gRPC, respondido para que o serviço
+// possa ser verificado por balanceadores, Kubernetes e pelo próprio modo de medição grpc://
+const grpcHealthCheck = "/grpc.health.v1.Health/Check"
+
+// GRPCHandler devolve o handler do serviço gRPC WorkerPool. Ele deve ser servido com HTTP/2
+// (em texto puro ou TLS), como exigido pelo protocolo
+func (s *Server) GRPCHandler() http.Handler {
+	return http.HandlerFunc(s.handleGRPC)
+}
+
+func (s *Server) handleGRPC(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "POST" || !strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+		http.Error(w, "gRPC requests only", http.StatusUnsupportedMediaType)
+		return
+	}
+	w.Header().Set("Content-Type", "application/grpc")
+	w.WriteHeader(http.StatusOK)
+
+	// Todos os métodos recebem uma única mensagem
+	req, err := grpcwire.ReadMessage(r.Body)
+	if err != nil {
+		grpcStatus(w, grpcwire.CodeInvalidArgument, "invalid request message")
+		return
+	}
+
+	if r.URL.Path == grpcHealthCheck {
+		var service string
+		err := grpcwire.Decode(req, func(f grpcwire.Field) {
+			if f.Number == 1 {
+				service = f.String()
+			}
+		})
+		if err != nil {
+			invalidMessage(w, err)
+			return
+		}
+		if service != "" && service != strings.Trim(grpcService, "/") {
+			grpcStatus(w, grpcwire.CodeNotFound, "unknown service "+service)
+			return
+		}
+		var resp grpcwire.Encoder
+		resp.Int64(1, 1) // SERVING
+		grpcwire.WriteMessage(w, resp.Bytes())
+		grpcStatus(w, grpcwire.CodeOK, "")
+		return
+	}
+
+	switch strings.TrimPrefix(r.URL.Path, grpcService) {
+	case "SubmitJobs":
+		var urls []string
+		err := grpcwire.Decode(req, func(f grpcwire.Field) {
+			if f.Number == 1 {
+				urls = append(urls, f.String())
+			}
+		})
+		if err != nil {
+			invalidMessage(w, err)
+			return
+		}
+		if len(urls) == 0 {
+			grpcStatus(w, grpcwire.CodeInvalidArgument, "no URLs submitted")
+			return
+		}
+		run := s.Submit(urls)
+		var resp grpcwire.Encoder
+		resp.String(1, run.ID)
+		resp.Int64(2, int64(run.Total))
+		grpcwire.WriteMessage(w, resp.Bytes())
+		grpcStatus(w, grpcwire.CodeOK, "")
+	case "GetStats":
+		grpcwire.WriteMessage(w, encodeStats(s.pool.Stats()))
+		grpcStatus(w, grpcwire.CodeOK, "")
+	case "StreamResults":
+		s.streamResultsGRPC(w, r, req)
+	default:
+		grpcStatus(w, grpcwire.CodeUnimplemented, "unknown method "+r.URL.Path)
+	}
+}
+
+// streamResultsGRPC envia cada job concluído até que o cliente cancele a chamada ou, quando um
+// run_id é informado, até que a execução termine
+func (s *Server) streamResultsGRPC(w http.ResponseWriter, r *http.Request, req []byte) {
+	var runID string
+	err := grpcwire.Decode(req, func(f grpcwire.Field) {
+		if f.Number == 1 {
+			runID = f.String()
+		}
+	})
+	if err != nil {
+		invalidMessage(w, err)
+		return
+	}
+	flusher, _ := w.(http.Flusher)
+	if runID != "" {
+		s.streamRunGRPC(w, r, flusher, runID)
+		return
+	}
+
+	// Sem run_id, os resultados vêm dos eventos de todas as execuções, que podem ser descartados
+	// para um cliente lento, como no dashboard
+	events := s.dashboard.events.subscribe()
+	defer s.dashboard.events.unsubscribe(events)
+	if flusher != nil {
+		flusher.Flush()
+	}
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e := <-events:
+			if e.Type != EventResult {
+				continue
+			}
+			if err := grpcwire.WriteMessage(w, encodeResult(e.RunID, *e.Result)); err != nil {
+				return
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+}
+
+// runPollInterval é o intervalo com que uma execução transmitida é consultada quando nenhum evento
+// dela chega, já que os eventos podem ser descartados
+const runPollInterval = 100 * time.Millisecond
+
+// streamRunGRPC transmite os resultados de uma execução a partir da própria execução, e não dos
+// eventos: eles só servem para avisar que há resultados novos. Assim nenhum resultado se perde e a
+// chamada termina mesmo que o evento de conclusão seja descartado
+func (s *Server) streamRunGRPC(w http.ResponseWriter, r *http.Request, flusher http.Flusher, runID string) {
+	events := s.dashboard.events.subscribe()
+	defer s.dashboard.events.unsubscribe(events)
+	ticker := time.NewTicker(runPollInterval)
+	defer ticker.Stop()
+
+	sent := 0
+	for {
+		results, done, ok := s.resultsSince(runID, sent)
+		if !ok {
+			grpcStatus(w, grpcwire.CodeNotFound, "run not found")
+			return
+		}
+		for _, result := range results {
+			if err := grpcwire.WriteMessage(w, encodeResult(runID, result)); err != nil {
+				return
+			}
+		}
+		sent += len(results)
+		if done {
+			grpcStatus(w, grpcwire.CodeOK, "")
+			return
+		}
+		if flusher != nil {
+			flusher.Flush()
+		}
+
+		select {
+		case <-r.Context().Done():
+			return
+		case <-events:
+		case <-ticker.C:
+		}
+	}
+}
+
+// invalidMessage encerra a chamada cuja mensagem não pôde ser decodificada, em vez de tratá-la como
+// uma requisição vazia
+func invalidMessage(w http.ResponseWriter, err error) {
+	grpcStatus(w, grpcwire.CodeInvalidArgument, "invalid request message: "+err.Error())
+}
+
+// grpcStatus define os trailers com o resultado da chamada
+func grpcStatus(w http.ResponseWriter, code int, msg string) {
+	w.Header().Set(http.TrailerPrefix+"Grpc-Status", strconv.Itoa(code))
+	if msg != "" {
+		w.Header().Set(http.TrailerPrefix+"Grpc-Message", msg)
+	}
+}
+
+func encodeResult(runID string, result pool.Result) []byte {
+	var e grpcwire.Encoder
+	e.String(1, runID)
+	e.String(2, result.URL)
+	e.Int64(3, int64(result.TimeTooked))
+	if result.Err != nil {
+		e.String(4, result.Err.Error())
+	}
+	e.Int64(5, result.Timestamp.UnixNano())
+	return e.Bytes()
+}
+
+func encodeStats(stats pool.Stats) []byte {
+	var e grpcwire.Encoder
+	e.Int64(1, int64(stats.Workers))
+	e.Int64(2, int64(stats.Busy))
+	e.Int64(3, int64(stats.QueueDepth))
+	e.Int64(4, stats.Processed)
+	e.Bool(5, stats.Paused)
+	return e.Bytes()
+}
@@ -0,0 +1,152 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/grpcwire"
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// callGRPC envia msg ao método e devolve o código e a mensagem dos trailers
+func callGRPC(t *testing.T, s *Server, method string, msg []byte) (int, string) {
+	t.Helper()
+	var body bytes.Buffer
+	grpcwire.WriteMessage(&body, msg)
+	req := httptest.NewRequest("POST", method, &body)
+	req.Header.Set("Content-Type", "application/grpc")
+	rec := httptest.NewRecorder()
+	s.GRPCHandler().ServeHTTP(rec, req)
+	trailer := rec.Result().Trailer
+	code, err := strconv.Atoi(trailer.Get("Grpc-Status"))
+	if err != nil {
+		t.Fatalf("%s: missing grpc-status trailer: %v", method, trailer)
+	}
+	return code, trailer.Get("Grpc-Message")
+}
+
+func TestGRPCRejectsMalformedMessages(t *testing.T) {
+	p := pool.New(1, func(job pool.Job) pool.Result { return pool.Result{URL: job.URL} })
+	defer p.Close()
+	s := New(p)
+
+	// Campo 1 length-delimited anunciando 100 bytes, mas com apenas 3
+	malformed := []byte{0x0a, 0x64, 'a', 'b', 'c'}
+	for _, method := range []string{
+		grpcHealthCheck,
+		grpcService + "SubmitJobs",
+		grpcService + "StreamResults",
+	} {
+		code, msg := callGRPC(t, s, method, malformed)
+		if code != grpcwire.CodeInvalidArgument {
+			t.Errorf("%s: grpc-status = %d, want %d (INVALID_ARGUMENT)", method, code, grpcwire.CodeInvalidArgument)
+		}
+		if !strings.Contains(msg, "invalid request message") {
+			t.Errorf("%s: grpc-message = %q", method, msg)
+		}
+	}
+	if runs := len(s.runs); runs != 0 {
+		t.Errorf("%d runs created from malformed messages, want 0", runs)
+	}
+}
+
+func TestGRPCHealthCheck(t *testing.T) {
+	p := pool.New(1, func(job pool.Job) pool.Result { return pool.Result{URL: job.URL} })
+	defer p.Close()
+	s := New(p)
+
+	var req grpcwire.Encoder
+	req.String(1, strings.Trim(grpcService, "/"))
+	if code, msg := callGRPC(t, s, grpcHealthCheck, req.Bytes()); code != grpcwire.CodeOK {
+		t.Errorf("health check grpc-status = %d (%s), want OK", code, msg)
+	}
+	if code, _ := callGRPC(t, s, grpcHealthCheck, nil); code != grpcwire.CodeOK {
+		t.Errorf("empty health check grpc-status = %d, want OK", code)
+	}
+}
+
+// slowRecorder simula um cliente lento (como um limitado pelo controle de fluxo do HTTP/2), fazendo
+// com que os eventos se acumulem no buffer do inscrito
+type slowRecorder struct {
+	*httptest.ResponseRecorder
+	mux sync.Mutex
+}
+
+func (r *slowRecorder) Write(p []byte) (int, error) {
+	time.Sleep(200 * time.Microsecond)
+	r.mux.Lock()
+	defer r.mux.Unlock()
+	return r.ResponseRecorder.Write(p)
+}
+
+func TestGRPCStreamRunDeliversEveryResult(t *testing.T) {
+	p := pool.New(4, func(job pool.Job) pool.Result { return pool.Result{URL: job.URL, Timestamp: time.Now()} })
+	defer p.Close()
+	s := New(p)
+
+	// Duas execuções simultâneas, cada uma com mais resultados que o buffer de eventos
+	const perRun = 300
+	var wg sync.WaitGroup
+	for _, name := range []string{"a", "b"} {
+		urls := make([]string, perRun)
+		for i := range urls {
+			urls[i] = fmt.Sprintf("http://%s/%d", name, i)
+		}
+		run := s.Submit(urls)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var req grpcwire.Encoder
+			req.String(1, run.ID)
+			var body bytes.Buffer
+			grpcwire.WriteMessage(&body, req.Bytes())
+			httpReq := httptest.NewRequest("POST", grpcService+"StreamResults", &body)
+			httpReq.Header.Set("Content-Type", "application/grpc")
+			rec := &slowRecorder{ResponseRecorder: httptest.NewRecorder()}
+
+			finished := make(chan struct{})
+			go func() {
+				s.GRPCHandler().ServeHTTP(rec, httpReq)
+				close(finished)
+			}()
+			select {
+			case <-finished:
+			case <-time.After(10 * time.Second):
+				t.Errorf("run %s: StreamResults did not end", run.ID)
+				return
+			}
+
+			if code := rec.Result().Trailer.Get("Grpc-Status"); code != "0" {
+				t.Errorf("run %s: grpc-status = %q, want 0", run.ID, code)
+			}
+			seen := make(map[string]bool)
+			for {
+				msg, err := grpcwire.ReadMessage(rec.Body)
+				if err != nil {
+					break
+				}
+				grpcwire.Decode(msg, func(f grpcwire.Field) {
+					if f.Number == 1 && f.String() != run.ID {
+						t.Errorf("run %s: got a result of run %s", run.ID, f.String())
+					}
+					if f.Number == 2 {
+						if seen[f.String()] {
+							t.Errorf("run %s: %s sent twice", run.ID, f.String())
+						}
+						seen[f.String()] = true
+					}
+				})
+			}
+			if len(seen) != perRun {
+				t.Errorf("run %s: got %d results, want %d", run.ID, len(seen), perRun)
+			}
+		}()
+	}
+	wg.Wait()
+}
@@ -0,0 +1,228 @@
+package server
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+	"github.com/joaomarcelofa/entendendo-worker-pool/sink"
+)
+
+// Estados possíveis de uma execução
+const (
+	StatusRunning = "running"
+	StatusDone    = "done"
+)
+
+// Run representa uma lista de URLs submetida ao servidor e os resultados já obtidos
+type Run struct {
+	ID        string        `json:"id"`
+	Status    string        `json:"status"`
+	Submitted time.Time     `json:"submitted"`
+	Finished  *time.Time    `json:"finished,omitempty"`
+	Total     int           `json:"total"`
+	Completed int           `json:"completed"`
+	Errors    int           `json:"errors"`
+	Fastest   *pool.Result  `json:"fastest,omitempty"`
+	URLs      []string      `json:"-"`
+	Results   []pool.Result `json:"-"`
+}
+
+// Server expõe o worker pool através de uma API REST. O pool é de vida longa: cada execução
+// submetida tem seus jobs enfileirados no mesmo pool e processados em segundo plano
+type Server struct {
+	// MaxRuns é a quantidade de execuções mantidas em memória; as mais antigas são descartadas
+	MaxRuns int
+	// Sink, quando definido, recebe cada resultado; Flush é chamado quando uma execução termina
+	// Como as execuções são simultâneas, o sink deve ser seguro para uso concorrente (como sink.Multi)
+	Sink sink.Sink
+	// AllowedOrigins é repassado ao Dashboard (ver Dashboard.AllowedOrigins)
+	AllowedOrigins []string
+
+	pool      *pool.Pool
+	dashboard *Dashboard
+	mux       sync.Mutex
+	runs      map[string]*Run
+	order     []string
+	nextID    int
+}
+
+// New cria um servidor que executa as URLs submetidas no pool informado
+func New(p *pool.Pool) *Server {
+	return &Server{
+		MaxRuns:   100,
+		pool:      p,
+		dashboard: NewDashboard(p),
+		runs:      make(map[string]*Run),
+	}
+}
+
+// Handler devolve o http.Handler com as rotas da API:
+//
+//	POST /runs               submete uma lista de URLs ({"urls": [...]})
+//	GET  /runs               lista as execuções
+//	GET  /runs/{id}          consulta o estado de uma execução
+//	GET  /runs/{id}/results  devolve os resultados obtidos até o momento
+//
+// além das rotas do Dashboard
+func (s *Server) Handler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/runs", s.handleRuns)
+	mux.HandleFunc("/runs/", s.handleRun)
+	s.dashboard.AllowedOrigins = s.AllowedOrigins
+	s.dashboard.Register(mux)
+	return mux
+}
+
+// Submit cria uma nova execução e distribui as URLs para o pool
+func (s *Server) Submit(urls []string) Run {
+	s.mux.Lock()
+	s.nextID++
+	run := &Run{
+		ID:        strconv.Itoa(s.nextID),
+		Status:    StatusRunning,
+		Submitted: time.Now(),
+		Total:     len(urls),
+		URLs:      urls,
+	}
+	s.runs[run.ID] = run
+	s.order = append(s.order, run.ID)
+	// Descarta as execuções mais antigas
+	for len(s.order) > s.MaxRuns {
+		delete(s.runs, s.order[0])
+		s.order = s.order[1:]
+	}
+	snapshot := *run
+	s.mux.Unlock()
+
+	go s.execute(run)
+	return snapshot
+}
+
+func (s *Server) execute(run *Run) {
+	for result := range s.pool.Stream(pool.JobsFromURLs(run.URLs)) {
+		s.dashboard.Record(result)
+		if s.Sink != nil {
+			if err := s.Sink.Write(result); err != nil {
+				log.Printf("Error at writing result for %s: %s", result.URL, err.Error())
+			}
+		}
+		r := result
+		s.dashboard.Publish(Event{Type: EventResult, RunID: run.ID, Result: &r})
+		s.mux.Lock()
+		run.Results = append(run.Results, result)
+		run.Completed++
+		if result.Err != nil {
+			run.Errors++
+		} else if run.Fastest == nil || result.TimeTooked < run.Fastest.TimeTooked {
+			fastest := result
+			run.Fastest = &fastest
+		}
+		s.mux.Unlock()
+	}
+
+	if s.Sink != nil {
+		if err := s.Sink.Flush(); err != nil {
+			log.Printf("Error at flushing results: %s", err.Error())
+		}
+	}
+
+	s.mux.Lock()
+	now := time.Now()
+	run.Status = StatusDone
+	run.Finished = &now
+	s.mux.Unlock()
+	s.dashboard.Publish(Event{Type: EventRunDone, RunID: run.ID})
+}
+
+// get devolve uma cópia da execução, para que possa ser serializada fora do lock
+func (s *Server) get(id string) (Run, bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	run, ok := s.runs[id]
+	if !ok {
+		return Run{}, false
+	}
+	snapshot := *run
+	snapshot.Results = append([]pool.Result(nil), run.Results...)
+	return snapshot, true
+}
+
+// resultsSince devolve os resultados da execução a partir do índice from e se ela já terminou
+func (s *Server) resultsSince(id string, from int) ([]pool.Result, bool, bool) {
+	s.mux.Lock()
+	defer s.mux.Unlock()
+	run, ok := s.runs[id]
+	if !ok {
+		return nil, false, false
+	}
+	return append([]pool.Result(nil), run.Results[min(from, len(run.Results)):]...), run.Status == StatusDone, true
+}
+
+type submitRequest struct {
+	URLs []string `json:"urls"`
+}
+
+func (s *Server) handleRuns(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case "POST":
+		var req submitRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			writeError(w, http.StatusBadRequest, "invalid JSON body: "+err.Error())
+			return
+		}
+		if len(req.URLs) == 0 {
+			writeError(w, http.StatusBadRequest, "no URLs submitted")
+			return
+		}
+		run := s.Submit(req.URLs)
+		w.Header().Set("Location", "/runs/"+run.ID)
+		writeJSON(w, http.StatusAccepted, run)
+	case "GET":
+		s.mux.Lock()
+		runs := make([]Run, 0, len(s.order))
+		for _, id := range s.order {
+			runs = append(runs, *s.runs[id])
+		}
+		s.mux.Unlock()
+		writeJSON(w, http.StatusOK, runs)
+	default:
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+	}
+}
+
+func (s *Server) handleRun(w http.ResponseWriter, r *http.Request) {
+	if r.Method != "GET" {
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	parts := strings.Split(strings.TrimPrefix(r.URL.Path, "/runs/"), "/")
+	run, ok := s.get(parts[0])
+	if !ok {
+		writeError(w, http.StatusNotFound, "run not found")
+		return
+	}
+	switch {
+	case len(parts) == 1:
+		writeJSON(w, http.StatusOK, run)
+	case len(parts) == 2 && parts[1] == "results":
+		writeJSON(w, http.StatusOK, run.Results)
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
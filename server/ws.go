@@ -0,0 +1,118 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/websocket"
+)
+
+// command é a mensagem enviada pelos clientes WebSocket: {"action": "pause"} ou {"action": "resume"}
+type command struct {
+	Action string `json:"action"`
+}
+
+// handleWebSocket transmite os eventos e, a cada segundo, uma fotografia das estatísticas do pool.
+// Os clientes podem pausar e retomar o pool enviando comandos pela mesma conexão
+func (d *Dashboard) handleWebSocket(w http.ResponseWriter, r *http.Request) {
+	// Como o WebSocket pode pausar o pool, uma página de outro site não pode se conectar usando o
+	// navegador de quem acessa o dashboard
+	if !d.allowOrigin(r) {
+		http.Error(w, "origin not allowed", http.StatusForbidden)
+		return
+	}
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	events := d.events.subscribe()
+	defer d.events.unsubscribe(events)
+
+	// As leituras são feitas em uma goroutine separada; quando o cliente desconecta, o canal
+	// done é fechado e o envio de eventos é encerrado
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			opcode, data, err := conn.ReadMessage()
+			if err != nil {
+				return
+			}
+			if opcode != websocket.OpText {
+				continue
+			}
+			var cmd command
+			if err := json.Unmarshal(data, &cmd); err != nil {
+				continue
+			}
+			switch cmd.Action {
+			case "pause":
+				d.pool.Pause()
+			case "resume":
+				d.pool.Resume()
+			default:
+				continue
+			}
+			// Confirma o comando enviando as estatísticas atualizadas
+			d.sendStats(conn)
+		}
+	}()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	d.sendStats(conn)
+	for {
+		select {
+		case <-done:
+			return
+		case <-ticker.C:
+			if d.sendStats(conn) != nil {
+				return
+			}
+		case e := <-events:
+			if sendEvent(conn, e) != nil {
+				return
+			}
+		}
+	}
+}
+
+// allowOrigin aceita as requisições sem Origin (clientes que não são navegadores), as da mesma
+// origem que o dashboard e as de hosts em AllowedOrigins
+func (d *Dashboard) allowOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	if strings.EqualFold(u.Host, r.Host) {
+		return true
+	}
+	for _, allowed := range d.AllowedOrigins {
+		if strings.EqualFold(u.Host, allowed) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d *Dashboard) sendStats(conn *websocket.Conn) error {
+	stats := d.pool.Stats()
+	return sendEvent(conn, Event{Type: EventStats, Stats: &stats})
+}
+
+func sendEvent(conn *websocket.Conn, e Event) error {
+	data, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	return conn.WriteMessage(websocket.OpText, data)
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+func TestWebSocketOrigin(t *testing.T) {
+	p := pool.New(1, func(job pool.Job) pool.Result { return pool.Result{URL: job.URL} })
+	defer p.Close()
+	d := NewDashboard(p)
+	d.AllowedOrigins = []string{"admin.example.com"}
+
+	tests := []struct {
+		origin string
+		allow  bool
+	}{
+		{"", true},
+		{"http://dashboard.local:8080", true},
+		{"https://DASHBOARD.local:8080", true},
+		{"https://admin.example.com", true},
+		{"http://dashboard.local:9090", false},
+		{"https://evil.example", false},
+		{"null", false},
+	}
+	for _, tt := range tests {
+		req := httptest.NewRequest("GET", "http://dashboard.local:8080/ws", nil)
+		if tt.origin != "" {
+			req.Header.Set("Origin", tt.origin)
+		}
+		if got := d.allowOrigin(req); got != tt.allow {
+			t.Errorf("allowOrigin(%q) = %v, want %v", tt.origin, got, tt.allow)
+		}
+	}
+
+	// A origem é verificada antes do upgrade
+	req := httptest.NewRequest("GET", "http://dashboard.local:8080/ws", nil)
+	req.Header.Set("Origin", "https://evil.example")
+	req.Header.Set("Connection", "Upgrade")
+	req.Header.Set("Upgrade", "websocket")
+	req.Header.Set("Sec-WebSocket-Version", "13")
+	req.Header.Set("Sec-WebSocket-Key", "dGhlIHNhbXBsZSBub25jZQ==")
+	rec := httptest.NewRecorder()
+	d.Handler().ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("cross-origin handshake status = %d, want 403", rec.Code)
+	}
+}
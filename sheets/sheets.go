@@ -0,0 +1,100 @@
+package sheets
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/googleauth"
+	"github.com/joaomarcelofa/entendendo-worker-pool/report"
+)
+
+const scope = "https://www.googleapis.com/auth/spreadsheets"
+
+// Append adiciona as linhas ao final da tabela encontrada no intervalo (ex: "Sheet1" ou "Página1!A:F")
+// da planilha informada, através da API do Google Sheets
+func Append(spreadsheetID, sheetRange string, rows [][]interface{}) error {
+	client := &http.Client{Timeout: 30 * time.Second}
+	token, err := googleauth.AccessToken(client, scope)
+	if err != nil {
+		return err
+	}
+
+	body, err := json.Marshal(map[string]interface{}{"values": rows})
+	if err != nil {
+		return err
+	}
+	endpoint := "https://sheets.googleapis.com/v4/spreadsheets/" + url.PathEscape(spreadsheetID) +
+		"/values/" + url.PathEscape(sheetRange) + ":append?valueInputOption=USER_ENTERED&insertDataOption=INSERT_ROWS"
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != 200 {
+		msg, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("sheets API returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// RowsPerRun gera uma linha por método: data, método, tempo total, URL mais rápida, seu tempo,
+// quantidade de URLs visitadas e quantidade de erros
+func RowsPerRun(rep *report.Report) [][]interface{} {
+	var rows [][]interface{}
+	for _, m := range rep.Methods {
+		errorsCount := 0
+		for _, r := range m.Results {
+			if r.Err != nil {
+				errorsCount++
+			}
+		}
+		rows = append(rows, []interface{}{
+			rep.GeneratedAt.Format("2006-01-02 15:04:05"),
+			m.Name,
+			milliseconds(m.Elapsed),
+			m.Fastest.URL,
+			milliseconds(m.Fastest.TimeTooked),
+			len(m.Results),
+			errorsCount,
+		})
+	}
+	return rows
+}
+
+// RowsPerURL gera uma linha por visita: data, método, URL, tempo de resposta e erro
+func RowsPerURL(rep *report.Report) [][]interface{} {
+	var rows [][]interface{}
+	for _, m := range rep.Methods {
+		for _, r := range m.Results {
+			errMsg := ""
+			if r.Err != nil {
+				errMsg = r.Err.Error()
+			}
+			rows = append(rows, []interface{}{
+				rep.GeneratedAt.Format("2006-01-02 15:04:05"),
+				m.Name,
+				r.URL,
+				milliseconds(r.TimeTooked),
+				errMsg,
+			})
+		}
+	}
+	return rows
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
@@ -0,0 +1,256 @@
+package sink
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/mqtt"
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+func init() {
+	Register("stdout", func(string) (Sink, error) { return Stdout{}, nil })
+	Register("json", NewJSONFile)
+	Register("csv", NewCSVFile)
+	Register("webhook", NewWebhook)
+	Register("mqtt", NewMQTT)
+}
+
+// Stdout imprime cada resultado no terminal
+type Stdout struct{}
+
+// Write imprime o resultado no mesmo formato usado desde a primeira versão do projeto
+func (Stdout) Write(result pool.Result) error {
+	if result.Err != nil {
+		fmt.Printf("Error at getting url %s\nError: %s\n", result.URL, result.Err.Error())
+		return nil
+	}
+	if len(result.Details) == 0 {
+		fmt.Printf("Visited %s - Took: %s\n", result.URL, result.TimeTooked)
+		return nil
+	}
+	// Os detalhes da medição são impressos em ordem alfabética, para que a saída seja estável
+	keys := make([]string, 0, len(result.Details))
+	for k := range result.Details {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	details := make([]string, len(keys))
+	for i, k := range keys {
+		details[i] = k + "=" + result.Details[k]
+	}
+	fmt.Printf("Visited %s - Took: %s (%s)\n", result.URL, result.TimeTooked, strings.Join(details, ", "))
+	return nil
+}
+
+// Flush não faz nada, pois a saída padrão não é bufferizada
+func (Stdout) Flush() error {
+	return nil
+}
+
+// JSONFile grava um resultado por linha (JSON Lines), adicionando ao final do arquivo
+type JSONFile struct {
+	file   *os.File
+	writer *bufio.Writer
+}
+
+// NewJSONFile abre (ou cria) o arquivo informado
+func NewJSONFile(path string) (Sink, error) {
+	if path == "" {
+		return nil, errors.New("json sink requires a file path (json:path)")
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &JSONFile{file: f, writer: bufio.NewWriter(f)}, nil
+}
+
+func (s *JSONFile) Write(result pool.Result) error {
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	s.writer.Write(data)
+	return s.writer.WriteByte('\n')
+}
+
+func (s *JSONFile) Flush() error {
+	if err := s.writer.Flush(); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// CSVFile grava os resultados em CSV; o cabeçalho é escrito apenas quando o arquivo está vazio. Os
+// labels ficam no formato chave=valor separados por ponto e vírgula, seguidos da espera na fila
+type CSVFile struct {
+	file   *os.File
+	writer *csv.Writer
+}
+
+// NewCSVFile abre (ou cria) o arquivo informado
+func NewCSVFile(path string) (Sink, error) {
+	if path == "" {
+		return nil, errors.New("csv sink requires a file path (csv:path)")
+	}
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s := &CSVFile{file: f, writer: csv.NewWriter(f)}
+	if info, err := f.Stat(); err == nil && info.Size() == 0 {
+		s.writer.Write([]string{"timestamp", "url", "time_tooked_ms", "error", "labels", "queue_wait_ms"})
+	}
+	return s, nil
+}
+
+func (s *CSVFile) Write(result pool.Result) error {
+	errMsg := ""
+	if result.Err != nil {
+		errMsg = result.Err.Error()
+	}
+	return s.writer.Write([]string{
+		result.Timestamp.Format(time.RFC3339Nano),
+		result.URL,
+		strconv.FormatFloat(float64(result.TimeTooked)/float64(time.Millisecond), 'f', 3, 64),
+		errMsg,
+		labelsText(result.Labels),
+		strconv.FormatFloat(float64(result.QueueWait)/float64(time.Millisecond), 'f', 3, 64),
+	})
+}
+
+// labelsText junta os labels em ordem alfabética, para que a saída seja estável
+func labelsText(labels map[string]string) string {
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + labels[k]
+	}
+	return strings.Join(parts, ";")
+}
+
+func (s *CSVFile) Flush() error {
+	s.writer.Flush()
+	if err := s.writer.Error(); err != nil {
+		return err
+	}
+	return s.file.Sync()
+}
+
+// Webhook acumula os resultados e, a cada Flush (ou a cada 100 resultados), envia um POST com um
+// array JSON para a URL configurada. Um envio que falha mantém o lote, que é reenviado no próximo
+// Flush junto com os novos resultados; com o destino fora do ar por muito tempo, apenas os
+// webhookMaxPending resultados mais recentes são mantidos
+type Webhook struct {
+	URL     string
+	Client  *http.Client
+	pending []pool.Result
+	// written conta os resultados recebidos desde o último envio, para que um destino fora do ar
+	// não seja chamado a cada novo resultado
+	written int
+}
+
+// webhookMaxPending é a quantidade máxima de resultados guardados enquanto os envios falham
+const webhookMaxPending = 10000
+
+// NewWebhook cria o sink para a URL informada
+func NewWebhook(target string) (Sink, error) {
+	if !strings.HasPrefix(target, "http://") && !strings.HasPrefix(target, "https://") {
+		return nil, errors.New("webhook sink requires an http(s) URL (webhook:https://...)")
+	}
+	return &Webhook{URL: target, Client: &http.Client{Timeout: 10 * time.Second}}, nil
+}
+
+func (s *Webhook) Write(result pool.Result) error {
+	s.pending = append(s.pending, result)
+	if len(s.pending) > webhookMaxPending {
+		s.pending = append(s.pending[:0], s.pending[len(s.pending)-webhookMaxPending:]...)
+	}
+	s.written++
+	if s.written >= 100 {
+		return s.Flush()
+	}
+	return nil
+}
+
+func (s *Webhook) Flush() error {
+	if len(s.pending) == 0 {
+		return nil
+	}
+	s.written = 0
+	body, err := json.Marshal(s.pending)
+	if err != nil {
+		return err
+	}
+	resp, err := s.Client.Post(s.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook returned status %d", resp.StatusCode)
+	}
+	// O lote só é descartado depois de aceito pelo destino
+	s.pending = s.pending[:0]
+	return nil
+}
+
+// MQTT publica cada resultado em JSON em um tópico
+type MQTT struct {
+	Client *mqtt.Client
+	Topic  string
+	QoS    byte
+}
+
+// NewMQTT cria o sink a partir de tcp://[usuario:senha@]host:porta/topico?qos=1
+func NewMQTT(target string) (Sink, error) {
+	u, err := url.Parse(target)
+	if err != nil {
+		return nil, err
+	}
+	topic := strings.TrimPrefix(u.Path, "/")
+	if topic == "" {
+		topic = "entendendo-worker-pool/results"
+	}
+	qos := 0
+	if q := u.Query().Get("qos"); q != "" {
+		if qos, err = strconv.Atoi(q); err != nil || qos < 0 || qos > 1 {
+			return nil, fmt.Errorf("unsupported MQTT QoS %q", q)
+		}
+	}
+	u.Path, u.RawQuery = "", ""
+	client, err := mqtt.NewClient(u.String(), fmt.Sprintf("entendendo-worker-pool-%d", os.Getpid()))
+	if err != nil {
+		return nil, err
+	}
+	return &MQTT{Client: client, Topic: topic, QoS: byte(qos)}, nil
+}
+
+func (s *MQTT) Write(result pool.Result) error {
+	payload, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+	return s.Client.Publish(s.Topic, s.QoS, payload)
+}
+
+// Flush não faz nada: cada resultado é publicado imediatamente
+func (s *MQTT) Flush() error {
+	return nil
+}
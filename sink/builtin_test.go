@@ -0,0 +1,66 @@
+package sink
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+func TestWebhookKeepsBatchUntilAccepted(t *testing.T) {
+	status := http.StatusServiceUnavailable
+	var received [][]json.RawMessage
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var batch []json.RawMessage
+		json.NewDecoder(r.Body).Decode(&batch)
+		received = append(received, batch)
+		w.WriteHeader(status)
+	}))
+	defer srv.Close()
+
+	s, err := NewWebhook(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Write(pool.Result{URL: "http://a", TimeTooked: time.Millisecond})
+	s.Write(pool.Result{URL: "http://b", TimeTooked: time.Millisecond})
+	if err := s.Flush(); err == nil {
+		t.Fatal("Flush() = nil with a 503 response, want an error")
+	}
+
+	// O lote recusado é reenviado junto com os novos resultados
+	status = http.StatusNoContent
+	s.Write(pool.Result{URL: "http://c", TimeTooked: time.Millisecond})
+	if err := s.Flush(); err != nil {
+		t.Fatalf("Flush() = %v", err)
+	}
+	if len(received) != 2 || len(received[1]) != 3 {
+		t.Fatalf("batches = %d, last with %d results; want 2 batches, the last with 3", len(received), len(received[len(received)-1]))
+	}
+
+	// Depois de aceito, o lote não é enviado de novo
+	if err := s.Flush(); err != nil || len(received) != 2 {
+		t.Errorf("empty Flush() = %v with %d batches, want no new request", err, len(received))
+	}
+}
+
+func TestWebhookTransportErrorKeepsBatch(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	target := srv.URL
+	srv.Close()
+
+	s, err := NewWebhook(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Write(pool.Result{URL: "http://a"})
+	if err := s.Flush(); err == nil {
+		t.Fatal("Flush() = nil with the server down, want an error")
+	}
+	if n := len(s.(*Webhook).pending); n != 1 {
+		t.Errorf("%d results pending after the failure, want 1", n)
+	}
+}
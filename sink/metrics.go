@@ -0,0 +1,173 @@
+package sink
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+func init() {
+	Register("metrics", NewMetricsFile)
+}
+
+// metricsPrefix é o prefixo do nome de todas as métricas
+const metricsPrefix = "entendendo_worker_pool_"
+
+// durationBuckets são os limites, em segundos, do histograma de latência
+var durationBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// MetricsFile acumula métricas dos resultados (requisições, erros e o histograma de latência por URL
+// e labels) e, a cada Flush, grava um snapshot no formato texto 0.0.4 do Prometheus, para
+// pipelines que importam arquivos (como o textfile collector do node_exporter) em vez de fazer scrape.
+// O arquivo é substituído por inteiro a cada gravação, então quem o lê nunca vê um snapshot pela metade
+type MetricsFile struct {
+	path    string
+	started time.Time
+	series  map[string]*metricSeries
+}
+
+// metricSeries são os valores de uma combinação de URL e labels
+type metricSeries struct {
+	labels   string
+	requests uint64
+	errors   uint64
+	sum      float64
+	buckets  []uint64
+	last     float64
+	lastAt   time.Time
+}
+
+// NewMetricsFile cria o sink para o arquivo informado; o arquivo só é escrito no primeiro Flush
+func NewMetricsFile(path string) (Sink, error) {
+	if path == "" {
+		return nil, errors.New("metrics sink requires a file path (metrics:path)")
+	}
+	return &MetricsFile{path: path, started: time.Now(), series: make(map[string]*metricSeries)}, nil
+}
+
+func (s *MetricsFile) Write(result pool.Result) error {
+	labels := metricLabels(result)
+	series, ok := s.series[labels]
+	if !ok {
+		series = &metricSeries{labels: labels, buckets: make([]uint64, len(durationBuckets))}
+		s.series[labels] = series
+	}
+	series.requests++
+	if result.Err != nil {
+		series.errors++
+		return nil
+	}
+	seconds := result.TimeTooked.Seconds()
+	series.sum += seconds
+	for i, le := range durationBuckets {
+		if seconds <= le {
+			series.buckets[i]++
+		}
+	}
+	series.last, series.lastAt = seconds, result.Timestamp
+	return nil
+}
+
+func (s *MetricsFile) Flush() error {
+	keys := make([]string, 0, len(s.series))
+	for k := range s.series {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".metrics-*")
+	if err != nil {
+		return err
+	}
+	w := bufio.NewWriter(tmp)
+	family := func(name, kind, help string) {
+		fmt.Fprintf(w, "# HELP %s%s %s\n# TYPE %s%s %s\n", metricsPrefix, name, help, metricsPrefix, name, kind)
+	}
+	// No formato 0.0.4, lido pelo textfile collector, a família tem o mesmo nome das amostras, então
+	// os counters são declarados com o sufixo _total (ao contrário do OpenMetrics)
+	family("requests_total", "counter", "Requests made, including the failed ones.")
+	for _, k := range keys {
+		fmt.Fprintf(w, "%srequests_total{%s} %d\n", metricsPrefix, k, s.series[k].requests)
+	}
+	family("request_errors_total", "counter", "Requests that failed.")
+	for _, k := range keys {
+		fmt.Fprintf(w, "%srequest_errors_total{%s} %d\n", metricsPrefix, k, s.series[k].errors)
+	}
+	family("request_duration_seconds", "histogram", "Latency of the successful requests.")
+	for _, k := range keys {
+		series := s.series[k]
+		for i, le := range durationBuckets {
+			fmt.Fprintf(w, "%srequest_duration_seconds_bucket{%s,le=\"%s\"} %d\n", metricsPrefix, k, formatFloat(le), series.buckets[i])
+		}
+		ok := series.requests - series.errors
+		fmt.Fprintf(w, "%srequest_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", metricsPrefix, k, ok)
+		fmt.Fprintf(w, "%srequest_duration_seconds_sum{%s} %s\n", metricsPrefix, k, formatFloat(series.sum))
+		fmt.Fprintf(w, "%srequest_duration_seconds_count{%s} %d\n", metricsPrefix, k, ok)
+	}
+	family("last_request_duration_seconds", "gauge", "Latency of the last successful request.")
+	for _, k := range keys {
+		if series := s.series[k]; !series.lastAt.IsZero() {
+			fmt.Fprintf(w, "%slast_request_duration_seconds{%s} %s\n", metricsPrefix, k, formatFloat(series.last))
+		}
+	}
+	// O horário do snapshot permite alertar quando o arquivo para de ser atualizado
+	family("run_start_timestamp_seconds", "gauge", "Unix time the run started.")
+	fmt.Fprintf(w, "%srun_start_timestamp_seconds %d\n", metricsPrefix, s.started.Unix())
+	family("snapshot_timestamp_seconds", "gauge", "Unix time this snapshot was written.")
+	fmt.Fprintf(w, "%ssnapshot_timestamp_seconds %d\n", metricsPrefix, time.Now().Unix())
+
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	// CreateTemp cria o arquivo com permissão 0600, o que impediria a leitura por um coletor que roda
+	// com outro usuário
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), s.path)
+}
+
+var invalidLabelChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// metricLabels monta os labels da série: a URL e os labels da execução, em ordem alfabética
+func metricLabels(result pool.Result) string {
+	keys := make([]string, 0, len(result.Labels))
+	for k := range result.Labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := []string{`url="` + escapeLabel(result.URL) + `"`}
+	for _, k := range keys {
+		name := invalidLabelChars.ReplaceAllString(k, "_")
+		// Os nomes de label não podem começar com número nem coincidir com os usados pelas métricas
+		if name == "url" || name == "le" || (name[0] >= '0' && name[0] <= '9') {
+			name = "label_" + name
+		}
+		parts = append(parts, name+`="`+escapeLabel(result.Labels[k])+`"`)
+	}
+	return strings.Join(parts, ",")
+}
+
+func escapeLabel(v string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
@@ -0,0 +1,64 @@
+package sink
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+func TestMetricsFileIsPrometheusText(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "run.prom")
+	s, err := NewMetricsFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels := map[string]string{"env": "staging"}
+	s.Write(pool.Result{URL: "http://a", TimeTooked: 20 * time.Millisecond, Timestamp: time.Now(), Labels: labels})
+	s.Write(pool.Result{URL: "http://a", Err: errors.New("timeout"), Labels: labels})
+	if err := s.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := string(data)
+
+	for _, want := range []string{
+		"# TYPE entendendo_worker_pool_requests_total counter\n",
+		"# TYPE entendendo_worker_pool_request_errors_total counter\n",
+		`entendendo_worker_pool_requests_total{url="http://a",env="staging"} 2` + "\n",
+		`entendendo_worker_pool_request_errors_total{url="http://a",env="staging"} 1` + "\n",
+		`entendendo_worker_pool_request_duration_seconds_bucket{url="http://a",env="staging",le="0.025"} 1` + "\n",
+		`entendendo_worker_pool_request_duration_seconds_count{url="http://a",env="staging"} 1` + "\n",
+	} {
+		if !strings.Contains(text, want) {
+			t.Errorf("snapshot is missing %q", want)
+		}
+	}
+	// No formato 0.0.4 cada amostra pertence à última família declarada com o mesmo nome (ou, nos
+	// histogramas, com o nome sem o sufixo _bucket, _sum ou _count)
+	family := ""
+	for _, line := range strings.Split(strings.TrimSuffix(text, "\n"), "\n") {
+		if fields := strings.Fields(line); len(fields) == 4 && fields[1] == "TYPE" {
+			family = fields[2]
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		name, _, _ := strings.Cut(strings.Fields(line)[0], "{")
+		base := strings.TrimSuffix(strings.TrimSuffix(strings.TrimSuffix(name, "_bucket"), "_sum"), "_count")
+		if name != family && base != family {
+			t.Errorf("sample %q does not belong to the declared family %q", name, family)
+		}
+	}
+	if strings.Contains(text, "# EOF") {
+		t.Error("snapshot ends with the OpenMetrics # EOF marker")
+	}
+}
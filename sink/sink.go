@@ -0,0 +1,128 @@
+package sink
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// Sink é um destino para os resultados. Write recebe cada resultado assim que ele fica pronto e
+// Flush é chamado ao final de uma execução (ou periodicamente nos modos contínuos) para que os dados
+// acumulados sejam gravados ou enviados
+type Sink interface {
+	Write(result pool.Result) error
+	Flush() error
+}
+
+// Factory cria um sink a partir do destino informado na especificação (o que vem depois de "nome:")
+type Factory func(target string) (Sink, error)
+
+var (
+	registryMux sync.Mutex
+	registry    = make(map[string]Factory)
+)
+
+// Register associa um nome a uma Factory, permitindo que o sink seja usado com Open("nome:destino")
+func Register(name string, factory Factory) {
+	registryMux.Lock()
+	defer registryMux.Unlock()
+	registry[name] = factory
+}
+
+// Names devolve os nomes registrados, em ordem alfabética
+func Names() []string {
+	registryMux.Lock()
+	defer registryMux.Unlock()
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Open cria um sink a partir de uma especificação no formato "nome" ou "nome:destino"
+// (ex: "stdout", "json:resultados.jsonl", "webhook:https://example.com/hook")
+func Open(spec string) (Sink, error) {
+	name, target := spec, ""
+	if i := strings.Index(spec, ":"); i >= 0 {
+		name, target = spec[:i], spec[i+1:]
+	}
+	registryMux.Lock()
+	factory, ok := registry[name]
+	registryMux.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("unknown sink %q (available: %s)", name, strings.Join(Names(), ", "))
+	}
+	return factory(target)
+}
+
+// Multi distribui cada resultado para vários sinks. As chamadas são serializadas por um mutex,
+// então os sinks individuais não precisam ser seguros para uso concorrente
+type Multi struct {
+	// Labels, quando informados, são anexados a cada resultado antes da distribuição; os labels que
+	// o resultado já traz (ex: vindos de um agente remoto) têm precedência
+	Labels map[string]string
+
+	mux   sync.Mutex
+	sinks []Sink
+}
+
+// OpenAll abre todos os sinks das especificações informadas
+func OpenAll(specs []string) (*Multi, error) {
+	m := &Multi{}
+	for _, spec := range specs {
+		s, err := Open(spec)
+		if err != nil {
+			return nil, err
+		}
+		m.Add(s)
+	}
+	return m, nil
+}
+
+// Add inclui um sink
+func (m *Multi) Add(s Sink) {
+	m.mux.Lock()
+	m.sinks = append(m.sinks, s)
+	m.mux.Unlock()
+}
+
+// Write envia o resultado para todos os sinks, devolvendo o primeiro erro encontrado
+func (m *Multi) Write(result pool.Result) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	if len(m.Labels) > 0 {
+		labels := make(map[string]string, len(m.Labels)+len(result.Labels))
+		for k, v := range m.Labels {
+			labels[k] = v
+		}
+		for k, v := range result.Labels {
+			labels[k] = v
+		}
+		result.Labels = labels
+	}
+	var first error
+	for _, s := range m.sinks {
+		if err := s.Write(result); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// Flush chama Flush em todos os sinks, devolvendo o primeiro erro encontrado
+func (m *Multi) Flush() error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	var first error
+	for _, s := range m.sinks {
+		if err := s.Flush(); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
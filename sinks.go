@@ -0,0 +1,60 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+	"github.com/joaomarcelofa/entendendo-worker-pool/sink"
+)
+
+// sinkFlag registra a flag -sink, que pode ser repetida para ativar vários destinos, e a flag
+// -metrics-file, um atalho para o sink metrics
+func sinkFlag(fs *flag.FlagSet, specs *stringList) {
+	fs.Var(specs, "sink", fmt.Sprintf("result destination as name[:target], one of: %s; may be repeated", strings.Join(sink.Names(), ", ")))
+	fs.Func("metrics-file", "write a Prometheus text format (0.0.4) snapshot of the run's metrics to this file (e.g. out.prom), rewritten at the end of the run and of every monitor round", func(path string) error {
+		return specs.Set("metrics:" + path)
+	})
+}
+
+// labelFlag registra a flag -label, cujos pares chave=valor são anexados a todos os resultados
+// enviados aos sinks e aos relatórios
+func labelFlag(fs *flag.FlagSet) labelList {
+	labels := make(labelList)
+	fs.Var(labels, "label", "attach key=value to every result sent to the sinks and to the reports (e.g. env=staging); may be repeated")
+	return labels
+}
+
+// openSinks abre os sinks informados, que recebem os resultados com os labels da execução; sem
+// nenhum sink, são utilizados os padrões do modo. O arquivo de métricas não conta como destino dos
+// resultados, então também não substitui os padrões
+func openSinks(specs []string, labels labelList, defaults ...string) (*sink.Multi, error) {
+	destinations := 0
+	for _, spec := range specs {
+		if !strings.HasPrefix(spec, "metrics:") {
+			destinations++
+		}
+	}
+	if destinations == 0 {
+		specs = append(specs, defaults...)
+	}
+	sinks, err := sink.OpenAll(specs)
+	if err != nil {
+		return nil, err
+	}
+	sinks.Labels = labels
+	return sinks, nil
+}
+
+func writeResult(s sink.Sink, result pool.Result) {
+	if err := s.Write(result); err != nil {
+		fmt.Printf("Error at writing result for %s\nError: %s\n", result.URL, err.Error())
+	}
+}
+
+func flushSinks(s sink.Sink) {
+	if err := s.Flush(); err != nil {
+		fmt.Printf("Error at flushing results\nError: %s\n", err.Error())
+	}
+}
@@ -0,0 +1,242 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/discovery"
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+	"github.com/joaomarcelofa/entendendo-worker-pool/urls"
+)
+
+// tagsFlag registra a flag -tags, que restringe a execução às entradas com alguma das tags
+func tagsFlag(fs *flag.FlagSet) *string {
+	return fs.String("tags", "", "only measure the entries with one of these comma separated tags (e.g. critical,static), given as #tag in the URL list file")
+}
+
+// filterTags aplica o filtro da flag -tags, tratando como erro um filtro que não deixa nenhuma URL
+func filterTags(jobs []pool.Job, tags string) ([]pool.Job, error) {
+	wanted := splitTags(tags)
+	filtered := urls.FilterTags(jobs, wanted)
+	if len(wanted) > 0 && len(filtered) == 0 {
+		return nil, fmt.Errorf("no URL tagged %s", strings.Join(wanted, ", "))
+	}
+	return filtered, nil
+}
+
+func splitTags(tags string) []string {
+	var list []string
+	for _, tag := range strings.Split(tags, ",") {
+		if tag = strings.TrimPrefix(strings.TrimSpace(tag), "#"); tag != "" {
+			list = append(list, tag)
+		}
+	}
+	return list
+}
+
+// profileFlag registra a flag -profile, que escolhe o perfil de URLs (ver urls.LoadProfile)
+func profileFlag(fs *flag.FlagSet) *string {
+	usage := fmt.Sprintf("named URL profile to measure, one of: %s; user profiles are <name>.txt files in the user profile directory", strings.Join(urls.Profiles(), ", "))
+	if dir, err := urls.UserProfileDir(); err == nil {
+		usage += " (" + dir + ")"
+	}
+	return fs.String("profile", urls.DefaultProfile, usage)
+}
+
+// profileJobs carrega as entradas do perfil, com as opções de cada uma (timeout, peso e tags)
+func profileJobs(name string) ([]pool.Job, error) {
+	jobs, err := urls.LoadProfile(name)
+	if err != nil {
+		return nil, err
+	}
+	return urls.Expand(jobs)
+}
+
+// profileURLs carrega as URLs do perfil, para os comandos que trabalham apenas com a lista de URLs
+func profileURLs(name string) ([]string, error) {
+	jobs, err := profileJobs(name)
+	if err != nil {
+		return nil, err
+	}
+	return urls.URLs(jobs), nil
+}
+
+// targetSource é uma origem de URLs que pode mudar durante a execução, como a lista remota de
+// -urls-url ou o catálogo de serviços de -discover
+type targetSource interface {
+	Fetch() (jobs []pool.Job, changed bool, err error)
+}
+
+// expandedSource aplica urls.Expand às entradas de uma lista, fixa ou vinda de uma origem dinâmica.
+// Como os destinos dos registros SRV mudam com o tempo, a expansão é refeita a cada consulta, mesmo
+// quando a lista em si não mudou
+type expandedSource struct {
+	// source é a origem da lista; nil para as listas fixas, que ficam em entries
+	source  targetSource
+	entries []pool.Job
+	last    string
+}
+
+func (e *expandedSource) Fetch() ([]pool.Job, bool, error) {
+	changed := false
+	if e.source != nil {
+		entries, sourceChanged, err := e.source.Fetch()
+		if err != nil {
+			return nil, false, err
+		}
+		if sourceChanged {
+			e.entries, changed = entries, true
+		}
+	}
+	jobs, err := urls.Expand(e.entries)
+	if err != nil {
+		return nil, false, err
+	}
+	if key := strings.Join(urls.URLs(jobs), "\n"); key != e.last {
+		e.last, changed = key, true
+	}
+	return jobs, changed, nil
+}
+
+// targetList guarda as flags -profile, -list, -urls-url, -discover e -tags, que definem as URLs
+// medidas por um comando
+type targetList struct {
+	profile  *string
+	list     string
+	urlsURL  string
+	discover string
+	tags     *string
+	// source é a origem das URLs, mantida entre as atualizações (ex: para aproveitar o ETag)
+	source *expandedSource
+}
+
+// targetFlags registra as flags -profile, -list, -urls-url, -discover e -tags
+func targetFlags(fs *flag.FlagSet) *targetList {
+	t := &targetList{profile: profileFlag(fs)}
+	fs.StringVar(&t.list, "list", "", "file with the URLs to measure, one per line with optional options and #tags (overrides -profile)")
+	fs.StringVar(&t.urlsURL, "urls-url", "", "fetch the URLs to measure from this address (JSON list or URL list file), overriding -profile; refreshed every round in monitor mode")
+	fs.StringVar(&t.discover, "discover", "", "measure the healthy instances of a service from Consul, etcd or Kubernetes, e.g. consul://localhost:8500/web?path=/health or kube:///services?namespace=prod (in-cluster service account only; the kubeconfig is not read, so outside a cluster point it at kubectl proxy, e.g. kube://127.0.0.1:8001/services); refreshed every round in monitor mode")
+	t.tags = tagsFlag(fs)
+	return t
+}
+
+// jobs lê a lista informada (ou o perfil escolhido) e aplica o filtro de tags
+func (t *targetList) jobs() ([]pool.Job, error) {
+	given := 0
+	for _, v := range []string{t.list, t.urlsURL, t.discover} {
+		if v != "" {
+			given++
+		}
+	}
+	if given > 1 {
+		return nil, errors.New("only one of -list, -urls-url and -discover may be used")
+	}
+	t.source = &expandedSource{}
+	var err error
+	switch {
+	case t.list != "":
+		t.source.entries, err = urls.ReadFile(t.list)
+	case t.urlsURL != "":
+		t.source.source = urls.NewRemote(t.urlsURL, 10*time.Second)
+	case t.discover != "":
+		t.source.source, err = discovery.Open(t.discover)
+	default:
+		t.source.entries, err = urls.LoadProfile(*t.profile)
+	}
+	if err != nil {
+		return nil, err
+	}
+	jobs, _, err := t.source.Fetch()
+	if err != nil {
+		return nil, err
+	}
+	if t.discover != "" && len(jobs) == 0 {
+		return nil, fmt.Errorf("no healthy instance found at %s", t.discover)
+	}
+	return filterTags(jobs, *t.tags)
+}
+
+// refresh consulta novamente a origem das URLs, informando se a lista mudou; uma lista fixa só muda
+// quando as suas entradas srv:// passam a apontar para outros destinos
+func (t *targetList) refresh() ([]pool.Job, bool, error) {
+	jobs, changed, err := t.source.Fetch()
+	if err != nil || !changed {
+		return nil, false, err
+	}
+	jobs, err = filterTags(jobs, *t.tags)
+	return jobs, err == nil, err
+}
+
+// tagIndex associa cada URL às suas tags, para agrupar os resultados, que trazem apenas a URL
+func tagIndex(jobs []pool.Job) map[string][]string {
+	index := make(map[string][]string)
+	for _, job := range jobs {
+		for _, tag := range job.Tags {
+			if !contains(index[job.URL], tag) {
+				index[job.URL] = append(index[job.URL], tag)
+			}
+		}
+	}
+	return index
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// tagRound acumula os resultados de uma rodada do monitor por tag
+type tagRound map[string]*tagTotals
+
+type tagTotals struct {
+	visits int
+	errors int
+	times  []time.Duration
+}
+
+func (r tagRound) add(tags []string, result pool.Result) {
+	for _, tag := range tags {
+		t := r[tag]
+		if t == nil {
+			t = &tagTotals{}
+			r[tag] = t
+		}
+		t.visits++
+		if result.Err != nil {
+			t.errors++
+			continue
+		}
+		t.times = append(t.times, result.TimeTooked)
+	}
+}
+
+// print mostra uma linha por tag, com a quantidade de URLs da tag que estão em violação
+func (r tagRound) print(index map[string][]string, breached map[string]bool) {
+	tags := make([]string, 0, len(r))
+	for tag := range r {
+		tags = append(tags, tag)
+	}
+	sort.Strings(tags)
+	for _, tag := range tags {
+		t := r[tag]
+		inBreach := 0
+		for url := range breached {
+			if contains(index[url], tag) {
+				inBreach++
+			}
+		}
+		med := "-"
+		if len(t.times) > 0 {
+			med = median(t.times).String()
+		}
+		fmt.Printf("  #%s: %d visit(s), %d error(s), median %s, %d URL(s) in breach\n", tag, t.visits, t.errors, med, inBreach)
+	}
+}
@@ -0,0 +1,36 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// traceHooks monta os hooks do modo -trace, que imprime cada evento do pool com o tempo decorrido
+// desde a criação, para acompanhar como os workers dividem o trabalho e quando a fila enche
+func traceHooks(w io.Writer) pool.Hooks {
+	start := time.Now()
+	var mux sync.Mutex
+	event := func(format string, args ...interface{}) {
+		mux.Lock()
+		defer mux.Unlock()
+		fmt.Fprintf(w, "[trace %10s] %s\n", time.Since(start).Round(time.Microsecond*100), fmt.Sprintf(format, args...))
+	}
+	return pool.Hooks{
+		OnWorkerStart: func(worker int) { event("worker %d started", worker) },
+		OnWorkerStop:  func(worker int) { event("worker %d stopped", worker) },
+		OnWorkerIdle:  func(worker int) { event("worker %d idle, waiting for a job", worker) },
+		OnJobStart:    func(worker int, job pool.Job) { event("worker %d picked %s", worker, job.URL) },
+		OnJobDone: func(worker int, result pool.Result) {
+			if result.Err != nil {
+				event("worker %d failed %s after %s: %v", worker, result.URL, result.TimeTooked, result.Err)
+				return
+			}
+			event("worker %d finished %s in %s", worker, result.URL, result.TimeTooked)
+		},
+		OnQueueFull: func(job pool.Job) { event("queue full, %s waits for a free worker", job.URL) },
+	}
+}
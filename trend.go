@@ -0,0 +1,94 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+	"github.com/joaomarcelofa/entendendo-worker-pool/report"
+)
+
+// runReport agrupa os relatórios gerados a partir dos resultados guardados pelos sinks
+func runReport(args []string) error {
+	sub := ""
+	if len(args) > 0 {
+		sub, args = args[0], args[1:]
+	}
+	switch sub {
+	case "trend", "":
+		return runTrend(args)
+	default:
+		return fmt.Errorf("unknown report %q (available: trend)", sub)
+	}
+}
+
+// runTrend mostra a tendência da latência de cada URL nos resultados guardados pelos sinks json e
+// csv, com uma sparkline por URL, para que degradações lentas fiquem visíveis de relance
+func runTrend(args []string) error {
+	fs := flag.NewFlagSet("report trend", flag.ExitOnError)
+	width := fs.Int("width", 40, "number of points of each sparkline; the period is split into this many intervals")
+	since := fs.Duration("since", 0, "only use the results of this last period (e.g. 168h); 0 uses the whole history")
+	svg := fs.String("svg", "", "also write the trends as an SVG image to this file")
+	labels := make(labelList)
+	fs.Var(labels, "label", "only use the results with this key=value label (see -label in the other commands); may be repeated")
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	if fs.NArg() == 0 {
+		return errors.New("usage: report trend [flags] results.jsonl|results.csv...")
+	}
+	if *width < 2 {
+		return errors.New("-width must be at least 2")
+	}
+
+	var results []pool.Result
+	for _, path := range fs.Args() {
+		read, err := report.ReadResults(path)
+		if err != nil {
+			return err
+		}
+		results = append(results, read...)
+	}
+	results = filterStored(results, labels, *since)
+	if len(results) == 0 {
+		return errors.New("no results to report")
+	}
+
+	trends, from, to := report.BuildTrends(results, *width)
+	report.WriteTrends(os.Stdout, trends, from, to)
+	if *svg != "" {
+		if err := report.WriteTrendSVG(*svg, trends, from, to); err != nil {
+			return err
+		}
+		fmt.Printf("Trend written to %s\n", *svg)
+	}
+	return nil
+}
+
+// filterStored mantém os resultados com todos os labels informados e, com since, os mais recentes
+func filterStored(results []pool.Result, labels labelList, since time.Duration) []pool.Result {
+	cutoff := time.Time{}
+	if since > 0 {
+		cutoff = time.Now().Add(-since)
+	}
+	var kept []pool.Result
+	for _, r := range results {
+		if r.Timestamp.Before(cutoff) {
+			continue
+		}
+		matches := true
+		for k, v := range labels {
+			if r.Labels[k] != v {
+				matches = false
+				break
+			}
+		}
+		if matches {
+			kept = append(kept, r)
+		}
+	}
+	return kept
+}
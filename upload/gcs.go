@@ -0,0 +1,33 @@
+package upload
+
+import (
+	"bytes"
+	"net/http"
+	"net/url"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/googleauth"
+)
+
+const gcsScope = "https://www.googleapis.com/auth/devstorage.read_write"
+
+// putGCS grava o objeto através da API JSON do Cloud Storage (upload simples)
+func putGCS(client *http.Client, bucket, key, contentType string, body []byte) error {
+	token, err := googleauth.AccessToken(client, gcsScope)
+	if err != nil {
+		return err
+	}
+	endpoint := "https://storage.googleapis.com/upload/storage/v1/b/" + url.PathEscape(bucket) +
+		"/o?uploadType=media&name=" + url.QueryEscape(key)
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", contentType)
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp)
+}
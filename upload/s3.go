@@ -0,0 +1,269 @@
+package upload
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const imdsURL = "http://169.254.169.254/latest"
+
+// awsCredentials são as credenciais usadas para assinar as requisições (Signature V4)
+type awsCredentials struct {
+	AccessKeyID     string `json:"AccessKeyId"`
+	SecretAccessKey string `json:"SecretAccessKey"`
+	SessionToken    string `json:"Token"`
+}
+
+// putS3 grava o objeto com um PUT assinado. Um endpoint compatível (ex: MinIO) pode ser informado
+// através de AWS_ENDPOINT_URL_S3 ou AWS_ENDPOINT_URL, e nesse caso é usado o estilo de caminho
+func putS3(client *http.Client, bucket, key, contentType string, body []byte) error {
+	creds, err := loadAWSCredentials(client)
+	if err != nil {
+		return err
+	}
+	region := awsRegion()
+
+	endpoint := os.Getenv("AWS_ENDPOINT_URL_S3")
+	if endpoint == "" {
+		endpoint = os.Getenv("AWS_ENDPOINT_URL")
+	}
+	var target string
+	if endpoint != "" {
+		target = strings.TrimSuffix(endpoint, "/") + "/" + bucket + "/" + awsEscapePath(key)
+	} else {
+		target = "https://" + bucket + ".s3." + region + ".amazonaws.com/" + awsEscapePath(key)
+	}
+
+	req, err := http.NewRequest("PUT", target, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+	signV4(req, body, creds, region, "s3", time.Now().UTC())
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	return checkResponse(resp)
+}
+
+// signV4 adiciona os cabeçalhos de autenticação AWS Signature Version 4
+func signV4(req *http.Request, body []byte, creds awsCredentials, region, service string, now time.Time) {
+	amzDate := now.Format("20060102T150405Z")
+	date := now.Format("20060102")
+	payloadHash := sha256Hex(body)
+
+	req.Header.Set("Host", req.URL.Host)
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", payloadHash)
+	if creds.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	// Cabeçalhos canônicos: nomes em minúsculo, ordenados
+	var names []string
+	for name := range req.Header {
+		names = append(names, strings.ToLower(name))
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + strings.TrimSpace(req.Header.Get(name)) + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		payloadHash,
+	}, "\n")
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + sha256Hex([]byte(canonicalRequest))
+
+	signingKey := hmacSHA256([]byte("AWS4"+creds.SecretAccessKey), date)
+	signingKey = hmacSHA256(signingKey, region)
+	signingKey = hmacSHA256(signingKey, service)
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+creds.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signedHeaders+", Signature="+signature)
+	// O cabeçalho Host é enviado a partir de req.Host
+	req.Header.Del("Host")
+}
+
+// awsEscapePath codifica cada segmento da chave mantendo as barras, como exigido pela assinatura
+func awsEscapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, s := range segments {
+		segments[i] = strings.Replace(url.PathEscape(s), "+", "%2B", -1)
+	}
+	return strings.Join(segments, "/")
+}
+
+func sha256Hex(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// loadAWSCredentials segue a cadeia padrão dos SDKs: variáveis de ambiente, arquivo de credenciais
+// compartilhado e, por fim, o serviço de metadados da instância (IMDSv2)
+func loadAWSCredentials(client *http.Client) (awsCredentials, error) {
+	creds := awsCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.AccessKeyID != "" && creds.SecretAccessKey != "" {
+		return creds, nil
+	}
+
+	path := os.Getenv("AWS_SHARED_CREDENTIALS_FILE")
+	if path == "" {
+		path = awsHomeFile("credentials")
+	}
+	section := readINISection(path, awsProfile())
+	if section["aws_access_key_id"] != "" {
+		return awsCredentials{
+			AccessKeyID:     section["aws_access_key_id"],
+			SecretAccessKey: section["aws_secret_access_key"],
+			SessionToken:    section["aws_session_token"],
+		}, nil
+	}
+
+	return imdsCredentials(client)
+}
+
+func imdsCredentials(client *http.Client) (awsCredentials, error) {
+	var creds awsCredentials
+	noCreds := errors.New("no AWS credentials found")
+
+	// IMDSv2: obtém um token de sessão antes de consultar as credenciais
+	req, _ := http.NewRequest("PUT", imdsURL+"/api/token", nil)
+	req.Header.Set("X-aws-ec2-metadata-token-ttl-seconds", "60")
+	quick := &http.Client{Timeout: 2 * time.Second, Transport: client.Transport}
+	resp, err := quick.Do(req)
+	if err != nil {
+		return creds, noCreds
+	}
+	token, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != 200 || err != nil {
+		return creds, noCreds
+	}
+
+	get := func(path string) ([]byte, error) {
+		req, _ := http.NewRequest("GET", imdsURL+path, nil)
+		req.Header.Set("X-aws-ec2-metadata-token", string(token))
+		resp, err := quick.Do(req)
+		if err != nil {
+			return nil, err
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode != 200 {
+			return nil, noCreds
+		}
+		return io.ReadAll(resp.Body)
+	}
+	role, err := get("/meta-data/iam/security-credentials/")
+	if err != nil {
+		return creds, noCreds
+	}
+	data, err := get("/meta-data/iam/security-credentials/" + strings.TrimSpace(string(role)))
+	if err != nil {
+		return creds, noCreds
+	}
+	err = json.Unmarshal(data, &creds)
+	return creds, err
+}
+
+func awsProfile() string {
+	if profile := os.Getenv("AWS_PROFILE"); profile != "" {
+		return profile
+	}
+	return "default"
+}
+
+func awsRegion() string {
+	for _, name := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if region := os.Getenv(name); region != "" {
+			return region
+		}
+	}
+	path := os.Getenv("AWS_CONFIG_FILE")
+	if path == "" {
+		path = awsHomeFile("config")
+	}
+	// No arquivo config os perfis diferentes do default são declarados como [profile nome]
+	profile := awsProfile()
+	if profile != "default" {
+		profile = "profile " + profile
+	}
+	if region := readINISection(path, profile)["region"]; region != "" {
+		return region
+	}
+	return "us-east-1"
+}
+
+func awsHomeFile(name string) string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".aws", name)
+}
+
+// readINISection lê as chaves de uma seção dos arquivos de configuração da AWS
+func readINISection(path, name string) map[string]string {
+	values := make(map[string]string)
+	f, err := os.Open(path)
+	if err != nil {
+		return values
+	}
+	defer f.Close()
+
+	current := ""
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]") {
+			current = strings.TrimSpace(line[1 : len(line)-1])
+			continue
+		}
+		if current != name {
+			continue
+		}
+		if i := strings.Index(line, "="); i > 0 {
+			values[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+		}
+	}
+	return values
+}
@@ -0,0 +1,61 @@
+package upload
+
+import (
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// File envia o arquivo local para o destino informado, no formato s3://bucket/prefixo/ ou
+// gs://bucket/prefixo/. Caso o destino termine com "/", o nome do arquivo é adicionado ao prefixo
+func File(path, destination string) (string, error) {
+	u, err := url.Parse(destination)
+	if err != nil {
+		return "", err
+	}
+	if u.Host == "" {
+		return "", fmt.Errorf("missing bucket in %q", destination)
+	}
+	key := strings.TrimPrefix(u.Path, "/")
+	if key == "" || strings.HasSuffix(key, "/") {
+		key += filepath.Base(path)
+	}
+
+	body, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	contentType := mime.TypeByExtension(filepath.Ext(path))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+
+	client := &http.Client{Timeout: time.Minute}
+	switch u.Scheme {
+	case "s3":
+		err = putS3(client, u.Host, key, contentType, body)
+	case "gs":
+		err = putGCS(client, u.Host, key, contentType, body)
+	default:
+		return "", fmt.Errorf("unsupported upload scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return "", err
+	}
+	return u.Scheme + "://" + u.Host + "/" + key, nil
+}
+
+// checkResponse converte respostas sem sucesso em erro, incluindo o corpo devolvido pelo serviço
+func checkResponse(resp *http.Response) error {
+	if resp.StatusCode >= 200 && resp.StatusCode <= 299 {
+		return nil
+	}
+	msg, _ := io.ReadAll(resp.Body)
+	return fmt.Errorf("upload returned status %d: %s", resp.StatusCode, strings.TrimSpace(string(msg)))
+}
@@ -0,0 +1,201 @@
+package urls
+
+import (
+	"fmt"
+	"net"
+	"net/netip"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// maxTargets limita quantos alvos uma única entrada pode gerar, para que um erro de digitação como
+// 10.0.0.0/8:80 não crie milhões de jobs
+const maxTargets = 65536
+
+// Expand substitui as entradas que representam vários alvos pelos alvos concretos, mantendo as
+// opções e as tags da entrada em cada um:
+//   - intervalos entre chaves, como http://10.0.0.{1..20}:8080/health ou http://web{01..12}.internal/,
+//     geram uma URL para cada número (com zeros à esquerda, todos os números têm a mesma largura);
+//   - um bloco CIDR seguido da porta, como http://10.0.0.0/28:8080/health, apenas 10.0.0.0/28:8080
+//     ou, em IPv6, http://[fd00::/120]:8080/, gera uma URL para cada endereço de host do bloco (sem
+//     o endereço da rede e o de broadcast);
+//   - srv://_http._tcp.example.com/health gera um job para cada destino do registro SRV, para que as
+//     instâncias de um serviço em cluster sejam medidas individualmente; o serviço _https usa HTTPS
+//     e os demais, HTTP
+func Expand(jobs []pool.Job) ([]pool.Job, error) {
+	expanded := make([]pool.Job, 0, len(jobs))
+	for _, job := range jobs {
+		patterns, err := expandPatterns(job)
+		if err != nil {
+			return nil, err
+		}
+		for _, pattern := range patterns {
+			if !strings.HasPrefix(pattern.URL, "srv://") {
+				expanded = append(expanded, pattern)
+				continue
+			}
+			targets, err := expandSRV(pattern)
+			if err != nil {
+				return nil, err
+			}
+			expanded = append(expanded, targets...)
+		}
+	}
+	return expanded, nil
+}
+
+var (
+	braceRange = regexp.MustCompile(`\{(\d+)\.\.(\d+)\}`)
+	cidrTarget = regexp.MustCompile(`^(\w+://)?\[?([0-9a-fA-F.:]+/\d+)\]?:(\d+)(/.*)?$`)
+)
+
+// expandPatterns expande os intervalos entre chaves e o bloco CIDR de uma entrada. Os intervalos são
+// expandidos primeiro, para que um bloco possa ser combinado a um intervalo de portas
+// (ex: 10.0.0.0/30:{8080..8081})
+func expandPatterns(job pool.Job) ([]pool.Job, error) {
+	urls := []string{job.URL}
+	for braceRange.MatchString(urls[0]) {
+		var next []string
+		for _, u := range urls {
+			loc := braceRange.FindStringSubmatchIndex(u)
+			numbers, err := rangeNumbers(u[loc[2]:loc[3]], u[loc[4]:loc[5]])
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", job.URL, err)
+			}
+			for _, n := range numbers {
+				next = append(next, u[:loc[0]]+n+u[loc[1]:])
+			}
+			if len(next) > maxTargets {
+				return nil, fmt.Errorf("%s: expands to more than %d targets", job.URL, maxTargets)
+			}
+		}
+		urls = next
+	}
+	var targets []string
+	for _, u := range urls {
+		hosts, err := expandCIDR(u)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", job.URL, err)
+		}
+		targets = append(targets, hosts...)
+		if len(targets) > maxTargets {
+			return nil, fmt.Errorf("%s: expands to more than %d targets", job.URL, maxTargets)
+		}
+	}
+	urls = targets
+	if len(urls) == 1 && urls[0] == job.URL {
+		return []pool.Job{job}, nil
+	}
+	jobs := make([]pool.Job, len(urls))
+	for i, u := range urls {
+		jobs[i] = job
+		jobs[i].URL = u
+	}
+	return jobs, nil
+}
+
+// rangeNumbers lista os números de um intervalo {first..last}, que também pode ser decrescente
+func rangeNumbers(first, last string) ([]string, error) {
+	from, err := strconv.Atoi(first)
+	if err != nil {
+		return nil, err
+	}
+	to, err := strconv.Atoi(last)
+	if err != nil {
+		return nil, err
+	}
+	step := 1
+	if to < from {
+		step = -1
+	}
+	if (to-from)*step >= maxTargets {
+		return nil, fmt.Errorf("range {%s..%s} has more than %d numbers", first, last, maxTargets)
+	}
+	width := 0
+	if (len(first) > 1 && first[0] == '0') || (len(last) > 1 && last[0] == '0') {
+		width = max(len(first), len(last))
+	}
+	var numbers []string
+	for n := from; ; n += step {
+		numbers = append(numbers, fmt.Sprintf("%0*d", width, n))
+		if n == to {
+			return numbers, nil
+		}
+	}
+}
+
+// expandCIDR gera uma URL para cada endereço de host de um bloco CIDR; URLs sem bloco são
+// devolvidas como estão
+func expandCIDR(target string) ([]string, error) {
+	m := cidrTarget.FindStringSubmatch(target)
+	if m == nil {
+		return []string{target}, nil
+	}
+	prefix, err := netip.ParsePrefix(m[2])
+	if err != nil {
+		return nil, err
+	}
+	prefix = prefix.Masked()
+	bits := prefix.Addr().BitLen() - prefix.Bits()
+	if bits > 16 {
+		return nil, fmt.Errorf("%s expands to more than %d targets", prefix, maxTargets)
+	}
+	scheme := m[1]
+	if scheme == "" {
+		scheme = "http://"
+	}
+	var urls []string
+	count := 1 << bits
+	addr := prefix.Addr()
+	for i := 0; i < count; i, addr = i+1, addr.Next() {
+		// Em blocos IPv4 com mais de dois endereços, o primeiro é o da rede e o último, o de broadcast
+		if addr.Is4() && count > 2 && (i == 0 || i == count-1) {
+			continue
+		}
+		urls = append(urls, scheme+net.JoinHostPort(addr.String(), m[3])+m[4])
+	}
+	return urls, nil
+}
+
+func expandSRV(job pool.Job) ([]pool.Job, error) {
+	u, err := url.Parse(job.URL)
+	if err != nil {
+		return nil, err
+	}
+	name := u.Hostname()
+	service, _, _ := strings.Cut(name, ".")
+	if !strings.HasPrefix(service, "_") || u.Port() != "" {
+		return nil, fmt.Errorf("invalid SRV entry %s: expected srv://_service._proto.domain/path", job.URL)
+	}
+	_, records, err := net.LookupSRV("", "", name)
+	if err != nil {
+		return nil, err
+	}
+	scheme := "http"
+	if service == "_https" {
+		scheme = "https"
+	}
+	var targets []pool.Job
+	// Os registros já vêm ordenados por prioridade e, dentro dela, pelo peso
+	for _, record := range records {
+		host := strings.TrimSuffix(record.Target, ".")
+		// Um destino "." indica que o serviço não está disponível no domínio
+		if host == "" {
+			continue
+		}
+		target := *u
+		target.Scheme = scheme
+		target.Host = net.JoinHostPort(host, strconv.Itoa(int(record.Port)))
+		instance := job
+		instance.URL = target.String()
+		targets = append(targets, instance)
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no SRV target found for %s", name)
+	}
+	return targets, nil
+}
@@ -0,0 +1,79 @@
+package urls
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		name    string
+		url     string
+		want    []string
+		wantErr string
+	}{
+		{name: "plain URL", url: "http://example.com/", want: []string{"http://example.com/"}},
+		{name: "range", url: "http://10.0.0.{1..3}:8080/health", want: []string{
+			"http://10.0.0.1:8080/health", "http://10.0.0.2:8080/health", "http://10.0.0.3:8080/health",
+		}},
+		{name: "zero padding", url: "http://web{08..10}.internal/", want: []string{
+			"http://web08.internal/", "http://web09.internal/", "http://web10.internal/",
+		}},
+		{name: "descending range", url: "http://h{3..1}/", want: []string{"http://h3/", "http://h2/", "http://h1/"}},
+		{name: "single number range", url: "http://h{5..5}/", want: []string{"http://h5/"}},
+		{name: "combined ranges", url: "http://r{1..2}-n{1..2}/", want: []string{
+			"http://r1-n1/", "http://r1-n2/", "http://r2-n1/", "http://r2-n2/",
+		}},
+		{name: "CIDR skips network and broadcast", url: "http://10.0.0.0/30:8080/health", want: []string{
+			"http://10.0.0.1:8080/health", "http://10.0.0.2:8080/health",
+		}},
+		{name: "/31 keeps both addresses", url: "10.0.0.0/31:80", want: []string{"http://10.0.0.0:80", "http://10.0.0.1:80"}},
+		{name: "/32 is a single host", url: "https://192.168.1.7/32:443/", want: []string{"https://192.168.1.7:443/"}},
+		{name: "unmasked CIDR", url: "http://10.0.0.5/30:80/", want: []string{"http://10.0.0.5:80/", "http://10.0.0.6:80/"}},
+		{name: "IPv6", url: "http://[fd00::/127]:8080/", want: []string{"http://[fd00::]:8080/", "http://[fd00::1]:8080/"}},
+		{name: "CIDR with range", url: "http://10.0.0.0/31:{80..81}/", want: []string{
+			"http://10.0.0.0:80/", "http://10.0.0.1:80/", "http://10.0.0.0:81/", "http://10.0.0.1:81/",
+		}},
+		{name: "block too large", url: "http://10.0.0.0/8:80/", wantErr: "more than 65536 targets"},
+		{name: "range too large", url: "http://h{0..70000}/", wantErr: "more than 65536 numbers"},
+		{name: "ranges multiply past the cap", url: "http://h{1..300}-{1..300}/", wantErr: "expands to more than 65536 targets"},
+		{name: "invalid prefix", url: "http://10.0.0.0/40:80/", wantErr: "10.0.0.0/40"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			jobs, err := Expand([]pool.Job{{URL: tt.url}})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := URLs(jobs); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExpandKeepsOptionsAndTags(t *testing.T) {
+	entry := pool.Job{URL: "http://h{1..2}/", Timeout: 3 * time.Second, Weight: 2, Tags: []string{"api"}}
+	jobs, err := Expand([]pool.Job{entry})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(jobs) != 2 {
+		t.Fatalf("got %d jobs, want 2", len(jobs))
+	}
+	for _, job := range jobs {
+		if job.Timeout != entry.Timeout || job.Weight != entry.Weight || !reflect.DeepEqual(job.Tags, entry.Tags) {
+			t.Errorf("job %+v lost the entry options", job)
+		}
+	}
+}
@@ -0,0 +1,154 @@
+package urls
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// ReadFile lê uma lista de URLs de um arquivo, uma por linha. Linhas em branco e linhas iniciadas
+// por # são ignoradas. Após a URL, cada linha pode trazer opções separadas por vírgula, que valem
+// somente para ela (ver ParseEntry)
+func ReadFile(path string) ([]pool.Job, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return Parse(f, path)
+}
+
+// Parse lê uma lista de URLs no mesmo formato de ReadFile; name identifica a lista nas mensagens de erro
+func Parse(r io.Reader, name string) ([]pool.Job, error) {
+	var jobs []pool.Job
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		job, err := ParseEntry(line)
+		if err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", name, n, err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, scanner.Err()
+}
+
+// ParseEntry interpreta uma entrada da lista, no formato "<url>[, opção=valor...]". As opções são
+// timeout, que substitui o timeout global para a URL, já que um endpoint de download e um de health
+// check têm expectativas bem diferentes (ex: "https://example.com/backup.zip, timeout=30s"), e weight,
+// a frequência relativa com que a URL é amostrada nos modos contínuos (ex: "https://example.com/health, weight=3").
+// No fim da linha, a entrada pode receber tags precedidas por # e separadas por espaço
+// (ex: "https://example.com/health, timeout=2s #critical #api").
+// As opções começam na primeira vírgula seguida de espaço e nome=valor (ou de timeout= e weight=), então
+// as vírgulas da própria URL, como em "https://example.com/?ids=1,2&a=1,b=2", não são confundidas com opções
+func ParseEntry(entry string) (pool.Job, error) {
+	entry, tags, err := cutTags(entry)
+	if err != nil {
+		return pool.Job{}, err
+	}
+	url, options := entry, []string(nil)
+	if loc := optionStart.FindStringIndex(entry); loc != nil {
+		url, options = entry[:loc[0]], strings.Split(entry[loc[0]+1:], ",")
+	}
+	// Uma URL não contém espaços: o que vem depois de ", " sem o formato nome=valor é uma opção inválida
+	if _, option, ok := strings.Cut(url, ", "); ok {
+		return pool.Job{}, fmt.Errorf("invalid option %q: expected name=value", strings.TrimSpace(option))
+	}
+	job := pool.Job{URL: strings.TrimSpace(url)}
+	for _, option := range options {
+		name, value, ok := strings.Cut(strings.TrimSpace(option), "=")
+		if !ok {
+			return pool.Job{}, fmt.Errorf("invalid option %q: expected name=value", strings.TrimSpace(option))
+		}
+		switch strings.TrimSpace(name) {
+		case "timeout":
+			timeout, err := time.ParseDuration(strings.TrimSpace(value))
+			if err != nil {
+				return pool.Job{}, fmt.Errorf("invalid timeout %q: %w", value, err)
+			}
+			if timeout <= 0 {
+				return pool.Job{}, fmt.Errorf("invalid timeout %q: must be greater than zero", value)
+			}
+			job.Timeout = timeout
+		case "weight":
+			weight, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil || weight < 1 {
+				return pool.Job{}, fmt.Errorf("invalid weight %q: expected a whole number greater than zero", value)
+			}
+			job.Weight = weight
+		default:
+			return pool.Job{}, fmt.Errorf("unknown option %q", name)
+		}
+	}
+	job.Tags = tags
+	return job, nil
+}
+
+// cutTags separa as tags do fim da entrada. Uma tag começa com # depois de um espaço, o que não se
+// confunde com o fragmento de uma URL (ex: https://example.com/page#top)
+func cutTags(entry string) (string, []string, error) {
+	loc := tagStart.FindStringIndex(entry)
+	if loc == nil {
+		return entry, nil, nil
+	}
+	var tags []string
+	for _, field := range strings.Fields(entry[loc[0]:]) {
+		tag := strings.TrimPrefix(field, "#")
+		if tag == field || tag == "" || strings.ContainsAny(tag, ",=") {
+			return "", nil, fmt.Errorf("invalid tag %q: tags come at the end of the line, as #name", field)
+		}
+		tags = append(tags, tag)
+	}
+	return strings.TrimSpace(entry[:loc[0]]), tags, nil
+}
+
+var (
+	tagStart    = regexp.MustCompile(`\s#`)
+	optionStart = regexp.MustCompile(`,(?:\s+\w+|\s*(?:timeout|weight))\s*=`)
+)
+
+// HasTag informa se o job tem alguma das tags informadas
+func HasTag(job pool.Job, tags []string) bool {
+	for _, tag := range job.Tags {
+		for _, wanted := range tags {
+			if strings.EqualFold(tag, wanted) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// FilterTags devolve somente os jobs com alguma das tags informadas; sem nenhuma tag, todos os jobs
+// são devolvidos
+func FilterTags(jobs []pool.Job, tags []string) []pool.Job {
+	if len(tags) == 0 {
+		return jobs
+	}
+	var filtered []pool.Job
+	for _, job := range jobs {
+		if HasTag(job, tags) {
+			filtered = append(filtered, job)
+		}
+	}
+	return filtered
+}
+
+// URLs devolve as URLs dos jobs, na mesma ordem
+func URLs(jobs []pool.Job) []string {
+	list := make([]string, len(jobs))
+	for i, job := range jobs {
+		list[i] = job.URL
+	}
+	return list
+}
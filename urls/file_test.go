@@ -0,0 +1,50 @@
+package urls
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+func TestParseEntry(t *testing.T) {
+	tests := []struct {
+		entry   string
+		want    pool.Job
+		wantErr string
+	}{
+		{entry: "https://example.com/", want: pool.Job{URL: "https://example.com/"}},
+		{entry: "https://example.com/backup.zip, timeout=30s", want: pool.Job{URL: "https://example.com/backup.zip", Timeout: 30 * time.Second}},
+		{entry: "https://example.com/health, timeout=2s, weight=3 #critical #api", want: pool.Job{URL: "https://example.com/health", Timeout: 2 * time.Second, Weight: 3, Tags: []string{"critical", "api"}}},
+		{entry: "https://example.com/health,timeout=2s,weight=3", want: pool.Job{URL: "https://example.com/health", Timeout: 2 * time.Second, Weight: 3}},
+		// As vírgulas da query string fazem parte da URL
+		{entry: "https://x/?a=1,2, timeout=3s", want: pool.Job{URL: "https://x/?a=1,2", Timeout: 3 * time.Second}},
+		{entry: "https://x/?a=1,b=2", want: pool.Job{URL: "https://x/?a=1,b=2"}},
+		{entry: "https://x/?a=1,b=2, weight=2", want: pool.Job{URL: "https://x/?a=1,b=2", Weight: 2}},
+		{entry: "https://x/path,with,commas#top", want: pool.Job{URL: "https://x/path,with,commas#top"}},
+		{entry: "https://x/, retries=3", wantErr: `unknown option "retries"`},
+		{entry: "https://x/, fast", wantErr: `invalid option "fast"`},
+		{entry: "https://x/, fast, timeout=1s", wantErr: `invalid option "fast"`},
+		{entry: "https://x/, timeout=0s", wantErr: "must be greater than zero"},
+		{entry: "https://x/, weight=0", wantErr: "invalid weight"},
+		{entry: "https://x/, timeout=1s,", wantErr: "invalid option"},
+	}
+	for _, tt := range tests {
+		job, err := ParseEntry(tt.entry)
+		if tt.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ParseEntry(%q) error = %v, want %q", tt.entry, err, tt.wantErr)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseEntry(%q) error = %v", tt.entry, err)
+			continue
+		}
+		if !reflect.DeepEqual(job, tt.want) {
+			t.Errorf("ParseEntry(%q) = %+v, want %+v", tt.entry, job, tt.want)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package urls
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// DefaultProfile é o perfil usado quando nenhum é informado: a lista original do artigo
+const DefaultProfile = "default"
+
+// embedded guarda os perfis padrão. Perfis são listas de URLs com nome, no mesmo formato dos arquivos
+// de URLs (ver ReadFile); os embutidos no binário podem ser substituídos ou complementados por
+// arquivos do usuário em UserProfileDir, com o nome do perfil e a extensão .txt (ex: apis.txt)
+//
+//go:embed profiles/*.txt
+var embedded embed.FS
+
+// UserProfileDir é o diretório dos perfis do usuário (ex: ~/.config/entendendo-worker-pool/profiles)
+func UserProfileDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "entendendo-worker-pool", "profiles"), nil
+}
+
+// Profiles devolve os nomes dos perfis disponíveis, embutidos e do usuário, em ordem alfabética
+func Profiles() []string {
+	seen := make(map[string]bool)
+	entries, _ := embedded.ReadDir("profiles")
+	for _, entry := range entries {
+		seen[strings.TrimSuffix(entry.Name(), ".txt")] = true
+	}
+	if dir, err := UserProfileDir(); err == nil {
+		matches, _ := filepath.Glob(filepath.Join(dir, "*.txt"))
+		for _, match := range matches {
+			seen[strings.TrimSuffix(filepath.Base(match), ".txt")] = true
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadProfile carrega o perfil informado. Um perfil do usuário com o mesmo nome de um perfil embutido
+// tem precedência sobre ele
+func LoadProfile(name string) ([]pool.Job, error) {
+	if name == "" || strings.ContainsAny(name, `/\`) {
+		return nil, fmt.Errorf("invalid profile name %q", name)
+	}
+	if dir, err := UserProfileDir(); err == nil {
+		jobs, err := ReadFile(filepath.Join(dir, name+".txt"))
+		if !errors.Is(err, fs.ErrNotExist) {
+			return jobs, err
+		}
+	}
+	f, err := embedded.Open(path.Join("profiles", name+".txt"))
+	if err != nil {
+		return nil, fmt.Errorf("unknown profile %q (available: %s)", name, strings.Join(Profiles(), ", "))
+	}
+	defer f.Close()
+	return Parse(f, name)
+}
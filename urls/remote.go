@@ -0,0 +1,132 @@
+package urls
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/joaomarcelofa/entendendo-worker-pool/pool"
+)
+
+// Remote é uma lista de URLs mantida por um serviço central e obtida por HTTP, para que várias
+// instâncias meçam os mesmos alvos sem distribuir arquivos. O ETag da última resposta é guardado e
+// enviado em If-None-Match, então as atualizações periódicas não baixam novamente uma lista que não mudou
+//
+// O corpo pode ser uma lista JSON, em que cada item é uma entrada no formato dos arquivos de URLs
+// (ex: "https://example.com/health, timeout=2s #critical") ou um objeto
+// {"url": "...", "timeout": "2s", "weight": 3, "tags": ["critical"]}, um objeto {"urls": [...]} com
+// essa mesma lista ou, fora do JSON, o próprio formato dos arquivos de URLs
+type Remote struct {
+	URL    string
+	Client *http.Client
+
+	etag string
+	jobs []pool.Job
+}
+
+// NewRemote cria a fonte remota com um cliente HTTP com o timeout informado
+func NewRemote(url string, timeout time.Duration) *Remote {
+	return &Remote{URL: url, Client: &http.Client{Timeout: timeout}}
+}
+
+// Fetch busca a lista. Quando o servidor responde 304 Not Modified, a lista anterior é devolvida com
+// changed falso
+func (r *Remote) Fetch() (jobs []pool.Job, changed bool, err error) {
+	req, err := http.NewRequest("GET", r.URL, nil)
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Accept", "application/json, text/plain")
+	if r.etag != "" && r.jobs != nil {
+		req.Header.Set("If-None-Match", r.etag)
+	}
+	resp, err := r.Client.Do(req)
+	if err != nil {
+		return nil, false, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotModified && r.jobs != nil {
+		return r.jobs, false, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, false, fmt.Errorf("%s: status code %d", r.URL, resp.StatusCode)
+	}
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, false, err
+	}
+	if jobs, err = parseRemote(body, r.URL); err != nil {
+		return nil, false, err
+	}
+	if len(jobs) == 0 {
+		return nil, false, fmt.Errorf("%s: the list is empty", r.URL)
+	}
+	r.etag, r.jobs = resp.Header.Get("ETag"), jobs
+	return jobs, true, nil
+}
+
+// remoteEntry é um item da lista remota em JSON: um texto no formato dos arquivos ou um objeto
+type remoteEntry struct {
+	job pool.Job
+}
+
+func (e *remoteEntry) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		job, err := ParseEntry(text)
+		e.job = job
+		return err
+	}
+	var obj struct {
+		URL     string   `json:"url"`
+		Timeout string   `json:"timeout"`
+		Weight  int      `json:"weight"`
+		Tags    []string `json:"tags"`
+	}
+	if err := json.Unmarshal(data, &obj); err != nil {
+		return err
+	}
+	if obj.URL == "" {
+		return errors.New("entry without url")
+	}
+	e.job = pool.Job{URL: obj.URL, Weight: obj.Weight, Tags: obj.Tags}
+	if obj.Timeout != "" {
+		timeout, err := time.ParseDuration(obj.Timeout)
+		if err != nil || timeout <= 0 {
+			return fmt.Errorf("invalid timeout %q for %s", obj.Timeout, obj.URL)
+		}
+		e.job.Timeout = timeout
+	}
+	if obj.Weight < 0 {
+		return fmt.Errorf("invalid weight %d for %s", obj.Weight, obj.URL)
+	}
+	return nil
+}
+
+func parseRemote(body []byte, name string) ([]pool.Job, error) {
+	trimmed := bytes.TrimSpace(body)
+	if !bytes.HasPrefix(trimmed, []byte("[")) && !bytes.HasPrefix(trimmed, []byte("{")) {
+		return Parse(bytes.NewReader(body), name)
+	}
+	var entries []remoteEntry
+	if bytes.HasPrefix(trimmed, []byte("{")) {
+		var wrapper struct {
+			URLs []remoteEntry `json:"urls"`
+		}
+		if err := json.Unmarshal(trimmed, &wrapper); err != nil {
+			return nil, fmt.Errorf("%s: %v", name, err)
+		}
+		entries = wrapper.URLs
+	} else if err := json.Unmarshal(trimmed, &entries); err != nil {
+		return nil, fmt.Errorf("%s: %v", name, err)
+	}
+	jobs := make([]pool.Job, len(entries))
+	for i, entry := range entries {
+		jobs[i] = entry.job
+	}
+	return jobs, nil
+}
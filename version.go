@@ -0,0 +1,86 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"runtime"
+	"runtime/debug"
+)
+
+// Informações da versão, preenchidas na geração do binário com
+// -ldflags "-X main.version=v1.2.0 -X main.commit=abc123 -X main.buildDate=2024-01-01T00:00:00Z".
+// Quando não informadas, são obtidas das informações de build gravadas pelo Go (versão do módulo
+// instalado com go install e dados do controle de versão)
+var (
+	version   string
+	commit    string
+	buildDate string
+)
+
+// buildInfo descreve a versão do binário em execução
+type buildInfo struct {
+	Version   string
+	Commit    string
+	Modified  bool
+	BuildDate string
+	GoVersion string
+	Platform  string
+}
+
+func readBuildInfo() buildInfo {
+	info := buildInfo{
+		Version:   version,
+		Commit:    commit,
+		BuildDate: buildDate,
+		GoVersion: runtime.Version(),
+		Platform:  runtime.GOOS + "/" + runtime.GOARCH,
+	}
+	if bi, ok := debug.ReadBuildInfo(); ok {
+		if info.Version == "" {
+			info.Version = bi.Main.Version
+		}
+		for _, setting := range bi.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if info.Commit == "" {
+					info.Commit = setting.Value
+				}
+			case "vcs.time":
+				// Sem a data informada no build, a data do commit é a melhor aproximação disponível
+				if info.BuildDate == "" {
+					info.BuildDate = setting.Value
+				}
+			case "vcs.modified":
+				info.Modified = setting.Value == "true"
+			}
+		}
+	}
+	// go run e go build fora de um módulo versionado informam "(devel)"
+	if info.Version == "" {
+		info.Version = "(devel)"
+	}
+	return info
+}
+
+// runVersion mostra a versão do módulo, o commit, a data do build e a versão do Go
+func runVersion(args []string) error {
+	fs := flag.NewFlagSet("version", flag.ExitOnError)
+	if err := parseFlags(fs, args); err != nil {
+		return err
+	}
+	info := readBuildInfo()
+	fmt.Printf("Version:    %s\n", info.Version)
+	if info.Commit != "" {
+		modified := ""
+		if info.Modified {
+			modified = " (modified)"
+		}
+		fmt.Printf("Commit:     %s%s\n", info.Commit, modified)
+	}
+	if info.BuildDate != "" {
+		fmt.Printf("Built:      %s\n", info.BuildDate)
+	}
+	fmt.Printf("Go version: %s\n", info.GoVersion)
+	fmt.Printf("Platform:   %s\n", info.Platform)
+	return nil
+}
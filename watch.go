@@ -0,0 +1,64 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"time"
+)
+
+// fileState identifica uma versão de um arquivo pela data de modificação e pelo tamanho
+type fileState struct {
+	modTime time.Time
+	size    int64
+	missing bool
+}
+
+func (f fileState) same(other fileState) bool {
+	return f.missing == other.missing && f.size == other.size && f.modTime.Equal(other.modTime)
+}
+
+func statFile(path string) fileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileState{missing: true}
+	}
+	return fileState{modTime: info.ModTime(), size: info.Size()}
+}
+
+// watchFiles executa run e, depois, volta a executá-lo sempre que algum dos arquivos for alterado,
+// até o programa ser interrompido. Os arquivos são verificados a cada interval, comparando a data de
+// modificação e o tamanho, o que funciona em qualquer sistema sem depender de notificações do kernel.
+// Uma falha de run é mostrada sem encerrar a observação, já que ela pode vir de um arquivo salvo pela
+// metade e ser corrigida na próxima alteração
+func watchFiles(paths []string, interval time.Duration, run func() error) error {
+	if interval <= 0 {
+		return fmt.Errorf("invalid watch interval %s", interval)
+	}
+	states := make([]fileState, len(paths))
+	for i, path := range paths {
+		states[i] = statFile(path)
+	}
+	for {
+		if err := run(); err != nil {
+			fmt.Printf("Error: %s\n", err.Error())
+		}
+		fmt.Printf("\nWatching %d file(s) for changes (Ctrl+C to stop)\n", len(paths))
+
+		changed := waitForChange(paths, states, interval)
+		fmt.Printf("\n%s changed at %s, running again\n\n", changed, time.Now().Format("15:04:05"))
+	}
+}
+
+// waitForChange aguarda até que algum dos arquivos seja alterado, atualizando states, e devolve o
+// caminho do arquivo alterado
+func waitForChange(paths []string, states []fileState, interval time.Duration) string {
+	for {
+		time.Sleep(interval)
+		for i, path := range paths {
+			if current := statFile(path); !current.same(states[i]) {
+				states[i] = current
+				return path
+			}
+		}
+	}
+}
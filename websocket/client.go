@@ -0,0 +1,127 @@
+package websocket
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Dialer abre conexões WebSocket como cliente
+type Dialer struct {
+	// Timeout vale para a conexão e para o handshake
+	Timeout time.Duration
+	// NetDial, se informado, é usado para abrir a conexão TCP (ex: para direcionar a um IP específico)
+	NetDial func(ctx context.Context, network, addr string) (net.Conn, error)
+	// TLSConfig é a configuração usada nas conexões wss://; o ServerName, se vazio, é o host da URL
+	TLSConfig *tls.Config
+}
+
+// Dial abre uma conexão WebSocket com o endereço informado (ws:// ou wss://). O timeout vale para a
+// conexão e para o handshake
+func Dial(rawURL string, timeout time.Duration) (*Conn, error) {
+	return (&Dialer{Timeout: timeout}).Dial(rawURL)
+}
+
+// Dial abre uma conexão WebSocket com o endereço informado (ws:// ou wss://)
+func (d *Dialer) Dial(rawURL string) (*Conn, error) {
+	timeout := d.Timeout
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Host
+	if u.Port() == "" {
+		switch u.Scheme {
+		case "ws":
+			host = net.JoinHostPort(u.Hostname(), "80")
+		case "wss":
+			host = net.JoinHostPort(u.Hostname(), "443")
+		}
+	}
+
+	if u.Scheme != "ws" && u.Scheme != "wss" {
+		return nil, fmt.Errorf("websocket: unsupported scheme %q", u.Scheme)
+	}
+	netDial := d.NetDial
+	if netDial == nil {
+		netDial = (&net.Dialer{Timeout: timeout}).DialContext
+	}
+	ctx := context.Background()
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	conn, err := netDial(ctx, "tcp", host)
+	if err != nil {
+		return nil, err
+	}
+	if timeout > 0 {
+		conn.SetDeadline(time.Now().Add(timeout))
+	}
+	if u.Scheme == "wss" {
+		config := &tls.Config{}
+		if d.TLSConfig != nil {
+			config = d.TLSConfig.Clone()
+		}
+		if config.ServerName == "" {
+			config.ServerName = u.Hostname()
+		}
+		tlsConn := tls.Client(conn, config)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		conn = tlsConn
+	}
+
+	// Handshake: requisição GET com o pedido de upgrade e uma chave aleatória, que o servidor deve
+	// devolver transformada em Sec-WebSocket-Accept
+	var nonce [16]byte
+	rand.Read(nonce[:])
+	key := base64.StdEncoding.EncodeToString(nonce[:])
+	req := &http.Request{
+		Method: "GET",
+		URL:    &url.URL{Path: u.Path, RawQuery: u.RawQuery},
+		Host:   u.Host,
+		Header: http.Header{
+			"Upgrade":               {"websocket"},
+			"Connection":            {"Upgrade"},
+			"Sec-WebSocket-Key":     {key},
+			"Sec-WebSocket-Version": {"13"},
+		},
+	}
+	if req.URL.Path == "" {
+		req.URL.Path = "/"
+	}
+	if err := req.Write(conn); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, req)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		conn.Close()
+		return nil, fmt.Errorf("websocket: handshake failed with status %d", resp.StatusCode)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		conn.Close()
+		return nil, errors.New("websocket: invalid Sec-WebSocket-Accept")
+	}
+
+	conn.SetDeadline(time.Time{})
+	return &Conn{conn: conn, reader: reader, client: true}, nil
+}
@@ -0,0 +1,255 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Opcodes definidos pela RFC 6455
+const (
+	OpContinuation = 0x0
+	OpText         = 0x1
+	OpBinary       = 0x2
+	OpClose        = 0x8
+	OpPing         = 0x9
+	OpPong         = 0xA
+)
+
+// acceptGUID é o valor concatenado à chave do cliente para gerar o Sec-WebSocket-Accept
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// maxMessageSize limita o tamanho das mensagens recebidas
+const maxMessageSize = 1 << 20
+
+// ErrClosed é devolvido quando o outro lado encerra a conexão
+var ErrClosed = errors.New("websocket: connection closed")
+
+// Conn é uma conexão WebSocket. As escritas podem ser feitas por várias goroutines, mas as leituras
+// devem ser feitas por uma única goroutine
+type Conn struct {
+	conn   net.Conn
+	reader *bufio.Reader
+	// client indica que a conexão foi aberta por Dial; os frames enviados pelo cliente são mascarados
+	client bool
+
+	writeMux sync.Mutex
+}
+
+// Upgrade responde ao handshake de um cliente e devolve a conexão WebSocket
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if !headerContains(r.Header, "Connection", "upgrade") || !headerContains(r.Header, "Upgrade", "websocket") {
+		http.Error(w, "websocket upgrade required", http.StatusBadRequest)
+		return nil, errors.New("websocket: not an upgrade request")
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if key == "" {
+		http.Error(w, "missing Sec-WebSocket-Key", http.StatusBadRequest)
+		return nil, errors.New("websocket: missing Sec-WebSocket-Key")
+	}
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		http.Error(w, "websocket not supported", http.StatusInternalServerError)
+		return nil, errors.New("websocket: response does not support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, err
+	}
+
+	response := "HTTP/1.1 101 Switching Protocols\r\n" +
+		"Upgrade: websocket\r\n" +
+		"Connection: Upgrade\r\n" +
+		"Sec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n"
+	if _, err := conn.Write([]byte(response)); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &Conn{conn: conn, reader: rw.Reader}, nil
+}
+
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+func headerContains(h http.Header, name, value string) bool {
+	for _, v := range h[http.CanonicalHeaderKey(name)] {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), value) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// ReadMessage lê a próxima mensagem de dados, juntando os fragmentos. Pings são respondidos
+// automaticamente e um frame de close encerra a conexão, devolvendo ErrClosed
+func (c *Conn) ReadMessage() (int, []byte, error) {
+	var opcode int
+	var message []byte
+	for {
+		fin, op, payload, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+		switch op {
+		case OpPing:
+			if err := c.WriteMessage(OpPong, payload); err != nil {
+				return 0, nil, err
+			}
+			continue
+		case OpPong:
+			continue
+		case OpClose:
+			c.WriteMessage(OpClose, payload)
+			c.conn.Close()
+			return 0, nil, ErrClosed
+		case OpContinuation:
+		default:
+			opcode = op
+			message = message[:0]
+		}
+		message = append(message, payload...)
+		if len(message) > maxMessageSize {
+			return 0, nil, errors.New("websocket: message too large")
+		}
+		if fin {
+			return opcode, message, nil
+		}
+	}
+}
+
+func (c *Conn) readFrame() (bool, int, []byte, error) {
+	var header [2]byte
+	if _, err := io.ReadFull(c.reader, header[:]); err != nil {
+		return false, 0, nil, err
+	}
+	fin := header[0]&0x80 != 0
+	opcode := int(header[0] & 0x0f)
+	masked := header[1]&0x80 != 0
+
+	// O tamanho pode ocupar 7 bits, 16 bits ou 64 bits
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.reader, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = binary.BigEndian.Uint64(ext[:])
+	}
+	if length > maxMessageSize {
+		return false, 0, nil, errors.New("websocket: frame too large")
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.reader, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+	payload := make([]byte, length)
+	if _, err := io.ReadFull(c.reader, payload); err != nil {
+		return false, 0, nil, err
+	}
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+	return fin, opcode, payload, nil
+}
+
+// WriteMessage envia uma mensagem em um único frame. Frames do servidor não são mascarados e os do
+// cliente recebem uma máscara aleatória, como exige a RFC
+func (c *Conn) WriteMessage(opcode int, payload []byte) error {
+	frame := []byte{0x80 | byte(opcode)}
+	var maskBit byte
+	if c.client {
+		maskBit = 0x80
+	}
+	switch length := len(payload); {
+	case length < 126:
+		frame = append(frame, maskBit|byte(length))
+	case length <= 0xffff:
+		frame = append(frame, maskBit|126, byte(length>>8), byte(length))
+	default:
+		frame = append(frame, maskBit|127)
+		var ext [8]byte
+		binary.BigEndian.PutUint64(ext[:], uint64(length))
+		frame = append(frame, ext[:]...)
+	}
+	if c.client {
+		var mask [4]byte
+		rand.Read(mask[:])
+		frame = append(frame, mask[:]...)
+		start := len(frame)
+		frame = append(frame, payload...)
+		for i := range frame[start:] {
+			frame[start+i] ^= mask[i%4]
+		}
+	} else {
+		frame = append(frame, payload...)
+	}
+
+	c.writeMux.Lock()
+	defer c.writeMux.Unlock()
+	_, err := c.conn.Write(frame)
+	return err
+}
+
+// Ping envia um ping e aguarda o pong com o mesmo conteúdo. Assim como ReadMessage, deve ser chamado
+// pela goroutine que faz as leituras; mensagens de dados recebidas enquanto isso são descartadas
+func (c *Conn) Ping(payload []byte) error {
+	if err := c.WriteMessage(OpPing, payload); err != nil {
+		return err
+	}
+	for {
+		_, op, data, err := c.readFrame()
+		if err != nil {
+			return err
+		}
+		switch op {
+		case OpPong:
+			if bytes.Equal(data, payload) {
+				return nil
+			}
+		case OpPing:
+			if err := c.WriteMessage(OpPong, data); err != nil {
+				return err
+			}
+		case OpClose:
+			c.conn.Close()
+			return ErrClosed
+		}
+	}
+}
+
+// SetDeadline define o prazo para as leituras e escritas na conexão
+func (c *Conn) SetDeadline(t time.Time) error {
+	return c.conn.SetDeadline(t)
+}
+
+// Close encerra a conexão, enviando antes um frame de close
+func (c *Conn) Close() error {
+	c.WriteMessage(OpClose, []byte{0x03, 0xe8}) // 1000: encerramento normal
+	return c.conn.Close()
+}